@@ -0,0 +1,4 @@
+// Package bookpb contains the protobuf messages and the gRPC client and server of the books service.
+package bookpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative book.proto
@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"io"
+	"os"
 
 	_ "github.com/lib/pq"
 
+	"github.com/ivyoverflow/pub-sub/api/internal/graph"
 	"github.com/ivyoverflow/pub-sub/api/internal/handler"
+	"github.com/ivyoverflow/pub-sub/api/internal/rpc"
 	"github.com/ivyoverflow/pub-sub/api/internal/server"
 	"github.com/ivyoverflow/pub-sub/api/internal/service"
-	"github.com/ivyoverflow/pub-sub/api/internal/storage/mongo"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/backend"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/instrumented"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/resilient"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/metrics"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
+	"github.com/ivyoverflow/pub-sub/platform/shutdown"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
 func main() {
@@ -19,17 +31,113 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
-	db, err := mongo.New(ctx)
+	cfg, err := server.NewConfig()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	bookRepo := mongo.NewBookRepository(db)
+	shutdownTracing, err := tracing.Init(ctx, "api", cfg.TracingExporter)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	authn, err := auth.New(auth.NewConfig())
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if !authn.Enabled() {
+		log.Warn("Authentication is disabled, every request is allowed")
+	}
+
+	limiter, err := ratelimit.NewStore(cfg.RateLimitRedisURL)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	responses, err := idempotency.NewStore(cfg.IdempotencyRedisURL)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	storageCfg, err := backend.NewConfig()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	policyCfg, err := resilient.NewConfig()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	reg := metrics.NewRegistry()
+	store, err := backend.New(ctx, storageCfg, instrumented.NewMetrics(reg), log)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	bookRepo := resilient.NewBookRepository(store, policyCfg, log)
 	gen := service.NewUUIDGenerator()
 	bookSvc := service.NewBookController(bookRepo, gen)
 	bookHandl := handler.NewBookController(ctx, bookSvc, log)
-	srv := server.New(bookHandl)
-	if err = srv.Run(); err != nil {
+	graphHandl, err := graph.NewHandler(bookSvc, log)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	srv, err := server.New(bookHandl, graphHandl, store.Checks(), reg, authn, limiter, responses, log)
+	if err != nil {
 		log.Fatal(err.Error())
 	}
+
+	rpcSrv, err := rpc.New(bookSvc, store.Checks(), authn, log)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	runCtx, stop := shutdown.Notify(ctx)
+	defer stop()
+
+	// A failed server stops the other one too, and the process exits with an error after the cleanup.
+	rpcErr := make(chan error, 1)
+	go func() {
+		failure := rpcSrv.Run(runCtx)
+		if failure != nil {
+			log.Error(failure.Error())
+		}
+
+		rpcErr <- failure
+		stop()
+	}()
+
+	runErr := srv.Run(runCtx)
+	if runErr != nil {
+		log.Error(runErr.Error())
+	}
+
+	stop()
+	if err = <-rpcErr; err != nil {
+		runErr = err
+	}
+
+	if err = store.Close(ctx); err != nil {
+		log.Error(err.Error())
+	}
+
+	for _, shared := range []interface{}{limiter, responses} {
+		if closer, ok := shared.(io.Closer); ok {
+			if err = closer.Close(); err != nil {
+				log.Error(err.Error())
+			}
+		}
+	}
+
+	if err = shutdownTracing(ctx); err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("The server is stopped")
+	if runErr != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	_ "github.com/lib/pq"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/backend"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/replicated"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func main() {
+	var from, to, checkpoint string
+	var batchSize int
+	flag.StringVar(&from, "from", backend.Mongo, "sets the storage to copy books from")
+	flag.StringVar(&to, "to", backend.Postgres, "sets the storage to copy books to")
+	flag.StringVar(&checkpoint, "checkpoint", "backfill.checkpoint", "sets the file that stores the backfill progress")
+	flag.IntVar(&batchSize, "batch", 100, "sets the number of books copied per batch")
+	flag.Parse()
+
+	ctx := context.Background()
+	log, err := logger.New()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if from == to || batchSize <= 0 {
+		log.Fatal("Set different source and destination storages and a positive batch size")
+	}
+
+	src, err := backend.Open(ctx, from)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	dst, err := backend.Open(ctx, to)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	copied, err := replicated.Backfill(ctx, src, dst, replicated.NewCheckpoint(checkpoint), batchSize, log)
+	src.Close(ctx)
+	dst.Close(ctx)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	log.Info(fmt.Sprintf("Backfill from <<< %s >>> to <<< %s >>> finished, %d books copied", from, to, copied))
+}
@@ -7,15 +7,24 @@ require (
 	github.com/golang/mock v1.4.4
 	github.com/google/uuid v1.1.5
 	github.com/gorilla/mux v1.8.0
+	github.com/graphql-go/graphql v0.8.1
 	github.com/ivyoverflow/pub-sub/book v0.0.0-20210215112123-ce11ad458e09
 	github.com/ivyoverflow/pub-sub/platform v0.0.0-00010101000000-000000000000
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/lib/pq v1.8.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.9.0
 	github.com/shopspring/decimal v1.2.0
 	github.com/stretchr/testify v1.7.0
 	go.mongodb.org/mongo-driver v1.4.4
+	go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux v0.24.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	go.uber.org/zap v1.16.0
+	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
 )
 
 replace github.com/ivyoverflow/pub-sub/platform => ../platform
@@ -0,0 +1,30 @@
+// Package graph implements the GraphQL endpoint of the books service: queries, mutations
+// and subscriptions to book changes over websocket.
+package graph
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config contains fields that will be used to configure the GraphQL endpoint.
+type Config struct {
+	// MaxDepth is the deepest nesting of fields an operation may select.
+	MaxDepth int `envconfig:"GRAPHQL_MAX_DEPTH" default:"10"`
+	// MaxComplexity is the highest number of fields an operation may resolve, where the fields
+	// selected from a list count once for every book of the list.
+	MaxComplexity int `envconfig:"GRAPHQL_MAX_COMPLEXITY" default:"1000"`
+	// InitTimeout is the time a websocket client has to initialize the connection.
+	InitTimeout time.Duration `envconfig:"GRAPHQL_INIT_TIMEOUT" default:"10s"`
+}
+
+// NewConfig returns a new configured Config object, or an error if the environment sets invalid values.
+func NewConfig() (*Config, error) {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,302 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/graph"
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	svcmock "github.com/ivyoverflow/pub-sub/api/internal/service/mock"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func testBook() *model.Book {
+	return &model.Book{
+		ID:          uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
+		Name:        "Concurrency in Go",
+		DateOfIssue: "2017",
+		Author:      "Katherine Cox-Buday",
+		Description: "...",
+		Rating:      model.Decimal{Decimal: decimal.NewFromFloat(99.99)},
+		Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
+		InStock:     true,
+	}
+}
+
+func newHandler(t *testing.T, svc *svcmock.MockBookerService) *graph.Handler {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	handler, err := graph.NewHandler(svc, log)
+	if err != nil {
+		t.Fatalf("Handler initialization throws an error: %v", err)
+	}
+
+	return handler
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	const insertBook = `mutation { insertBook(book: {name: "Concurrency in Go", dateOfIssue: "2017", ` +
+		`author: "Katherine Cox-Buday", description: "...", rating: "99.99", price: "199.99", inStock: true}) { id } }`
+
+	testCases := []struct {
+		name               string
+		method             string
+		body               string
+		role               string
+		mockBehavior       func(*svcmock.MockBookerService)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:   "OK",
+			method: http.MethodPost,
+			body:   `{"query": "query ($id: ID!) { book(id: $id) { name rating } }", "variables": {"id": "7a2f922c-073a-11eb-adc1-0242ac120003"}}`,
+			role:   auth.RoleReader,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(testBook(), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"data":{"book":{"name":"Concurrency in Go","rating":"99.99"}}}`,
+		},
+		{
+			name:   "Not found",
+			method: http.MethodGet,
+			body:   `{ book(id: "7a2f922c-073a-11eb-adc1-0242ac120003") { name } }`,
+			role:   auth.RoleReader,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(nil, types.ErrorNotFound)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"data":{"book":null},"errors":[{"message":"not found","locations":[{"line":1,"column":3}],` +
+				`"path":["book"],"extensions":{"code":"NOT_FOUND"}}]}`,
+		},
+		{
+			name:   "Books page",
+			method: http.MethodPost,
+			body:   `{"query": "{ books(first: 1) { id } }"}`,
+			role:   auth.RoleReader,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().List(gomock.Any(), uuid.Nil, 1).Return([]model.Book{*testBook()}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"data":{"books":[{"id":"7a2f922c-073a-11eb-adc1-0242ac120003"}]}}`,
+		},
+		{
+			name:   "Editor mutation",
+			method: http.MethodPost,
+			body:   `{"query": ` + quote(insertBook) + `}`,
+			role:   auth.RoleEditor,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(testBook(), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"data":{"insertBook":{"id":"7a2f922c-073a-11eb-adc1-0242ac120003"}}}`,
+		},
+		{
+			name:               "Reader mutation",
+			method:             http.MethodPost,
+			body:               `{"query": ` + quote(insertBook) + `}`,
+			role:               auth.RoleReader,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"data":null,"errors":[{"message":"the editor role is required","locations":[{"line":1,"column":12}],` +
+				`"path":["insertBook"],"extensions":{"code":"FORBIDDEN"}}]}`,
+		},
+		{
+			name:               "Mutation with GET",
+			method:             http.MethodGet,
+			body:               insertBook,
+			role:               auth.RoleEditor,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       `{"data":null,"errors":[{"message":"mutations must be sent with POST","locations":[]}]}`,
+		},
+		{
+			name:               "Unknown field",
+			method:             http.MethodPost,
+			body:               `{"query": "{ book(id: \"1\") { title } }"}`,
+			role:               auth.RoleReader,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"data":null,"errors":[{"message":"Cannot query field \"title\" on type \"Book\".",` +
+				`"locations":[{"line":1,"column":19}]}]}`,
+		},
+		{
+			name:               "Too complex",
+			method:             http.MethodPost,
+			body:               `{"query": "{ a: books(first: 100) { id name author description rating price inStock dateOfIssue } b: books(first: 100) { id name } }"}`,
+			role:               auth.RoleReader,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"data":null,"errors":[{"message":"the operation complexity 1002 exceeds the limit of 1000",` +
+				`"locations":[],"extensions":{"code":"QUERY_TOO_COMPLEX"}}]}`,
+		},
+		{
+			name:               "Subscription over HTTP",
+			method:             http.MethodPost,
+			body:               `{"query": "subscription { bookChanged { type } }"}`,
+			role:               auth.RoleReader,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"data":null,"errors":[{"message":"subscriptions are served over websocket","locations":[]}]}`,
+		},
+		{
+			name:               "Invalid body",
+			method:             http.MethodPost,
+			body:               `{"query":`,
+			role:               auth.RoleReader,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"data":null,"errors":[{"message":"request body is not a GraphQL request","locations":[]}]}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ctrl := gomock.NewController(t)
+		svc := svcmock.NewMockBookerService(ctrl)
+		testCase.mockBehavior(svc)
+
+		req := httptest.NewRequest(testCase.method, "/graphql", strings.NewReader(testCase.body))
+		if testCase.method == http.MethodGet {
+			req = httptest.NewRequest(testCase.method, "/graphql?query="+url.QueryEscape(testCase.body), nil)
+		}
+
+		req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Subject: "test", Roles: []string{testCase.role}}))
+		rec := httptest.NewRecorder()
+		newHandler(t, svc).ServeHTTP(rec, req)
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		assert.JSONEq(t, testCase.expectedBody, rec.Body.String(), testCase.name)
+		ctrl.Finish()
+	}
+}
+
+func TestHandler_ServeHTTP_depth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The schema is only two levels deep, so the limit is lowered to check that fragments are expanded.
+	os.Setenv("GRAPHQL_MAX_DEPTH", "1")
+	defer os.Unsetenv("GRAPHQL_MAX_DEPTH")
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(
+		`{"query": "query { ...fields } fragment fields on Query { book(id: \"7a2f922c-073a-11eb-adc1-0242ac120003\") { id } }"}`))
+	rec := httptest.NewRecorder()
+	newHandler(t, svcmock.NewMockBookerService(ctrl)).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"the operation depth 2 exceeds the limit of 1"`)
+}
+
+func TestNewHandler_invalidConfig(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	os.Setenv("GRAPHQL_MAX_DEPTH", "deep")
+	defer os.Unsetenv("GRAPHQL_MAX_DEPTH")
+	_, err = graph.NewHandler(nil, log)
+	assert.Error(t, err)
+}
+
+func TestHandler_Subscriptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	events := make(chan model.BookEvent, 1)
+	svc := svcmock.NewMockBookerService(ctrl)
+	watched := make(chan struct{})
+	svc.EXPECT().Watch(gomock.Any()).DoAndReturn(func(context.Context) <-chan model.BookEvent {
+		close(watched)
+
+		return events
+	})
+
+	server := httptest.NewServer(newHandler(t, svc).Subscriptions())
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	_, err := websocket.Dial(url, "", server.URL)
+	assert.Error(t, err, "Connections without the subprotocol are rejected")
+
+	ws, err := websocket.Dial(url, graph.Subprotocol, server.URL)
+	if err != nil {
+		t.Fatalf("Dialing throws an error: %v", err)
+	}
+
+	defer ws.Close()
+
+	ws.SetDeadline(time.Now().Add(5 * time.Second))
+	receive := func() map[string]interface{} {
+		msg := make(map[string]interface{})
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			t.Fatalf("Receiving throws an error: %v", err)
+		}
+
+		return msg
+	}
+
+	websocket.Message.Send(ws, `{"type": "connection_init"}`)
+	assert.Equal(t, "connection_ack", receive()["type"])
+
+	websocket.Message.Send(ws, `{"type": "ping"}`)
+	assert.Equal(t, "pong", receive()["type"])
+
+	websocket.Message.Send(ws, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { bookChanged { type book { name } } }"}}`)
+	// Queries and mutations may be sent over websocket too.
+	websocket.Message.Send(ws, `{"id": "2", "type": "subscribe", "payload": {"query": "{ book(id: \"1\") { name } }"}}`)
+	assert.Equal(t, map[string]interface{}{
+		"id":   "2",
+		"type": "next",
+		"payload": map[string]interface{}{
+			"data": map[string]interface{}{"book": nil},
+			"errors": []interface{}{map[string]interface{}{
+				"message":    "invalid book ID",
+				"locations":  []interface{}{map[string]interface{}{"line": float64(1), "column": float64(3)}},
+				"path":       []interface{}{"book"},
+				"extensions": map[string]interface{}{"code": "BAD_USER_INPUT"},
+			}},
+		},
+	}, receive())
+	assert.Equal(t, map[string]interface{}{"id": "2", "type": "complete"}, receive())
+
+	<-watched
+	events <- model.BookEvent{Type: model.BookDeleted, Book: *testBook()}
+	assert.Equal(t, map[string]interface{}{
+		"id":   "1",
+		"type": "next",
+		"payload": map[string]interface{}{"data": map[string]interface{}{"bookChanged": map[string]interface{}{
+			"type": "DELETED",
+			"book": map[string]interface{}{"name": "Concurrency in Go"},
+		}}},
+	}, receive())
+
+	websocket.Message.Send(ws, `{"id": "1", "type": "subscribe", "payload": {"query": "{ books { id } }"}}`)
+	var data []byte
+	err = websocket.Message.Receive(ws, &data)
+	assert.Error(t, err, "A duplicate subscription ID closes the connection")
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+
+	return string(data)
+}
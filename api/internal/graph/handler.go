@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Handler serves GraphQL queries and mutations over HTTP and all operations over websocket.
+type Handler struct {
+	schema graphql.Schema
+	cfg    *Config
+	log    *logger.Logger
+}
+
+// NewHandler returns a new configured Handler object that resolves books with svc,
+// or an error if the environment sets an invalid config.
+func NewHandler(svc service.Booker, log *logger.Logger) (*Handler, error) {
+	cfg, err := NewConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := NewSchema(svc, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{schema: schema, cfg: cfg, log: log}, nil
+}
+
+// request is a GraphQL request of an HTTP request or of a websocket subscribe message.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes the query or mutation of a GET or POST request. Requests that cannot be executed,
+// because they are malformed, invalid or exceed the limits, are rejected with the 400 status code
+// and GraphQL errors. Executed requests respond with 200 and the result, errors included.
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	req := request{}
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeErrors(rw, http.StatusBadRequest, errors.New("variables are not a JSON object"))
+
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("GraphQL request decoding failed", zap.Error(err))
+			writeErrors(rw, http.StatusBadRequest, errors.New("request body is not a GraphQL request"))
+
+			return
+		}
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		writeErrors(rw, http.StatusMethodNotAllowed, errors.New("only GET and POST requests are allowed"))
+
+		return
+	}
+
+	doc, operation, errs := h.prepare(&req)
+	if errs != nil {
+		log.Info("GraphQL request rejected", zap.String("error", errs[0].Message))
+		writeResult(rw, http.StatusBadRequest, &graphql.Result{Errors: errs})
+
+		return
+	}
+
+	switch {
+	case operation == ast.OperationTypeSubscription:
+		writeErrors(rw, http.StatusBadRequest, errors.New("subscriptions are served over websocket"))
+
+		return
+	case operation == ast.OperationTypeMutation && r.Method != http.MethodPost:
+		rw.Header().Set("Allow", "POST")
+		writeErrors(rw, http.StatusMethodNotAllowed, errors.New("mutations must be sent with POST"))
+
+		return
+	}
+
+	writeResult(rw, http.StatusOK, graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       r.Context(),
+	}))
+}
+
+// prepare parses and validates the request and checks its limits. It returns the parsed document
+// and the type of the operation to execute, or the errors that prevent the execution.
+func (h *Handler) prepare(req *request) (*ast.Document, string, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, "", gqlerrors.FormatErrors(err)
+	}
+
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		return nil, "", result.Errors
+	}
+
+	if err := checkLimits(doc, req.Variables, h.cfg.MaxDepth, h.cfg.MaxComplexity); err != nil {
+		return nil, "", formatErrors(err)
+	}
+
+	operation, err := operationType(doc, req.OperationName)
+	if err != nil {
+		return nil, "", gqlerrors.FormatErrors(err)
+	}
+
+	return doc, operation, nil
+}
+
+// operationType returns the type of the operation named name, or of the only operation if name is empty.
+func operationType(doc *ast.Document, name string) (string, error) {
+	operation := ""
+	for _, definition := range doc.Definitions {
+		definition, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "" && operation != "":
+			return "", errors.New("the operation name is required with several operations")
+		case name == "" || (definition.Name != nil && definition.Name.Value == name):
+			operation = definition.Operation
+		}
+	}
+
+	if operation == "" {
+		return "", errors.New("the operation is not found")
+	}
+
+	return operation, nil
+}
+
+// formatErrors formats errors raised outside of resolvers, which gqlerrors.FormatErrors formats without extensions.
+func formatErrors(err error) []gqlerrors.FormattedError {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+
+	return []gqlerrors.FormattedError{formatted}
+}
+
+func writeErrors(rw http.ResponseWriter, statusCode int, err error) {
+	writeResult(rw, statusCode, &graphql.Result{Errors: formatErrors(err)})
+}
+
+func writeResult(rw http.ResponseWriter, statusCode int, result *graphql.Result) {
+	rw.Header().Set("content-type", "application/json")
+	rw.WriteHeader(statusCode)
+	json.NewEncoder(rw).Encode(result)
+}
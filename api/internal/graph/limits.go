@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// measure computes the depth and complexity of selection sets. Complexity is the number of fields
+// an operation resolves, so the fields selected from a list count once for every book of the list.
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkLimits returns an error if an operation of the document selects fields deeper than maxDepth
+// or more than maxComplexity fields. Fragments are expanded, so the document must be validated first,
+// which rejects fragment cycles. Introspection fields are not counted: the schema is small and fixed.
+func checkLimits(doc *ast.Document, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	m := &measure{fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		depth, complexity := m.selectionSet(operation.SelectionSet)
+		if depth > maxDepth {
+			return &Error{
+				Code:    "QUERY_TOO_DEEP",
+				Message: fmt.Sprintf("the operation depth %d exceeds the limit of %d", depth, maxDepth),
+			}
+		}
+
+		if complexity > maxComplexity {
+			return &Error{
+				Code:    "QUERY_TOO_COMPLEX",
+				Message: fmt.Sprintf("the operation complexity %d exceeds the limit of %d", complexity, maxComplexity),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *measure) selectionSet(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+
+			d, c = m.selectionSet(selection.SelectionSet)
+			d, c = d+1, 1+m.listSize(selection)*c
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				d, c = m.selectionSet(fragment.SelectionSet)
+			}
+		case *ast.InlineFragment:
+			d, c = m.selectionSet(selection.SelectionSet)
+		}
+
+		if d > depth {
+			depth = d
+		}
+
+		complexity += c
+	}
+
+	return depth, complexity
+}
+
+// listSize returns the number of books the field may resolve. The books query is the only list
+// of the schema: its size is the first argument, bounded like the resolver bounds it.
+func (m *measure) listSize(field *ast.Field) int {
+	if field.Name.Value != "books" {
+		return 1
+	}
+
+	size := defaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			size, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			if first, ok := m.variables[value.Name.Value].(float64); ok {
+				size = int(first)
+			}
+		}
+	}
+
+	switch {
+	case size < 0:
+		return 0
+	case size > maxPageSize:
+		return maxPageSize
+	default:
+		return size
+	}
+}
@@ -0,0 +1,418 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Defines page sizes of the books query.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// Error is a GraphQL error with a code in its extensions, so clients can react to it like to HTTP status codes.
+type Error struct {
+	Code    string
+	Message string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// errorOf returns the GraphQL error of the service error. Like the HTTP handlers,
+// it carries the message of the error kind and never the underlying cause.
+func errorOf(err error) error {
+	kind := types.KindOf(err)
+	message := types.New(kind, types.FieldOf(err), nil).Error()
+	switch kind {
+	case types.KindNotFound:
+		return &Error{Code: "NOT_FOUND", Message: message}
+	case types.KindBadRequest, types.KindValidation:
+		return &Error{Code: "BAD_USER_INPUT", Message: message}
+	case types.KindDuplicate:
+		return &Error{Code: "CONFLICT", Message: message}
+	case types.KindUnavailable:
+		return &Error{Code: "UNAVAILABLE", Message: message}
+	default:
+		return &Error{Code: "INTERNAL", Message: message}
+	}
+}
+
+// resolver resolves the root fields of the schema with the book service.
+type resolver struct {
+	svc service.Booker
+	log *logger.Logger
+}
+
+// NewSchema returns the GraphQL schema of books. Readers may query books and subscribe to their changes;
+// editors may also insert, update and delete them. Roles are read from the principal of the resolver context.
+func NewSchema(svc service.Booker, log *logger.Logger) (graphql.Schema, error) {
+	r := &resolver{svc: svc, log: log}
+
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"dateOfIssue": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"rating": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "A decimal number as a string, so no precision is lost.",
+			},
+			"price": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "A decimal number as a string, so no precision is lost.",
+			},
+			"inStock": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	bookInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BookInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"dateOfIssue": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"author":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"rating":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"price":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"inStock":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	eventTypeType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "BookEventType",
+		Values: graphql.EnumValueConfigMap{
+			"INSERTED": &graphql.EnumValueConfig{Value: string(model.BookInserted)},
+			"UPDATED":  &graphql.EnumValueConfig{Value: string(model.BookUpdated)},
+			"DELETED":  &graphql.EnumValueConfig{Value: string(model.BookDeleted)},
+		},
+	})
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "BookEvent",
+		Description: "A change of a book. The book of a deletion is the book before it was deleted.",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{Type: graphql.NewNonNull(eventTypeType)},
+			"book": &graphql.Field{Type: graphql.NewNonNull(bookType)},
+		},
+	})
+
+	id := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+	book := &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookInputType)}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"book": &graphql.Field{
+					Type:    bookType,
+					Args:    graphql.FieldConfigArgument{"id": id},
+					Resolve: r.book,
+				},
+				"books": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+					Description: "Books ordered by ID. The next page starts after the ID of the last book.",
+					Args: graphql.FieldConfigArgument{
+						"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+						"after": &graphql.ArgumentConfig{Type: graphql.ID},
+					},
+					Resolve: r.books,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"insertBook": &graphql.Field{
+					Type:    graphql.NewNonNull(bookType),
+					Args:    graphql.FieldConfigArgument{"book": book},
+					Resolve: r.insertBook,
+				},
+				"updateBook": &graphql.Field{
+					Type:    graphql.NewNonNull(bookType),
+					Args:    graphql.FieldConfigArgument{"id": id, "book": book},
+					Resolve: r.updateBook,
+				},
+				"deleteBook": &graphql.Field{
+					Type:    graphql.NewNonNull(bookType),
+					Args:    graphql.FieldConfigArgument{"id": id},
+					Resolve: r.deleteBook,
+				},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"bookChanged": &graphql.Field{
+					Type:      graphql.NewNonNull(eventType),
+					Subscribe: r.watch,
+					Resolve:   r.bookChanged,
+				},
+			},
+		}),
+	})
+}
+
+func (r *resolver) book(p graphql.ResolveParams) (interface{}, error) {
+	bookID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := r.svc.Get(p.Context, bookID)
+	if err != nil {
+		logger.FromContext(p.Context, r.log).Error("Book retrieval failed", zap.Error(err))
+
+		return nil, errorOf(err)
+	}
+
+	return toGraph(book), nil
+}
+
+func (r *resolver) books(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	switch {
+	case first < 0:
+		return nil, &Error{Code: "BAD_USER_INPUT", Message: "first must not be negative"}
+	case first > maxPageSize:
+		first = maxPageSize
+	}
+
+	after := uuid.Nil
+	if p.Args["after"] != nil {
+		var err error
+		if after, err = parseID(p.Args["after"]); err != nil {
+			return nil, err
+		}
+	}
+
+	if first == 0 {
+		return []interface{}{}, nil
+	}
+
+	books, err := r.svc.List(p.Context, after, first)
+	if err != nil {
+		logger.FromContext(p.Context, r.log).Error("Book listing failed", zap.Error(err))
+
+		return nil, errorOf(err)
+	}
+
+	result := make([]interface{}, 0, len(books))
+	for i := range books {
+		result = append(result, toGraph(&books[i]))
+	}
+
+	return result, nil
+}
+
+func (r *resolver) insertBook(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	book, err := fromGraph(p.Args["book"])
+	if err != nil {
+		return nil, err
+	}
+
+	log := logger.FromContext(p.Context, r.log)
+	insertedBook, err := r.svc.Insert(p.Context, book)
+	if err != nil {
+		log.Error("Book insertion failed", zap.Error(err))
+
+		return nil, errorOf(err)
+	}
+
+	log.Debug("Book added", zap.Stringer("book_id", insertedBook.ID), zap.String("name", insertedBook.Name))
+
+	return toGraph(insertedBook), nil
+}
+
+func (r *resolver) updateBook(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	bookID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := fromGraph(p.Args["book"])
+	if err != nil {
+		return nil, err
+	}
+
+	updatedBook, err := r.svc.Update(p.Context, bookID, book)
+	if err != nil {
+		logger.FromContext(p.Context, r.log).Error("Book update failed", zap.Error(err))
+
+		return nil, errorOf(err)
+	}
+
+	return toGraph(updatedBook), nil
+}
+
+func (r *resolver) deleteBook(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	bookID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	deletedBook, err := r.svc.Delete(p.Context, bookID)
+	if err != nil {
+		logger.FromContext(p.Context, r.log).Error("Book deletion failed", zap.Error(err))
+
+		return nil, errorOf(err)
+	}
+
+	return toGraph(deletedBook), nil
+}
+
+// watch returns the source stream of the bookChanged subscription. The stream ends when the context
+// of the subscription is done. A subscriber that falls behind receives an error and the stream ends.
+func (r *resolver) watch(p graphql.ResolveParams) (interface{}, error) {
+	events := r.svc.Watch(p.Context)
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+		for {
+			var payload interface{}
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					if p.Context.Err() != nil {
+						return
+					}
+
+					payload = &Error{Code: "RESOURCE_EXHAUSTED", Message: "the subscriber fell behind the book events"}
+				} else {
+					payload = map[string]interface{}{"type": string(event.Type), "book": toGraph(&event.Book)}
+				}
+			}
+
+			select {
+			case <-p.Context.Done():
+				return
+			case source <- payload:
+			}
+
+			if _, ok := payload.(error); ok {
+				return
+			}
+		}
+	}()
+
+	return source, nil
+}
+
+// bookChanged resolves every event of the source stream of the subscription.
+func (r *resolver) bookChanged(p graphql.ResolveParams) (interface{}, error) {
+	if err, ok := p.Source.(error); ok {
+		return nil, err
+	}
+
+	return p.Source, nil
+}
+
+// requireRole returns a FORBIDDEN error if the principal of ctx does not have the role.
+func requireRole(ctx context.Context, role string) error {
+	if principal, ok := auth.FromContext(ctx); !ok || !principal.HasRole(role) {
+		return &Error{Code: "FORBIDDEN", Message: "the " + role + " role is required"}
+	}
+
+	return nil
+}
+
+func parseID(id interface{}) (uuid.UUID, error) {
+	s, _ := id.(string)
+	bookID, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, &Error{Code: "BAD_USER_INPUT", Message: "invalid book ID"}
+	}
+
+	return bookID, nil
+}
+
+// toGraph converts a book to the value resolved by the fields of the Book type.
+func toGraph(book *model.Book) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          book.ID.String(),
+		"name":        book.Name,
+		"dateOfIssue": book.DateOfIssue,
+		"author":      book.Author,
+		"description": book.Description,
+		"rating":      book.Rating.String(),
+		"price":       book.Price.String(),
+		"inStock":     book.InStock,
+	}
+}
+
+// fromGraph converts a BookInput argument, whose fields are checked by GraphQL validation.
+func fromGraph(input interface{}) (*model.Book, error) {
+	fields, _ := input.(map[string]interface{})
+	rating, err := parseDecimal(fields["rating"], "rating")
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := parseDecimal(fields["price"], "price")
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := fields["name"].(string)
+	dateOfIssue, _ := fields["dateOfIssue"].(string)
+	author, _ := fields["author"].(string)
+	description, _ := fields["description"].(string)
+	inStock, _ := fields["inStock"].(bool)
+
+	return &model.Book{
+		Name:        name,
+		DateOfIssue: dateOfIssue,
+		Author:      author,
+		Description: description,
+		Rating:      rating,
+		Price:       price,
+		InStock:     inStock,
+	}, nil
+}
+
+// parseDecimal parses a decimal field. An empty value is zero, so the service reports the field as missing.
+func parseDecimal(value interface{}, field string) (model.Decimal, error) {
+	s, _ := value.(string)
+	if s == "" {
+		return model.Decimal{}, nil
+	}
+
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return model.Decimal{}, errorOf(types.New(types.KindBadRequest, field, nil))
+	}
+
+	return model.Decimal{Decimal: d}, nil
+}
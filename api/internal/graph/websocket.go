@@ -0,0 +1,260 @@
+package graph
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Subprotocol is the websocket subprotocol of GraphQL over websocket served by Subscriptions.
+const Subprotocol = "graphql-transport-ws"
+
+// Defines the close codes of the graphql-transport-ws protocol.
+const (
+	closeInvalidMessage     = 4400
+	closeUnauthorized       = 4401
+	closeInitTimeout        = 4408
+	closeSubscriberExists   = 4409
+	closeTooManyInitRequest = 4429
+)
+
+// message is a message of the graphql-transport-ws protocol.
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Subscriptions returns a handler that serves operations, subscriptions above all, over websocket with
+// the graphql-transport-ws protocol: the client sends connection_init, waits for connection_ack and then
+// starts operations with subscribe messages. Every result is sent in a next message and the end of an
+// operation in a complete message. Connections without the protocol are rejected with the 403 status code.
+func (h *Handler) Subscriptions() http.Handler {
+	return websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			for _, protocol := range config.Protocol {
+				if protocol == Subprotocol {
+					config.Protocol = []string{Subprotocol}
+
+					return nil
+				}
+			}
+
+			return errors.New("the " + Subprotocol + " subprotocol is required")
+		},
+		Handler: h.serve,
+	}
+}
+
+// session is a websocket connection and the operations running on it.
+type session struct {
+	h          *Handler
+	ws         *websocket.Conn
+	ctx        context.Context
+	log        *logger.Logger
+	mutex      sync.Mutex
+	operations map[string]context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func (h *Handler) serve(ws *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	s := &session{
+		h:          h,
+		ws:         ws,
+		ctx:        ctx,
+		log:        logger.FromContext(ctx, h.log),
+		operations: make(map[string]context.CancelFunc),
+	}
+
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+
+	ws.SetReadDeadline(time.Now().Add(h.cfg.InitTimeout))
+	acknowledged := false
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			var netErr interface{ Timeout() bool }
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				s.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+
+			return
+		}
+
+		msg := message{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.close(closeInvalidMessage, "Invalid message received")
+
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if acknowledged {
+				s.close(closeTooManyInitRequest, "Too many initialisation requests")
+
+				return
+			}
+
+			acknowledged = true
+			ws.SetReadDeadline(time.Time{})
+			s.send(message{Type: "connection_ack"})
+		case "ping":
+			s.send(message{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acknowledged {
+				s.close(closeUnauthorized, "Unauthorized")
+
+				return
+			}
+
+			req := request{}
+			if err := json.Unmarshal(msg.Payload, &req); err != nil || msg.ID == "" {
+				s.close(closeInvalidMessage, "Invalid subscribe message received")
+
+				return
+			}
+
+			if !s.start(msg.ID, &req) {
+				s.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+
+				return
+			}
+		case "complete":
+			s.stop(msg.ID)
+		default:
+			s.close(closeInvalidMessage, "Invalid message type received")
+
+			return
+		}
+	}
+}
+
+// start runs the operation with the client-chosen ID unless an operation with the ID is running.
+func (s *session) start(id string, req *request) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.operations[id]; ok {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.operations[id] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, id, req)
+	}()
+
+	return true
+}
+
+// stop cancels the operation with the ID, so its end is not reported to the client that completed it.
+func (s *session) stop(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cancel, ok := s.operations[id]; ok {
+		cancel()
+		delete(s.operations, id)
+	}
+}
+
+// finish forgets the operation with the ID and reports whether the client has not completed it.
+func (s *session) finish(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cancel, ok := s.operations[id]
+	if ok {
+		cancel()
+		delete(s.operations, id)
+	}
+
+	return ok
+}
+
+func (s *session) run(ctx context.Context, id string, req *request) {
+	doc, operation, errs := s.h.prepare(req)
+	if errs != nil {
+		s.log.Info("GraphQL operation rejected", zap.String("id", id), zap.String("error", errs[0].Message))
+		s.finish(id)
+		s.send(message{ID: id, Type: "error", Payload: encode(errs)})
+
+		return
+	}
+
+	params := graphql.ExecuteParams{
+		Schema:        s.h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	}
+	if operation != ast.OperationTypeSubscription {
+		s.send(message{ID: id, Type: "next", Payload: encode(graphql.Execute(params))})
+	} else {
+		s.log.Debug("Subscribed", zap.String("id", id))
+		// The results are drained until the channel is closed, so the executor never blocks on it.
+		for result := range graphql.ExecuteSubscription(params) {
+			if ctx.Err() == nil {
+				s.send(message{ID: id, Type: "next", Payload: encode(result)})
+			}
+		}
+	}
+
+	if s.finish(id) {
+		s.send(message{ID: id, Type: "complete"})
+	}
+}
+
+// send writes the message to the websocket. Messages of concurrent operations are sent one at a time.
+func (s *session) send(msg message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := websocket.JSON.Send(s.ws, &msg); err != nil {
+		s.log.Debug("GraphQL message sending failed", zap.String("type", msg.Type), zap.Error(err))
+	}
+}
+
+// close sends a close frame with the code and reason of the protocol error and closes the connection.
+func (s *session) close(code int, reason string) {
+	s.log.Info("GraphQL websocket closed", zap.Int("code", code), zap.String("reason", reason))
+
+	closeFrame := websocket.Codec{Marshal: func(interface{}) ([]byte, byte, error) {
+		msg := make([]byte, 2)
+		binary.BigEndian.PutUint16(msg, uint16(code))
+
+		return append(msg, reason...), websocket.CloseFrame, nil
+	}}
+
+	s.mutex.Lock()
+	closeFrame.Send(s.ws, nil)
+	s.mutex.Unlock()
+	s.ws.Close()
+}
+
+// encode returns the JSON encoding of a message payload. Payloads are results and errors, which always encode.
+func encode(payload interface{}) json.RawMessage {
+	data, _ := json.Marshal(payload)
+
+	return data
+}
@@ -4,17 +4,17 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
 	"github.com/ivyoverflow/pub-sub/api/internal/model"
 	"github.com/ivyoverflow/pub-sub/api/internal/service"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
 )
 
 // BookController contains all handlers for book.
@@ -31,16 +31,39 @@ func NewBookController(ctx context.Context, svc service.Booker, log *logger.Logg
 
 // AbortWithError sends a error response on error.
 func AbortWithError(rw http.ResponseWriter, statusCode int, err error) {
-	rw.WriteHeader(statusCode)
-	fmt.Fprintf(rw, `{"error": {"statusCode": %d, "message": "%s"}}`, statusCode, err.Error())
+	middleware.WriteError(rw, statusCode, err.Error())
+}
+
+// AbortWithKind sends an error response with the status code and message of the err kind.
+// The underlying cause is never sent to the client.
+func AbortWithKind(rw http.ResponseWriter, err error) {
+	kind := types.KindOf(err)
+	AbortWithError(rw, StatusCode(kind), types.New(kind, "", nil))
+}
+
+// StatusCode returns the HTTP status code of the error kind.
+func StatusCode(kind types.Kind) int {
+	switch kind {
+	case types.KindNotFound:
+		return http.StatusNotFound
+	case types.KindBadRequest, types.KindValidation:
+		return http.StatusBadRequest
+	case types.KindDuplicate:
+		return http.StatusConflict
+	case types.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 // Insert calls Insert service method and process POST requests.
 func (h *BookController) Insert(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Add("content-type", "application/json")
+	log := logger.FromContext(r.Context(), h.log)
 	request := model.Book{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Request body decoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusBadRequest, types.ErrorBadRequest)
 
 		return
@@ -48,85 +71,69 @@ func (h *BookController) Insert(rw http.ResponseWriter, r *http.Request) {
 
 	insertedBook, err := h.svc.Insert(r.Context(), &request)
 	if err != nil {
-		h.log.Error(err.Error())
-		switch err {
-		case types.ErrorDuplicateValue:
-			AbortWithError(rw, http.StatusConflict, types.ErrorDuplicateValue)
+		log.Error("Book insertion failed", zap.Error(err))
+		AbortWithKind(rw, err)
 
-			return
-		case types.ErrorValidation:
-			AbortWithError(rw, http.StatusBadRequest, types.ErrorValidation)
-
-			return
-		default:
-			AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
-
-			return
-		}
+		return
 	}
 
 	rw.WriteHeader(http.StatusCreated)
 
 	if err = json.NewEncoder(rw).Encode(insertedBook); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Response encoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
 
 		return
 	}
 
-	h.log.Debug(fmt.Sprintf("Book <<< %s >>> added", insertedBook.Name))
+	log.Debug("Book added", zap.Stringer("book_id", insertedBook.ID), zap.String("name", insertedBook.Name))
 }
 
 // Get calls Get service method and process GET requests.
 func (h *BookController) Get(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Add("content-type", "application/json")
+	log := logger.FromContext(r.Context(), h.log)
 	vars := mux.Vars(r)
 	bookID, err := uuid.Parse(vars["id"])
 	if err != nil {
-		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
+		AbortWithKind(rw, types.New(types.KindBadRequest, "id", err))
 
 		return
 	}
 
 	book, err := h.svc.Get(r.Context(), bookID)
 	if err != nil {
-		h.log.Error(err.Error())
-		switch err {
-		case types.ErrorNotFound:
-			AbortWithError(rw, http.StatusNotFound, types.ErrorNotFound)
-
-			return
-		default:
-			AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
+		log.Error("Book retrieval failed", zap.Error(err))
+		AbortWithKind(rw, err)
 
-			return
-		}
+		return
 	}
 
 	if err = json.NewEncoder(rw).Encode(&book); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Response encoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
 
 		return
 	}
 
-	h.log.Debug(fmt.Sprintf("Book <<< %s >>> sent", book.Name))
+	log.Debug("Book sent", zap.Stringer("book_id", book.ID), zap.String("name", book.Name))
 }
 
 // Update calls Update service method and process UPDATE requests.
 func (h *BookController) Update(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Add("content-type", "application/json")
+	log := logger.FromContext(r.Context(), h.log)
 	vars := mux.Vars(r)
 	bookID, err := uuid.Parse(vars["id"])
 	if err != nil {
-		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
+		AbortWithKind(rw, types.New(types.KindBadRequest, "id", err))
 
 		return
 	}
 
 	request := model.Book{}
 	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Request body decoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusBadRequest, types.ErrorBadRequest)
 
 		return
@@ -134,73 +141,52 @@ func (h *BookController) Update(rw http.ResponseWriter, r *http.Request) {
 
 	updatedBook, err := h.svc.Update(r.Context(), bookID, &request)
 	if err != nil {
-		h.log.Error(err.Error())
-		switch err {
-		case types.ErrorNotFound:
-			AbortWithError(rw, http.StatusNotFound, types.ErrorNotFound)
-
-			return
-		case types.ErrorDuplicateValue:
-			AbortWithError(rw, http.StatusConflict, types.ErrorDuplicateValue)
-
-			return
-		case types.ErrorValidation:
-			AbortWithError(rw, http.StatusBadRequest, types.ErrorValidation)
-
-			return
-		default:
-			AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
+		log.Error("Book update failed", zap.Error(err))
+		AbortWithKind(rw, err)
 
-			return
-		}
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
 
 	if err = json.NewEncoder(rw).Encode(&updatedBook); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Response encoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
 
 		return
 	}
 
-	h.log.Debug(fmt.Sprintf("Book <<< %s >>> updated", updatedBook.Name))
+	log.Debug("Book updated", zap.Stringer("book_id", updatedBook.ID), zap.String("name", updatedBook.Name))
 }
 
 // Delete calls Delete service method and process DELETE requests.
 func (h *BookController) Delete(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Add("content-type", "application/json")
+	log := logger.FromContext(r.Context(), h.log)
 	vars := mux.Vars(r)
 	bookID, err := uuid.Parse(vars["id"])
 	if err != nil {
-		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
+		AbortWithKind(rw, types.New(types.KindBadRequest, "id", err))
 
 		return
 	}
 
 	deletedBook, err := h.svc.Delete(r.Context(), bookID)
 	if err != nil {
-		h.log.Error(err.Error())
-		switch {
-		case errors.Cause(err) == types.ErrorNotFound:
-			AbortWithError(rw, http.StatusNotFound, types.ErrorNotFound)
+		log.Error("Book deletion failed", zap.Error(err))
+		AbortWithKind(rw, err)
 
-			return
-		default:
-			AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
-
-			return
-		}
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
 
 	if err = json.NewEncoder(rw).Encode(&deletedBook); err != nil {
-		h.log.Error(err.Error())
+		log.Error("Response encoding failed", zap.Error(err))
 		AbortWithError(rw, http.StatusInternalServerError, types.ErrorInternalServerError)
 
 		return
 	}
 
-	h.log.Debug(fmt.Sprintf("Book <<< %s >>> deleted", deletedBook.Name))
+	log.Debug("Book deleted", zap.Stringer("book_id", deletedBook.ID), zap.String("name", deletedBook.Name))
 }
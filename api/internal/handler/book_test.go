@@ -84,7 +84,7 @@ func TestBookHandler_Insert(t *testing.T) {
 		{
 			name:           "Insert method throws an error: duplicate value",
 			inputString:    `{"name":"Concurrency in Go: Tools and Techniques for Developers","dateOfIssue":"2017","author":"Katherine Cox-Buday","description": "...","rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString: `{"error": {"statusCode": 409, "message": "duplicate value"}}`,
+			expectedString: `{"error":{"statusCode":409,"message":"duplicate value"}}`,
 			mockBehaviorIDGenerator: func(gen *svcmock.MockGeneratorService) {
 				gen.EXPECT().GenerateUUID().Return(uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"))
 			},
@@ -106,7 +106,7 @@ func TestBookHandler_Insert(t *testing.T) {
 		{
 			name:                    "Insert method throws an error: invalid JSON value type",
 			inputString:             `{"name":"jfjwoaopfopwa","dateOfIssue":"2017","author":"Katherine Cox-Buday","description": 111,"rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString:          `{"error": {"statusCode": 400, "message": "bad request"}}`,
+			expectedString:          `{"error":{"statusCode":400,"message":"bad request"}}`,
 			mockBehaviorIDGenerator: func(gen *svcmock.MockGeneratorService) {},
 			mockBehaviorBook:        func(ctx context.Context, expected *model.Book, repo *repomock.MockBookerRepository) {},
 			expectedStatusCode:      400,
@@ -114,7 +114,7 @@ func TestBookHandler_Insert(t *testing.T) {
 		{
 			name:                    "Insert method throws an error: invalid JSON body",
 			inputString:             `{}`,
-			expectedString:          `{"error": {"statusCode": 400, "message": "received JSON is invalid"}}`,
+			expectedString:          `{"error":{"statusCode":400,"message":"received JSON is invalid"}}`,
 			mockBehaviorIDGenerator: func(gen *svcmock.MockGeneratorService) {},
 			mockBehaviorBook:        func(ctx context.Context, expected *model.Book, repo *repomock.MockBookerRepository) {},
 			expectedStatusCode:      400,
@@ -122,7 +122,7 @@ func TestBookHandler_Insert(t *testing.T) {
 		{
 			name:           "Insert method throws an error: internal service error",
 			inputString:    `{"name":"Hello World","dateOfIssue":"2017","author":"John Bob","description":"...","rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString: `{"error": {"statusCode": 500, "message": "internal server error"}}`,
+			expectedString: `{"error":{"statusCode":500,"message":"internal server error"}}`,
 			expectedJSON: &model.Book{
 				ID:          uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120005"),
 				Name:        "Hello World",
@@ -180,7 +180,7 @@ func TestBookHandler_Get(t *testing.T) {
 		inputUUID          uuid.UUID
 		expectedJSON       *model.Book
 		expectedString     string
-		mockBehavior       func(context.Context, uuid.UUID, *model.Book, *svcmock.MockBookerService)
+		mockBehavior       func(context.Context, uuid.UUID, *model.Book, *repomock.MockBookerRepository)
 		expectedStatusCode int
 	}{
 		{
@@ -200,26 +200,27 @@ func TestBookHandler_Get(t *testing.T) {
 			expectedString: fmt.Sprintf(`{"id":"%s","name":"Concurrency in Go: Tools and Techniques for Developers","dateOfIssue":"2017","author":"Katherine Cox-Buday","description":"...","rating":"99.99","price":"199.99","inStock":true}
 `,
 				uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003")),
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Get(gomock.Any(), bookID).Return(expected, nil)
 			},
 			expectedStatusCode: 200,
 		},
 		{
-			name:               "Book Get service method throws an error: invalid UUID ID",
-			inputStringID:      "wakldlkawdlklakwdlk",
-			expectedJSON:       nil,
-			expectedString:     `{"error": {"statusCode": 500, "message": "internal server error"}}`,
-			mockBehavior:       func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {},
-			expectedStatusCode: 500,
+			name:           "Book Get service method throws an error: invalid UUID ID",
+			inputStringID:  "wakldlkawdlklakwdlk",
+			expectedJSON:   nil,
+			expectedString: `{"error":{"statusCode":400,"message":"bad request"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
+			},
+			expectedStatusCode: 400,
 		},
 		{
 			name:           "Book Get service method throws an error: book not found",
 			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120002",
 			inputUUID:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
 			expectedJSON:   nil,
-			expectedString: `{"error": {"statusCode": 404, "message": "not found"}}`,
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			expectedString: `{"error":{"statusCode":404,"message":"not found"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
 			},
 			expectedStatusCode: 404,
@@ -238,19 +239,30 @@ func TestBookHandler_Get(t *testing.T) {
 				Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
 				InStock:     true,
 			},
-			expectedString: `{"error": {"statusCode": 500, "message": "internal server error"}}`,
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			expectedString: `{"error":{"statusCode":500,"message":"internal server error"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, errors.New("something went wrong"))
 			},
 			expectedStatusCode: 500,
 		},
+		{
+			name:           "Book Get service method throws an error: storage unavailable",
+			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120003",
+			inputUUID:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
+			expectedJSON:   nil,
+			expectedString: `{"error":{"statusCode":503,"message":"service unavailable"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
+				repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorUnavailable)
+			},
+			expectedStatusCode: 503,
+		},
 	}
 
 	for _, testCase := range testCases {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		repo := svcmock.NewMockBookerService(ctrl)
+		repo := repomock.NewMockBookerRepository(ctrl)
 		gen := svcmock.NewMockGeneratorService(ctrl)
 		ctx := context.Background()
 
@@ -325,7 +337,7 @@ func TestBookHandler_Update(t *testing.T) {
 			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120003",
 			inputUUID:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
 			inputString:    `{"name":"Concurrency in Go: Tools and Techniques for Developers","dateOfIssue":"2017","author":"Katherine Cox-Buday","description": "...","rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString: `{"error": {"statusCode": 409, "message": "duplicate value"}}`,
+			expectedString: `{"error":{"statusCode":409,"message":"duplicate value"}}`,
 			toUpdate: model.Book{
 				Name:        "Concurrency in Go: Tools and Techniques for Developers",
 				DateOfIssue: "2017",
@@ -345,15 +357,15 @@ func TestBookHandler_Update(t *testing.T) {
 			name:               "Book Update service method throws an error: invalid UUID ID",
 			inputStringID:      "wakldlkawdlklakwdlk",
 			expectedJSON:       nil,
-			expectedString:     `{"error": {"statusCode": 500, "message": "internal server error"}}`,
+			expectedString:     `{"error":{"statusCode":400,"message":"bad request"}}`,
 			mockBehavior:       func(context.Context, uuid.UUID, *model.Book, *model.Book, *repomock.MockBookerRepository) {},
-			expectedStatusCode: 500,
+			expectedStatusCode: 400,
 		},
 		{
 			name:               "Book Update service method throws an error: invalid JSON value type",
 			inputStringID:      "7a2f922c-073a-11eb-adc1-0242ac120003",
 			inputString:        `{"name":"jfjwoaopfopwa","dateOfIssue":"2017","author":"Katherine Cox-Buday","description": 111,"rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString:     `{"error": {"statusCode": 400, "message": "bad request"}}`,
+			expectedString:     `{"error":{"statusCode":400,"message":"bad request"}}`,
 			mockBehavior:       func(context.Context, uuid.UUID, *model.Book, *model.Book, *repomock.MockBookerRepository) {},
 			expectedStatusCode: 400,
 		},
@@ -361,7 +373,7 @@ func TestBookHandler_Update(t *testing.T) {
 			name:           "Book Update service method throws an error: invalid JSON body",
 			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120003",
 			inputString:    `{}`,
-			expectedString: `{"error": {"statusCode": 400, "message": "received JSON is invalid"}}`,
+			expectedString: `{"error":{"statusCode":400,"message":"received JSON is invalid"}}`,
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, book *model.Book, expected *model.Book, repo *repomock.MockBookerRepository) {
 			},
 			expectedStatusCode: 400,
@@ -381,7 +393,7 @@ func TestBookHandler_Update(t *testing.T) {
 				Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
 				InStock:     true,
 			},
-			expectedString: `{"error": {"statusCode": 404, "message": "not found"}}`,
+			expectedString: `{"error":{"statusCode":404,"message":"not found"}}`,
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, book *model.Book, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Update(gomock.Any(), bookID, book).Return(expected, types.ErrorNotFound)
 			},
@@ -392,7 +404,7 @@ func TestBookHandler_Update(t *testing.T) {
 			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120003",
 			inputUUID:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
 			inputString:    `{"name":"Concurrency in Go: Tools and Techniques for Developers","dateOfIssue":"2017","author":"Katherine Cox-Buday","description":"...","rating":99.99,"price":199.99,"inStock":true}`,
-			expectedString: `{"error": {"statusCode": 500, "message": "internal server error"}}`,
+			expectedString: `{"error":{"statusCode":500,"message":"internal server error"}}`,
 			toUpdate: model.Book{
 				Name:        "Concurrency in Go: Tools and Techniques for Developers",
 				DateOfIssue: "2017",
@@ -446,7 +458,7 @@ func TestBookHandler_Delete(t *testing.T) {
 		inputUUID          uuid.UUID
 		expectedJSON       *model.Book
 		expectedString     string
-		mockBehavior       func(context.Context, uuid.UUID, *model.Book, *svcmock.MockBookerService)
+		mockBehavior       func(context.Context, uuid.UUID, *model.Book, *repomock.MockBookerRepository)
 		expectedStatusCode int
 	}{
 		{
@@ -466,7 +478,7 @@ func TestBookHandler_Delete(t *testing.T) {
 			expectedString: fmt.Sprintf(`{"id":"%s","name":"Concurrency in Go: Tools and Techniques for Developers","dateOfIssue":"2017","author":"Katherine Cox-Buday","description":"...","rating":"99.99","price":"199.99","inStock":true}
 `,
 				uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003")),
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Delete(gomock.Any(), bookID).Return(expected, nil)
 			},
 			expectedStatusCode: 200,
@@ -475,17 +487,17 @@ func TestBookHandler_Delete(t *testing.T) {
 			name:               "Delete service method throws an error: invalid UUID ID",
 			inputStringID:      "wakldlkawdlklakwdlk",
 			expectedJSON:       nil,
-			expectedString:     `{"error": {"statusCode": 500, "message": "internal server error"}}`,
-			mockBehavior:       func(context.Context, uuid.UUID, *model.Book, *svcmock.MockBookerService) {},
-			expectedStatusCode: 500,
+			expectedString:     `{"error":{"statusCode":400,"message":"bad request"}}`,
+			mockBehavior:       func(context.Context, uuid.UUID, *model.Book, *repomock.MockBookerRepository) {},
+			expectedStatusCode: 400,
 		},
 		{
 			name:           "Book not found",
 			inputStringID:  "7a2f922c-073a-11eb-adc1-0242ac120002",
 			inputUUID:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
 			expectedJSON:   nil,
-			expectedString: `{"error": {"statusCode": 404, "message": "not found"}}`,
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			expectedString: `{"error":{"statusCode":404,"message":"not found"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Delete(gomock.Any(), bookID).Return(expected, types.ErrorNotFound)
 			},
 			expectedStatusCode: 404,
@@ -504,8 +516,8 @@ func TestBookHandler_Delete(t *testing.T) {
 				Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
 				InStock:     true,
 			},
-			expectedString: `{"error": {"statusCode": 500, "message": "internal server error"}}`,
-			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *svcmock.MockBookerService) {
+			expectedString: `{"error":{"statusCode":500,"message":"internal server error"}}`,
+			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *repomock.MockBookerRepository) {
 				repo.EXPECT().Delete(gomock.Any(), bookID).Return(nil, errors.New("something went wrong"))
 			},
 			expectedStatusCode: 500,
@@ -516,7 +528,7 @@ func TestBookHandler_Delete(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		repo := svcmock.NewMockBookerService(ctrl)
+		repo := repomock.NewMockBookerRepository(ctrl)
 		gen := svcmock.NewMockGeneratorService(ctrl)
 		ctx := context.Background()
 
@@ -3,30 +3,132 @@ package types
 
 import "errors"
 
+// Kind classifies an error by the way a client should react to it.
+type Kind int
+
+// Defines all error kinds.
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindBadRequest
+	KindValidation
+	KindDuplicate
+	KindUnavailable
+)
+
+// String returns the client-facing message of the error kind.
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindBadRequest:
+		return "bad request"
+	case KindValidation:
+		return "received JSON is invalid"
+	case KindDuplicate:
+		return "duplicate value"
+	case KindUnavailable:
+		return "service unavailable"
+	default:
+		return "internal server error"
+	}
+}
+
+// Error is an application error. It carries the error kind, the name of the field
+// that caused the error (if known) and the underlying driver or library error.
+//
+// Errors of the same kind match each other with errors.Is, so a classified repository error
+// like &Error{Kind: KindDuplicate, Field: "name", Err: pqErr} matches ErrorDuplicateValue.
+type Error struct {
+	Kind  Kind
+	Field string
+	Err   error
+}
+
+// New returns a new Error of the passed kind.
+func New(kind Kind, field string, err error) *Error {
+	return &Error{kind, field, err}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	message := e.Kind.String()
+	if e.Field != "" {
+		message += " (" + e.Field + ")"
+	}
+
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+
+	return message
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an Error of the same kind without a cause.
+// If target has a field, the fields must be equal too.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.Err != nil {
+		return false
+	}
+
+	return t.Kind == e.Kind && (t.Field == "" || t.Field == e.Field)
+}
+
+// KindOf returns the kind of the first Error in the err chain or KindInternal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	return KindInternal
+}
+
+// FieldOf returns the field of the first Error in the err chain or an empty string if there is none.
+func FieldOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Field
+	}
+
+	return ""
+}
+
 // Defines all custom errors that we will use.
 var (
 	// Returned if the item was not found in the database table.
 	// For example: we have an element id, which is a string, when we pass
 	// this id to the repository's Get () method, and the element with the
 	// passed id is not found, we will get an ErrorNotFound error.
-	ErrorNotFound = errors.New("not found")
+	ErrorNotFound = &Error{Kind: KindNotFound}
 	// Returned if the received JSON body is invalid.
 	// For example: we have a structure that has the following fields:
 	// username, email and password.
 	// If our user sends a JSON body without any of these fields,
 	// we will receive an ErrorBadRequest error.
-	ErrorBadRequest = errors.New("bad request")
+	ErrorBadRequest = &Error{Kind: KindBadRequest}
 	// Returned if internal server logic throws an unknown error.
-	ErrorInternalServerError = errors.New("internal server error")
+	ErrorInternalServerError = &Error{Kind: KindInternal}
 	// Returned if the received JSON body has a duplicate value.
 	// For example: we have a structure that has the following fields:
 	// username, email and password.
 	// If our user send a JSON body with username that has already been
 	// inserted into the database table, we will get an ErrorDuplicateValue error.
-	ErrorDuplicateValue            = errors.New("duplicate value")
+	ErrorDuplicateValue = &Error{Kind: KindDuplicate}
+	// Returned if the database is down or keeps failing with transient errors.
+	// For example: the circuit breaker around a repository is open,
+	// so the request fails fast with an ErrorUnavailable error.
+	ErrorUnavailable               = &Error{Kind: KindUnavailable}
+	ErrorValidation                = &Error{Kind: KindValidation}
 	ErrorMongoConnectionRefused    = errors.New("mongodb connection refused")
 	ErrorPostgresConnectionRefused = errors.New("postgres connection refused")
 	ErrorMigrate                   = errors.New("migrations cannot start")
-	ErrorValidation                = errors.New("received JSON is invalid")
 	ErrorConfigInitialization      = errors.New("config initialization failed")
+	ErrorUnknownStorage            = errors.New("unknown storage backend")
 )
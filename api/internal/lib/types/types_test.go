@@ -0,0 +1,101 @@
+package types_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+)
+
+func TestError_Is(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{
+			name:     "Same kind",
+			err:      types.New(types.KindNotFound, "id", sql.ErrNoRows),
+			target:   types.ErrorNotFound,
+			expected: true,
+		},
+		{
+			name:     "Wrapped error",
+			err:      pkgerrors.Wrap(types.New(types.KindDuplicate, "name", errors.New("E11000")), "insert"),
+			target:   types.ErrorDuplicateValue,
+			expected: true,
+		},
+		{
+			name:     "Same field",
+			err:      types.New(types.KindDuplicate, "name", nil),
+			target:   types.New(types.KindDuplicate, "name", nil),
+			expected: true,
+		},
+		{
+			name:     "Different field",
+			err:      types.New(types.KindDuplicate, "id", nil),
+			target:   types.New(types.KindDuplicate, "name", nil),
+			expected: false,
+		},
+		{
+			name:     "Different kind",
+			err:      types.New(types.KindNotFound, "id", nil),
+			target:   types.ErrorDuplicateValue,
+			expected: false,
+		},
+		{
+			name:     "Underlying cause",
+			err:      types.New(types.KindNotFound, "id", sql.ErrNoRows),
+			target:   sql.ErrNoRows,
+			expected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, errors.Is(testCase.err, testCase.target))
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		expectedKind  types.Kind
+		expectedField string
+	}{
+		{
+			name:          "Typed error",
+			err:           types.New(types.KindValidation, "rating", nil),
+			expectedKind:  types.KindValidation,
+			expectedField: "rating",
+		},
+		{
+			name:          "Wrapped typed error",
+			err:           pkgerrors.Wrap(types.ErrorUnavailable, "get"),
+			expectedKind:  types.KindUnavailable,
+			expectedField: "",
+		},
+		{
+			name:          "Unknown error",
+			err:           errors.New("something went wrong"),
+			expectedKind:  types.KindInternal,
+			expectedField: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedKind, types.KindOf(testCase.err))
+			assert.Equal(t, testCase.expectedField, types.FieldOf(testCase.err))
+		})
+	}
+}
@@ -47,3 +47,19 @@ func (d Decimal) MarshalBSON() (data []byte, err error) {
 	}
 	return bson.Marshal(bson.M{"decimal": string(bts)})
 }
+
+// BookEventType is the kind of a book change.
+type BookEventType string
+
+// Defines all book event types.
+const (
+	BookInserted BookEventType = "inserted"
+	BookUpdated  BookEventType = "updated"
+	BookDeleted  BookEventType = "deleted"
+)
+
+// BookEvent is a change of a book. Book is the book after the change, or before it for deletions.
+type BookEvent struct {
+	Type BookEventType
+	Book Book
+}
@@ -0,0 +1,282 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ivyoverflow/pub-sub/api/bookpb"
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Defines page sizes of List.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// BookServer implements bookpb.BookServiceServer on top of service.Booker.
+type BookServer struct {
+	bookpb.UnimplementedBookServiceServer
+	svc service.Booker
+	log *logger.Logger
+	// shutdown is closed by Shutdown to end the watch streams.
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// NewBookServer returns a new configured BookServer object.
+func NewBookServer(svc service.Booker, log *logger.Logger) *BookServer {
+	return &BookServer{svc: svc, log: log, shutdown: make(chan struct{})}
+}
+
+// Shutdown ends the watch streams with codes.Unavailable, so that a graceful stop of the gRPC server does not
+// wait for them and their clients watch again on another server. It may be called more than once.
+func (s *BookServer) Shutdown() {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+}
+
+// Insert calls Insert service method.
+func (s *BookServer) Insert(ctx context.Context, request *bookpb.InsertRequest) (*bookpb.Book, error) {
+	log := logger.FromContext(ctx, s.log)
+	book, err := fromProto(request.GetBook())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	insertedBook, err := s.svc.Insert(ctx, book)
+	if err != nil {
+		log.Error("Book insertion failed", zap.Error(err))
+
+		return nil, statusOf(err)
+	}
+
+	log.Debug("Book added", zap.Stringer("book_id", insertedBook.ID), zap.String("name", insertedBook.Name))
+
+	return toProto(insertedBook), nil
+}
+
+// Get calls Get service method.
+func (s *BookServer) Get(ctx context.Context, request *bookpb.GetRequest) (*bookpb.Book, error) {
+	bookID, err := parseID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := s.svc.Get(ctx, bookID)
+	if err != nil {
+		logger.FromContext(ctx, s.log).Error("Book retrieval failed", zap.Error(err))
+
+		return nil, statusOf(err)
+	}
+
+	return toProto(book), nil
+}
+
+// Update calls Update service method.
+func (s *BookServer) Update(ctx context.Context, request *bookpb.UpdateRequest) (*bookpb.Book, error) {
+	bookID, err := parseID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := fromProto(request.GetBook())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	updatedBook, err := s.svc.Update(ctx, bookID, book)
+	if err != nil {
+		logger.FromContext(ctx, s.log).Error("Book update failed", zap.Error(err))
+
+		return nil, statusOf(err)
+	}
+
+	return toProto(updatedBook), nil
+}
+
+// Delete calls Delete service method.
+func (s *BookServer) Delete(ctx context.Context, request *bookpb.DeleteRequest) (*bookpb.Book, error) {
+	bookID, err := parseID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	deletedBook, err := s.svc.Delete(ctx, bookID)
+	if err != nil {
+		logger.FromContext(ctx, s.log).Error("Book deletion failed", zap.Error(err))
+
+		return nil, statusOf(err)
+	}
+
+	return toProto(deletedBook), nil
+}
+
+// List calls List service method. The page token is the ID of the last book of the previous page.
+func (s *BookServer) List(ctx context.Context, request *bookpb.ListRequest) (*bookpb.ListResponse, error) {
+	pageSize := int(request.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	after := uuid.Nil
+	if token := request.GetPageToken(); token != "" {
+		var err error
+		if after, err = uuid.Parse(token); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+
+	books, err := s.svc.List(ctx, after, pageSize)
+	if err != nil {
+		logger.FromContext(ctx, s.log).Error("Book listing failed", zap.Error(err))
+
+		return nil, statusOf(err)
+	}
+
+	response := &bookpb.ListResponse{Books: make([]*bookpb.Book, 0, len(books))}
+	for i := range books {
+		response.Books = append(response.Books, toProto(&books[i]))
+	}
+
+	if len(books) == pageSize {
+		response.NextPageToken = books[len(books)-1].ID.String()
+	}
+
+	return response, nil
+}
+
+// WatchBooks streams book events until the client cancels the call or the server shuts down. A client that falls
+// behind is disconnected with codes.ResourceExhausted and may watch again.
+func (s *BookServer) WatchBooks(_ *bookpb.WatchBooksRequest, stream bookpb.BookService_WatchBooksServer) error {
+	ctx := stream.Context()
+	events := s.svc.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "the server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				return status.Error(codes.ResourceExhausted, "the watcher fell behind the book events")
+			}
+
+			if err := stream.Send(&bookpb.BookEvent{Type: eventType(event.Type), Book: toProto(&event.Book)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// statusOf returns the gRPC status of the service error. Like the HTTP handlers,
+// it carries the message of the error kind and never the underlying cause.
+func statusOf(err error) error {
+	kind := types.KindOf(err)
+	message := types.New(kind, types.FieldOf(err), nil).Error()
+	switch kind {
+	case types.KindNotFound:
+		return status.Error(codes.NotFound, message)
+	case types.KindBadRequest, types.KindValidation:
+		return status.Error(codes.InvalidArgument, message)
+	case types.KindDuplicate:
+		return status.Error(codes.AlreadyExists, message)
+	case types.KindUnavailable:
+		return status.Error(codes.Unavailable, message)
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+func parseID(id string) (uuid.UUID, error) {
+	bookID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid book ID")
+	}
+
+	return bookID, nil
+}
+
+func toProto(book *model.Book) *bookpb.Book {
+	return &bookpb.Book{
+		Id:          book.ID.String(),
+		Name:        book.Name,
+		DateOfIssue: book.DateOfIssue,
+		Author:      book.Author,
+		Description: book.Description,
+		Rating:      book.Rating.String(),
+		Price:       book.Price.String(),
+		InStock:     book.InStock,
+	}
+}
+
+// fromProto converts a request book. Its ID is ignored, like the ID of REST request bodies.
+func fromProto(book *bookpb.Book) (*model.Book, error) {
+	if book == nil {
+		return nil, types.ErrorBadRequest
+	}
+
+	rating, err := parseDecimal(book.GetRating(), "rating")
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := parseDecimal(book.GetPrice(), "price")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Book{
+		Name:        book.GetName(),
+		DateOfIssue: book.GetDateOfIssue(),
+		Author:      book.GetAuthor(),
+		Description: book.GetDescription(),
+		Rating:      rating,
+		Price:       price,
+		InStock:     book.GetInStock(),
+	}, nil
+}
+
+// parseDecimal parses a decimal field. An empty value is zero, so the service reports the field as missing.
+func parseDecimal(value, field string) (model.Decimal, error) {
+	if value == "" {
+		return model.Decimal{}, nil
+	}
+
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return model.Decimal{}, types.New(types.KindBadRequest, field, nil)
+	}
+
+	return model.Decimal{Decimal: d}, nil
+}
+
+func eventType(t model.BookEventType) bookpb.BookEvent_Type {
+	switch t {
+	case model.BookInserted:
+		return bookpb.BookEvent_TYPE_INSERTED
+	case model.BookUpdated:
+		return bookpb.BookEvent_TYPE_UPDATED
+	case model.BookDeleted:
+		return bookpb.BookEvent_TYPE_DELETED
+	default:
+		return bookpb.BookEvent_TYPE_UNSPECIFIED
+	}
+}
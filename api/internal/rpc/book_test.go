@@ -0,0 +1,283 @@
+package rpc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ivyoverflow/pub-sub/api/bookpb"
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/rpc"
+	svcmock "github.com/ivyoverflow/pub-sub/api/internal/service/mock"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func testBook() *model.Book {
+	return &model.Book{
+		ID:          uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
+		Name:        "Concurrency in Go",
+		DateOfIssue: "2017",
+		Author:      "Katherine Cox-Buday",
+		Description: "...",
+		Rating:      model.Decimal{Decimal: decimal.NewFromFloat(99.99)},
+		Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
+		InStock:     true,
+	}
+}
+
+// dial serves the book service on top of svc in memory and returns a client of it.
+func dial(t *testing.T, svc *svcmock.MockBookerService, cfg *auth.Config) bookpb.BookServiceClient {
+	client, _ := serve(t, svc, cfg)
+
+	return client
+}
+
+// serve serves the book service on top of svc in memory and returns a client of it and the served book server.
+func serve(t *testing.T, svc *svcmock.MockBookerService, cfg *auth.Config) (bookpb.BookServiceClient, *rpc.BookServer) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	authn, err := auth.New(cfg)
+	if err != nil {
+		t.Fatalf("Authenticator initialization throws an error: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(rpc.UnaryInterceptor(authn, log)),
+		grpc.StreamInterceptor(rpc.StreamInterceptor(authn, log)),
+	)
+	books := rpc.NewBookServer(svc, log)
+	bookpb.RegisterBookServiceServer(server, books)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatalf("Dialing throws an error: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return bookpb.NewBookServiceClient(conn), books
+}
+
+func TestBookServer_Insert(t *testing.T) {
+	testCases := []struct {
+		name         string
+		book         *bookpb.Book
+		mockBehavior func(*svcmock.MockBookerService)
+		expectedCode codes.Code
+	}{
+		{
+			name: "OK",
+			book: &bookpb.Book{Name: "Concurrency in Go", DateOfIssue: "2017", Author: "Katherine Cox-Buday",
+				Description: "...", Rating: "99.99", Price: "199.99", InStock: true},
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(testBook(), nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "Duplicate value",
+			book: &bookpb.Book{Name: "Concurrency in Go", Rating: "99.99", Price: "199.99"},
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, types.New(types.KindDuplicate, "name", nil))
+			},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name: "Validation error",
+			book: &bookpb.Book{Name: "Concurrency in Go"},
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, types.New(types.KindValidation, "rating", nil))
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Invalid decimal",
+			book:         &bookpb.Book{Name: "Concurrency in Go", Rating: "high"},
+			mockBehavior: func(svc *svcmock.MockBookerService) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Missing book",
+			mockBehavior: func(svc *svcmock.MockBookerService) {},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ctrl := gomock.NewController(t)
+		svc := svcmock.NewMockBookerService(ctrl)
+		testCase.mockBehavior(svc)
+
+		book, err := dial(t, svc, &auth.Config{}).Insert(context.Background(), &bookpb.InsertRequest{Book: testCase.book})
+		assert.Equal(t, testCase.expectedCode, status.Code(err), testCase.name)
+		if err == nil {
+			assert.Equal(t, "7a2f922c-073a-11eb-adc1-0242ac120003", book.GetId())
+			assert.Equal(t, "99.99", book.GetRating())
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestBookServer_Get(t *testing.T) {
+	testCases := []struct {
+		name         string
+		id           string
+		mockBehavior func(*svcmock.MockBookerService)
+		expectedCode codes.Code
+	}{
+		{
+			name: "OK",
+			id:   "7a2f922c-073a-11eb-adc1-0242ac120003",
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(testBook(), nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "Not found",
+			id:   "7a2f922c-073a-11eb-adc1-0242ac120003",
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(nil, types.ErrorNotFound)
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "Unavailable",
+			id:   "7a2f922c-073a-11eb-adc1-0242ac120003",
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(nil, types.ErrorUnavailable)
+			},
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name:         "Invalid ID",
+			id:           "1",
+			mockBehavior: func(svc *svcmock.MockBookerService) {},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ctrl := gomock.NewController(t)
+		svc := svcmock.NewMockBookerService(ctrl)
+		testCase.mockBehavior(svc)
+
+		_, err := dial(t, svc, &auth.Config{}).Get(context.Background(), &bookpb.GetRequest{Id: testCase.id})
+		assert.Equal(t, testCase.expectedCode, status.Code(err), testCase.name)
+		ctrl.Finish()
+	}
+}
+
+func TestBookServer_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	book := testBook()
+	svc := svcmock.NewMockBookerService(ctrl)
+	gomock.InOrder(
+		svc.EXPECT().List(gomock.Any(), uuid.Nil, 1).Return([]model.Book{*book}, nil),
+		svc.EXPECT().List(gomock.Any(), book.ID, 1).Return([]model.Book{}, nil),
+	)
+
+	client := dial(t, svc, &auth.Config{})
+	page, err := client.List(context.Background(), &bookpb.ListRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetBooks(), 1)
+	assert.Equal(t, book.ID.String(), page.GetNextPageToken())
+
+	page, err = client.List(context.Background(), &bookpb.ListRequest{PageSize: 1, PageToken: page.GetNextPageToken()})
+	assert.NoError(t, err)
+	assert.Empty(t, page.GetBooks())
+	assert.Empty(t, page.GetNextPageToken())
+
+	_, err = client.List(context.Background(), &bookpb.ListRequest{PageToken: "page 2"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBookServer_WatchBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	events := make(chan model.BookEvent, 1)
+	svc := svcmock.NewMockBookerService(ctrl)
+	svc.EXPECT().Watch(gomock.Any()).Return((<-chan model.BookEvent)(events))
+
+	stream, err := dial(t, svc, &auth.Config{}).WatchBooks(context.Background(), &bookpb.WatchBooksRequest{})
+	assert.NoError(t, err)
+
+	events <- model.BookEvent{Type: model.BookUpdated, Book: *testBook()}
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, bookpb.BookEvent_TYPE_UPDATED, event.GetType())
+	assert.Equal(t, "Concurrency in Go", event.GetBook().GetName())
+
+	// The service closes the channel of a watcher that fell behind.
+	close(events)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestBookServer_Shutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := svcmock.NewMockBookerService(ctrl)
+	svc.EXPECT().Watch(gomock.Any()).Return((<-chan model.BookEvent)(make(chan model.BookEvent)))
+
+	client, books := serve(t, svc, &auth.Config{})
+	stream, err := client.WatchBooks(context.Background(), &bookpb.WatchBooksRequest{})
+	assert.NoError(t, err)
+
+	// Shutdown ends the watch streams, so that the graceful stop of the server does not wait for them.
+	books.Shutdown()
+	books.Shutdown()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestBookServer_authentication(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	if err := ioutil.WriteFile(keysFile, []byte(`[{"key":"reader-key","subject":"reader","roles":["reader"]}]`), 0o600); err != nil {
+		t.Fatalf("Writing the keys file throws an error: %v", err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := svcmock.NewMockBookerService(ctrl)
+	svc.EXPECT().Get(gomock.Any(), testBook().ID).Return(testBook(), nil)
+	client := dial(t, svc, &auth.Config{APIKeysFile: keysFile})
+
+	_, err := client.Get(context.Background(), &bookpb.GetRequest{Id: testBook().ID.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "reader-key")
+	_, err = client.Get(ctx, &bookpb.GetRequest{Id: testBook().ID.String()})
+	assert.NoError(t, err)
+
+	_, err = client.Delete(ctx, &bookpb.DeleteRequest{Id: testBook().ID.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,42 @@
+// Package rpc implements the gRPC server of the books service.
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config contains fields that will be used to configure the gRPC server.
+type Config struct {
+	Addr            string        `envconfig:"ADDR" default:"localhost"`
+	Port            string        `envconfig:"GRPC_PORT" default:"9090"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	HealthTimeout   time.Duration `envconfig:"HEALTH_TIMEOUT" default:"2s"`
+	// HealthInterval is the interval between readiness checks reported by the gRPC health service.
+	HealthInterval time.Duration `envconfig:"GRPC_HEALTH_INTERVAL" default:"10s"`
+}
+
+// ErrInvalidHealthInterval is returned when the health interval is not positive.
+var ErrInvalidHealthInterval = errors.New("GRPC_HEALTH_INTERVAL must be positive")
+
+// NewConfig returns a new configured Config object, or an error if the environment sets invalid values.
+func NewConfig() (*Config, error) {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, err
+	}
+
+	if config.HealthInterval <= 0 {
+		return nil, ErrInvalidHealthInterval
+	}
+
+	return &config, nil
+}
+
+// GetConnectionURI returns the formatted connection URI.
+func (cfg *Config) GetConnectionURI() string {
+	return fmt.Sprintf("%s:%s", cfg.Addr, cfg.Port)
+}
@@ -0,0 +1,38 @@
+package rpc_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/rpc"
+)
+
+func TestNewConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		interval string
+		expected string
+	}{
+		{name: "OK", interval: "1s"},
+		{name: "Zero interval", interval: "0s", expected: rpc.ErrInvalidHealthInterval.Error()},
+		{name: "Invalid interval", interval: "soon",
+			expected: "envconfig.Process: assigning GRPC_HEALTH_INTERVAL to HealthInterval: converting 'soon' to type time.Duration. details: time: invalid duration \"soon\""},
+	}
+
+	defer os.Unsetenv("GRPC_HEALTH_INTERVAL")
+	for _, testCase := range testCases {
+		os.Setenv("GRPC_HEALTH_INTERVAL", testCase.interval)
+		cfg, err := rpc.NewConfig()
+		if testCase.expected != "" {
+			assert.EqualError(t, err, testCase.expected, testCase.name)
+
+			continue
+		}
+
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, "localhost:9090", cfg.GetConnectionURI(), testCase.name)
+		}
+	}
+}
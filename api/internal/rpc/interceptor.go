@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// serviceName is the full name of the book service, the prefix of its method names.
+const serviceName = "/pubsub.book.v1.BookService/"
+
+// readerMethods are the methods of the book service that the reader role may call.
+// The other methods require the editor role.
+func readerMethods() map[string]bool {
+	return map[string]bool{
+		serviceName + "Get":        true,
+		serviceName + "List":       true,
+		serviceName + "WatchBooks": true,
+	}
+}
+
+// authorize authenticates the call by the "authorization" or "x-api-key" metadata, like the HTTP middleware
+// authenticates requests by their headers, and checks the role required by the method. Methods of other
+// services, like health and reflection, are not authenticated. It returns the context with the principal
+// or, on failure, the passed context.
+func authorize(ctx context.Context, authn *auth.Authenticator, method string, log *logger.Logger) (context.Context, error) {
+	if !strings.HasPrefix(method, serviceName) {
+		return ctx, nil
+	}
+
+	request := &http.Request{Header: make(http.Header), URL: &url.URL{}}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+
+	principal, err := authn.Authenticate(request)
+	if err != nil {
+		logger.FromContext(ctx, log).Info("Authentication failed", zap.Error(err))
+
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	role := auth.RoleEditor
+	if readerMethods()[method] {
+		role = auth.RoleReader
+	}
+
+	if !principal.HasRole(role) {
+		return ctx, status.Errorf(codes.PermissionDenied, "the %s role is required", role)
+	}
+
+	ctx = auth.NewContext(ctx, principal)
+
+	return logger.WithFields(ctx, log, zap.String("user", principal.Subject)), nil
+}
+
+// UnaryInterceptor returns an interceptor that authorizes and logs unary calls.
+func UnaryInterceptor(authn *auth.Authenticator, log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		started := time.Now()
+		ctx = logger.WithFields(ctx, log, zap.String("method", info.FullMethod))
+		ctx, err := authorize(ctx, authn, info.FullMethod, log)
+		var resp interface{}
+		if err == nil {
+			resp, err = handler(ctx, req)
+		}
+
+		logCall(ctx, log, started, err)
+
+		return resp, err
+	}
+}
+
+// StreamInterceptor returns an interceptor that authorizes and logs streaming calls.
+func StreamInterceptor(authn *auth.Authenticator, log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+		ctx := logger.WithFields(stream.Context(), log, zap.String("method", info.FullMethod))
+		ctx, err := authorize(ctx, authn, info.FullMethod, log)
+		if err == nil {
+			err = handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		}
+
+		logCall(ctx, log, started, err)
+
+		return err
+	}
+}
+
+// logCall logs the status code and duration of a call, like the access log of HTTP requests.
+func logCall(ctx context.Context, log *logger.Logger, started time.Time, err error) {
+	logger.FromContext(ctx, log).Info("Call served",
+		zap.Stringer("code", status.Code(err)), zap.Duration("duration", time.Since(started)))
+}
+
+// contextStream is a server stream with the context of the interceptor.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
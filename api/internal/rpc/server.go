@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/ivyoverflow/pub-sub/api/bookpb"
+	"github.com/ivyoverflow/pub-sub/api/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	platformhealth "github.com/ivyoverflow/pub-sub/platform/health"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Server represents the gRPC server.
+type Server struct {
+	grpcServer *grpc.Server
+	books      *BookServer
+	health     *health.Server
+	checker    *platformhealth.Checker
+	log        *logger.Logger
+	cfg        *Config
+}
+
+// New returns a new configured Server object that serves the book service on top of svc, the gRPC health
+// service and the reflection service. Book service calls are authenticated by authn: readers may get, list
+// and watch books and editors may also insert, update and delete them. The health service reports the
+// book service as serving while every check passes. It returns an error if the configuration is invalid.
+func New(svc service.Booker, checks map[string]platformhealth.Check, authn *auth.Authenticator,
+	log *logger.Logger) (*Server, error) {
+	cfg, err := NewConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := platformhealth.NewChecker(cfg.HealthTimeout)
+	for name, check := range checks {
+		checker.Add(name, check)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryInterceptor(authn, log)),
+		grpc.StreamInterceptor(StreamInterceptor(authn, log)),
+	)
+	healthServer := health.NewServer()
+	books := NewBookServer(svc, log)
+	bookpb.RegisterBookServiceServer(grpcServer, books)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+
+	return &Server{grpcServer: grpcServer, books: books, health: healthServer, checker: checker, log: log, cfg: cfg}, nil
+}
+
+// Run starts the server. When ctx is done, the server ends the watch streams, stops accepting new calls
+// and waits up to the shutdown timeout for the other in-flight calls to finish before it cancels them.
+func (srv *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", srv.cfg.GetConnectionURI())
+	if err != nil {
+		return err
+	}
+
+	go srv.reportHealth(ctx)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	srv.health.Shutdown()
+	srv.books.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		srv.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(srv.cfg.ShutdownTimeout):
+		srv.grpcServer.Stop()
+	}
+
+	return nil
+}
+
+// reportHealth sets the serving status of the book service from the checks every health interval until ctx is done.
+func (srv *Server) reportHealth(ctx context.Context) {
+	ticker := time.NewTicker(srv.cfg.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if response := srv.checker.Check(ctx); response.Status != platformhealth.StatusOK {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		srv.health.SetServingStatus("", servingStatus)
+		srv.health.SetServingStatus(bookpb.BookService_ServiceDesc.ServiceName, servingStatus)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,28 +3,47 @@ package server
 
 import (
 	"fmt"
-	"os"
-	"sync"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
 )
 
-// Config contains addr and port fields that will be used to configure the server.
+// Config contains fields that will be used to configure the server.
 type Config struct {
-	Addr string `envconfig:"ADDR" default:"localhost"`
-	Port string `envconfig:"PORT" default:"8080"`
+	Addr            string        `envconfig:"ADDR" default:"localhost"`
+	Port            string        `envconfig:"PORT" default:"8080"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	HealthTimeout   time.Duration `envconfig:"HEALTH_TIMEOUT" default:"2s"`
+	TracingExporter string        `envconfig:"TRACING_EXPORTER" default:""`
+	// CORSAllowedOrigins is a comma-separated list of origins allowed to call the /v1 routes, "*" allows any.
+	CORSAllowedOrigins []string      `envconfig:"CORS_ALLOWED_ORIGINS" default:""`
+	CORSMaxAge         time.Duration `envconfig:"CORS_MAX_AGE" default:"10m"`
+	MaxBodyBytes       int64         `envconfig:"MAX_BODY_BYTES" default:"1048576"`
+	RequestTimeout     time.Duration `envconfig:"REQUEST_TIMEOUT" default:"10s"`
+	// TrustedProxies is a comma-separated list of proxy networks whose X-Forwarded-For headers are trusted.
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES" default:""`
+	// RateLimit is the limit of every client on every /v1 route, like "100/m". Empty disables it.
+	RateLimit string `envconfig:"RATE_LIMIT" default:""`
+	// RateLimitRoutes overrides RateLimit per route, like "POST /v1/book/:10/m,DELETE /v1/book/{id}:10/m".
+	RateLimitRoutes map[string]string `envconfig:"RATE_LIMIT_ROUTES" default:""`
+	// RateLimitRedisURL is the Redis server that shares limits between replicas. Empty keeps them in memory.
+	RateLimitRedisURL string `envconfig:"RATE_LIMIT_REDIS_URL" default:""`
+	// IdempotencyTTL is how long the responses of requests with the Idempotency-Key header are replayed.
+	IdempotencyTTL time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
+	// IdempotencyRedisURL is the Redis server that shares responses between replicas. Empty keeps them in memory.
+	IdempotencyRedisURL string `envconfig:"IDEMPOTENCY_REDIS_URL" default:""`
+	// ValidateResponses replaces responses that do not match the OpenAPI document with 500. It is meant for tests.
+	ValidateResponses bool `envconfig:"OPENAPI_VALIDATE_RESPONSES" default:"false"`
 }
 
-// NewConfig returrns a new configured ServerConfig object.
-func NewConfig() *Config {
+// NewConfig returns a new configured Config object, or an error if the environment sets invalid values.
+func NewConfig() (*Config, error) {
 	var config Config
-	var once sync.Once
-	once.Do(func() {
-		config = Config{
-			Addr: os.Getenv("ADDR"),
-			Port: os.Getenv("PORT"),
-		}
-	})
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, err
+	}
 
-	return &config
+	return &config, nil
 }
 
 // GetConnectionURI returns the formatted connection URI.
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// Spec returns the OpenAPI document of the /v1 routes. It must be updated together with the routes and handlers.
+func Spec() *openapi.Document {
+	bookID := &openapi.Parameter{
+		Name:        "id",
+		In:          "path",
+		Description: "The ID of the book.",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+	book := &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("Book"))}
+
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "pub-sub books API",
+			Description: "Readers may get books; editors may also insert, update and delete them.",
+			Version:     "1.0.0",
+		},
+		Paths: map[string]openapi.PathItem{
+			"/v1/book/": {
+				"post": {
+					OperationID: "insertBook",
+					Summary:     "Insert a book",
+					Description: "Requires the editor role. A retry with the same Idempotency-Key returns the original response.",
+					Tags:        []string{"books"},
+					Parameters: []*openapi.Parameter{{
+						Name:        idempotency.Header,
+						In:          "header",
+						Description: "A client-chosen key that makes retries of the request safe.",
+						Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(255)},
+					}},
+					RequestBody: book,
+					Responses: responses(map[string]*openapi.Response{
+						"201": {Description: "The inserted book.", Content: openapi.JSON(openapi.Ref("Book"))},
+						"409": errorResponse("A book with the same name exists or a request with the key is in progress."),
+						"422": errorResponse("The idempotency key was used with a different request."),
+					}),
+				},
+			},
+			"/v1/book/{id}": {
+				"get": {
+					OperationID: "getBook",
+					Summary:     "Get a book",
+					Description: "Requires the reader role.",
+					Tags:        []string{"books"},
+					Parameters:  []*openapi.Parameter{bookID},
+					Responses: responses(map[string]*openapi.Response{
+						"200": {Description: "The book.", Content: openapi.JSON(openapi.Ref("Book"))},
+						"404": errorResponse("The book does not exist."),
+					}),
+				},
+				"put": {
+					OperationID: "updateBook",
+					Summary:     "Update a book",
+					Description: "Requires the editor role.",
+					Tags:        []string{"books"},
+					Parameters:  []*openapi.Parameter{bookID},
+					RequestBody: book,
+					Responses: responses(map[string]*openapi.Response{
+						"200": {Description: "The updated book.", Content: openapi.JSON(openapi.Ref("Book"))},
+						"404": errorResponse("The book does not exist."),
+						"409": errorResponse("A book with the same name exists."),
+					}),
+				},
+				"delete": {
+					OperationID: "deleteBook",
+					Summary:     "Delete a book",
+					Description: "Requires the editor role.",
+					Tags:        []string{"books"},
+					Parameters:  []*openapi.Parameter{bookID},
+					Responses: responses(map[string]*openapi.Response{
+						"200": {Description: "The deleted book.", Content: openapi.JSON(openapi.Ref("Book"))},
+						"404": errorResponse("The book does not exist."),
+					}),
+				},
+			},
+		},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{
+				"Book": {
+					Type:     "object",
+					Required: []string{"name", "dateOfIssue", "author", "description", "rating", "price", "inStock"},
+					Properties: map[string]*openapi.Schema{
+						"id": {
+							Type:        "string",
+							Format:      "uuid",
+							Description: "Generated on insertion.",
+							ReadOnly:    true,
+						},
+						"name":        {Type: "string", MinLength: openapi.Int(1), Example: "Concurrency in Go"},
+						"dateOfIssue": {Type: "string", MinLength: openapi.Int(1), Example: "2017"},
+						"author":      {Type: "string", MinLength: openapi.Int(1), Example: "Katherine Cox-Buday"},
+						"description": {Type: "string", MinLength: openapi.Int(1)},
+						"rating":      openapi.Ref("Decimal"),
+						"price":       openapi.Ref("Decimal"),
+						"inStock":     {Type: "boolean"},
+					},
+				},
+				"Decimal": {
+					Description: "A decimal number. It is accepted as a number or a string and returned as a string.",
+					AnyOf: []*openapi.Schema{
+						{Type: "number"},
+						{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]+)?$`},
+					},
+					Example: "99.99",
+				},
+				"Error": errorSchema(),
+			},
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"apiKey": {Type: "apiKey", Name: auth.APIKeyHeader, In: "header"},
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{"apiKey": {}}, {"bearer": {}}},
+	}
+}
+
+// responses adds the errors every /v1 route may respond with to the responses of an operation.
+func responses(specific map[string]*openapi.Response) map[string]*openapi.Response {
+	all := map[string]*openapi.Response{
+		"400":     errorResponse("The request does not match the specification."),
+		"401":     errorResponse("The credentials are missing or invalid."),
+		"403":     errorResponse("The role of the credentials is not allowed to call the route."),
+		"413":     errorResponse("The request body is too large."),
+		"429":     errorResponse("The rate limit is exceeded."),
+		"503":     errorResponse("The storage is unavailable or the request timed out."),
+		"default": errorResponse("An unexpected error."),
+	}
+	for status, response := range specific {
+		all[status] = response
+	}
+
+	return all
+}
+
+func errorResponse(description string) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.JSON(openapi.Ref("Error"))}
+}
+
+// errorSchema returns the schema of the error responses of all handlers and middlewares.
+func errorSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type:     "object",
+		Required: []string{"error"},
+		Properties: map[string]*openapi.Schema{
+			"error": {
+				Type:     "object",
+				Required: []string{"statusCode", "message"},
+				Properties: map[string]*openapi.Schema{
+					"statusCode": {Type: "integer"},
+					"message":    {Type: "string"},
+				},
+			},
+		},
+	}
+}
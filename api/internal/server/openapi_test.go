@@ -0,0 +1,146 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/handler"
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/server"
+	svcmock "github.com/ivyoverflow/pub-sub/api/internal/service/mock"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// TestSpec checks that the responses of the book handlers match the OpenAPI document.
+func TestSpec(t *testing.T) {
+	bookID := uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003")
+	book := &model.Book{
+		ID:          bookID,
+		Name:        "Concurrency in Go",
+		DateOfIssue: "2017",
+		Author:      "Katherine Cox-Buday",
+		Description: "...",
+		Rating:      model.Decimal{Decimal: decimal.NewFromFloat(99.99)},
+		Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
+		InStock:     true,
+	}
+	body := `{"name":"Concurrency in Go","dateOfIssue":"2017","author":"Katherine Cox-Buday","description":"...","rating":99.99,"price":"199.99","inStock":true}`
+
+	testCases := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		mockBehavior       func(*svcmock.MockBookerService)
+		expectedStatusCode int
+	}{
+		{
+			name:   "Insert OK",
+			method: http.MethodPost,
+			path:   "/v1/book/",
+			body:   body,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(book, nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:   "Insert duplicate",
+			method: http.MethodPost,
+			path:   "/v1/book/",
+			body:   body,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, types.ErrorDuplicateValue)
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "Insert without a required field",
+			method:             http.MethodPost,
+			path:               "/v1/book/",
+			body:               `{"name":"Concurrency in Go"}`,
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Get OK",
+			method: http.MethodGet,
+			path:   "/v1/book/" + bookID.String(),
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), bookID).Return(book, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "Get not found",
+			method: http.MethodGet,
+			path:   "/v1/book/" + bookID.String(),
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Get with an invalid ID",
+			method:             http.MethodGet,
+			path:               "/v1/book/1",
+			mockBehavior:       func(svc *svcmock.MockBookerService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Update OK",
+			method: http.MethodPut,
+			path:   "/v1/book/" + bookID.String(),
+			body:   body,
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Update(gomock.Any(), bookID, gomock.Any()).Return(book, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "Delete OK",
+			method: http.MethodDelete,
+			path:   "/v1/book/" + bookID.String(),
+			mockBehavior: func(svc *svcmock.MockBookerService) {
+				svc.EXPECT().Delete(gomock.Any(), bookID).Return(book, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	for _, testCase := range testCases {
+		ctrl := gomock.NewController(t)
+		svc := svcmock.NewMockBookerService(ctrl)
+		testCase.mockBehavior(svc)
+
+		handl := handler.NewBookController(context.Background(), svc, log)
+		router := mux.NewRouter()
+		router.Use(mux.MiddlewareFunc(openapi.Middleware(server.Spec(), true, log)))
+		router.HandleFunc("/v1/book/", handl.Insert).Methods("POST")
+		router.HandleFunc("/v1/book/{id}", handl.Get).Methods("GET")
+		router.HandleFunc("/v1/book/{id}", handl.Update).Methods("PUT")
+		router.HandleFunc("/v1/book/{id}", handl.Delete).Methods("DELETE")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(testCase.method, testCase.path, bytes.NewBufferString(testCase.body)))
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		ctrl.Finish()
+	}
+}
@@ -2,41 +2,197 @@
 package server
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
+	"github.com/ivyoverflow/pub-sub/api/internal/graph"
 	"github.com/ivyoverflow/pub-sub/api/internal/handler"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/health"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/metrics"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
 )
 
 // Server represents application server.
 type Server struct {
 	httpServer *http.Server
 	handl      *handler.BookController
+	graph      *graph.Handler
+	checker    *health.Checker
+	reg        *prometheus.Registry
+	log        *logger.Logger
+	authn      *auth.Authenticator
+	limiter    ratelimit.Store
+	responses  idempotency.Store
+	cfg        *Config
 }
 
-// New returns a new configured Server object.
-func New(handl *handler.BookController) *Server {
-	cfg := NewConfig()
+// New returns a new configured Server object. The checks are reported by the /readyz route
+// and the metrics of reg are exported by the /metrics route. Every request is logged by a child of log
+// that carries the request ID and route. The /v1 routes are authenticated by authn: readers may get books
+// and editors may also insert, update and delete them. Every client is limited per route by the buckets of limiter.
+// Book insertions with the Idempotency-Key header are stored in responses and replayed to retries.
+// The /graphql route serves graph with the same authentication and limits as the /v1 routes.
+// It returns an error if the configuration is invalid.
+func New(handl *handler.BookController, graph *graph.Handler, checks map[string]health.Check, reg *prometheus.Registry,
+	authn *auth.Authenticator, limiter ratelimit.Store, responses idempotency.Store, log *logger.Logger) (*Server, error) {
+	cfg, err := NewConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := health.NewChecker(cfg.HealthTimeout)
+	for name, check := range checks {
+		checker.Add(name, check)
+	}
+
+	if shared, ok := limiter.(*ratelimit.RedisStore); ok {
+		checker.Add("ratelimit", shared.Ping)
+	}
+
+	if shared, ok := responses.(*idempotency.RedisStore); ok {
+		checker.Add("idempotency", shared.Ping)
+	}
 
 	return &Server{
 		httpServer: &http.Server{
 			Addr: cfg.GetConnectionURI(),
 		},
-		handl: handl,
-	}
+		handl:     handl,
+		graph:     graph,
+		checker:   checker,
+		reg:       reg,
+		log:       log,
+		authn:     authn,
+		limiter:   limiter,
+		responses: responses,
+		cfg:       cfg,
+	}, nil
 }
 
-// Run configures routes and starts the server.
-func (srv *Server) Run() error {
+// Run configures routes and starts the server. When ctx is done, the server stops accepting
+// new connections and waits up to the shutdown timeout for in-flight requests to finish.
+// The OpenAPI document of the /v1 routes is served by /openapi.json and rendered by /docs,
+// and /v1 requests that do not match it are rejected. GraphQL subscriptions are served by /graphql websockets.
+func (srv *Server) Run(ctx context.Context) error {
+	trustedProxies, err := middleware.ParseCIDRs(srv.cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	defaultLimit, err := ratelimit.ParseLimit(srv.cfg.RateLimit)
+	if err != nil {
+		return err
+	}
+
+	routeLimits, err := ratelimit.ParseLimits(srv.cfg.RateLimitRoutes)
+	if err != nil {
+		return err
+	}
+
+	rateLimitRule := func(r *http.Request) (string, ratelimit.Limit) {
+		route := r.Method + " " + routeTemplate(r)
+		if limit, ok := routeLimits[route]; ok {
+			return route, limit
+		}
+
+		return route, defaultLimit
+	}
+
 	router := mux.NewRouter()
+	router.Use(logger.Middleware(srv.log, routeTemplate))
+	router.HandleFunc("/healthz", srv.checker.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", srv.checker.Readyz).Methods("GET")
+	router.Handle("/metrics", metrics.Handler(srv.reg)).Methods("GET")
+	spec := Spec()
+	router.Handle("/openapi.json", openapi.Handler(spec)).Methods("GET")
+	router.Handle("/docs", openapi.DocsHandler("/openapi.json")).Methods("GET")
+	// Tracing and metrics are outermost, so they observe the requests rejected by the other middlewares
+	// and the panics recovered into 500. The timeout buffers responses, so it wraps every route but the GraphQL
+	// websocket.
+	httpMetrics := metrics.NewHTTP(srv.reg)
+	common := middleware.Chain(
+		middleware.RealIP(trustedProxies),
+		middleware.Middleware(otelmux.Middleware("api")),
+		httpMetrics.Middleware(routeTemplate),
+		middleware.Recovery(srv.log),
+		middleware.AccessLog(srv.log),
+		middleware.CORS(&middleware.CORSConfig{
+			AllowedOrigins: srv.cfg.CORSAllowedOrigins,
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+			AllowedHeaders: []string{
+				"Content-Type", "Authorization", auth.APIKeyHeader, logger.RequestIDHeader, idempotency.Header,
+			},
+			ExposedHeaders: []string{
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", idempotency.ReplayedHeader,
+			},
+			MaxAge: srv.cfg.CORSMaxAge,
+		}),
+		auth.Middleware(srv.authn, srv.log),
+		ratelimit.Middleware(srv.limiter, rateLimitRule, srv.log),
+		middleware.BodyLimit(srv.cfg.MaxBodyBytes),
+	)
 	booksSubrouter := router.PathPrefix("/v1").Subrouter()
-	booksSubrouter.HandleFunc("/book/", srv.handl.Insert).Methods("POST")
-	booksSubrouter.HandleFunc("/book/{id}", srv.handl.Get).Methods("GET")
-	booksSubrouter.HandleFunc("/book/{id}", srv.handl.Update).Methods("PUT")
-	booksSubrouter.HandleFunc("/book/{id}", srv.handl.Delete).Methods("DELETE")
+	booksSubrouter.Use(mux.MiddlewareFunc(middleware.Chain(
+		common,
+		middleware.Timeout(srv.cfg.RequestTimeout),
+		middleware.Gzip(),
+		openapi.Middleware(spec, srv.cfg.ValidateResponses, srv.log),
+	)))
+	// Preflight requests must match a route to reach the CORS middleware.
+	booksSubrouter.PathPrefix("/").Methods("OPTIONS").HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	reader, editor := auth.RequireRole(auth.RoleReader), auth.RequireRole(auth.RoleEditor)
+	idempotent := idempotency.Middleware(srv.responses, srv.cfg.IdempotencyTTL, srv.log)
+	booksSubrouter.Handle("/book/", editor(idempotent(http.HandlerFunc(srv.handl.Insert)))).Methods("POST")
+	booksSubrouter.Handle("/book/{id}", reader(http.HandlerFunc(srv.handl.Get))).Methods("GET")
+	booksSubrouter.Handle("/book/{id}", editor(http.HandlerFunc(srv.handl.Update))).Methods("PUT")
+	booksSubrouter.Handle("/book/{id}", editor(http.HandlerFunc(srv.handl.Delete))).Methods("DELETE")
+	// Mutations check the editor role themselves.
+	graphSubrouter := router.PathPrefix("/graphql").Subrouter()
+	graphSubrouter.Use(mux.MiddlewareFunc(common))
+	graphSubrouter.Handle("", reader(srv.graph.Subscriptions())).HeadersRegexp("Upgrade", "(?i)^websocket$")
+	graphSubrouter.Handle("", middleware.Chain(middleware.Timeout(srv.cfg.RequestTimeout), middleware.Gzip(), reader)(srv.graph)).
+		Methods("GET", "POST")
+	graphSubrouter.Methods("OPTIONS").HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
 
 	srv.httpServer.Handler = router
 
-	return srv.httpServer.ListenAndServe()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), srv.cfg.ShutdownTimeout)
+	defer cancel()
+
+	return srv.httpServer.Shutdown(shutdownCtx)
+}
+
+// routeTemplate returns the path template of the matched route, for example "/v1/book/{id}".
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return r.URL.Path
 }
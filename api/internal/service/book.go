@@ -5,49 +5,97 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 
-	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
 	"github.com/ivyoverflow/pub-sub/api/internal/model"
 	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
+// tracerName is the name of the tracer that records service calls.
+const tracerName = "github.com/ivyoverflow/pub-sub/api/internal/service"
+
 // BookController implements all service methods for book.
 type BookController struct {
-	repo storage.Booker
-	gen  Generator
+	repo     storage.Booker
+	gen      Generator
+	watchers *watchers
 }
 
 // NewBookController returns a new configured BookController object.
 func NewBookController(repo storage.Booker, gen Generator) *BookController {
-	return &BookController{repo, gen}
+	return &BookController{repo, gen, newWatchers()}
 }
 
 // Insert calls Insert repository method.
-func (s *BookController) Insert(ctx context.Context, book *model.Book) (*model.Book, error) {
-	if err := Validate(book); err != nil {
-		return nil, types.ErrorValidation
+func (s *BookController) Insert(ctx context.Context, book *model.Book) (insertedBook *model.Book, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BookController.Insert")
+	defer func() { tracing.End(span, err) }()
+
+	if err = Validate(book); err != nil {
+		return nil, err
 	}
 
 	book.ID = s.gen.GenerateUUID()
+	if insertedBook, err = s.repo.Insert(ctx, book); err != nil {
+		return nil, err
+	}
 
-	return s.repo.Insert(ctx, book)
+	s.watchers.notify(model.BookEvent{Type: model.BookInserted, Book: *insertedBook})
+
+	return insertedBook, nil
 }
 
 // Get calls Get repository method.
-func (s *BookController) Get(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+func (s *BookController) Get(ctx context.Context, bookID uuid.UUID) (book *model.Book, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BookController.Get")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.Get(ctx, bookID)
 }
 
 // Update calls Update repository method.
-func (s *BookController) Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (*model.Book, error) {
-	if err := Validate(book); err != nil {
-		return nil, types.ErrorValidation
+func (s *BookController) Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (updatedBook *model.Book, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BookController.Update")
+	defer func() { tracing.End(span, err) }()
+
+	if err = Validate(book); err != nil {
+		return nil, err
+	}
+
+	if updatedBook, err = s.repo.Update(ctx, bookID, book); err != nil {
+		return nil, err
 	}
 
-	return s.repo.Update(ctx, bookID, book)
+	s.watchers.notify(model.BookEvent{Type: model.BookUpdated, Book: *updatedBook})
+
+	return updatedBook, nil
 }
 
 // Delete calls Delete repository method.
-func (s *BookController) Delete(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
-	return s.repo.Delete(ctx, bookID)
+func (s *BookController) Delete(ctx context.Context, bookID uuid.UUID) (deletedBook *model.Book, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BookController.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	if deletedBook, err = s.repo.Delete(ctx, bookID); err != nil {
+		return nil, err
+	}
+
+	s.watchers.notify(model.BookEvent{Type: model.BookDeleted, Book: *deletedBook})
+
+	return deletedBook, nil
+}
+
+// List calls List repository method.
+func (s *BookController) List(ctx context.Context, after uuid.UUID, limit int) (books []model.Book, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BookController.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.List(ctx, after, limit)
+}
+
+// Watch returns a channel that receives the changes of books made through the controller until ctx is done.
+// The channel is closed when ctx is done or when the receiver falls too far behind.
+func (s *BookController) Watch(ctx context.Context) <-chan model.BookEvent {
+	return s.watchers.add(ctx)
 }
@@ -46,7 +46,7 @@ func TestBookService_Insert(t *testing.T) {
 				InStock:     true,
 			},
 			mockBehavior: func(ctx context.Context, book *model.Book, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Insert(ctx, book).Return(expected, nil)
+				repo.EXPECT().Insert(gomock.Any(), book).Return(expected, nil)
 			},
 			expectedError: nil,
 		},
@@ -64,7 +64,7 @@ func TestBookService_Insert(t *testing.T) {
 			},
 			expected: nil,
 			mockBehavior: func(ctx context.Context, book *model.Book, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Insert(ctx, book).Return(expected, types.ErrorDuplicateValue)
+				repo.EXPECT().Insert(gomock.Any(), book).Return(expected, types.ErrorDuplicateValue)
 			},
 			expectedError: types.ErrorDuplicateValue,
 		},
@@ -96,7 +96,7 @@ func TestBookService_Insert(t *testing.T) {
 
 		insertedBook, err := svc.Insert(ctx, &testCase.input)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, insertedBook)
@@ -125,7 +125,7 @@ func TestBookService_Get(t *testing.T) {
 				InStock:     true,
 			},
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Get(ctx, bookID).Return(expected, nil)
+				repo.EXPECT().Get(gomock.Any(), bookID).Return(expected, nil)
 			},
 			expectedError: nil,
 		},
@@ -134,7 +134,7 @@ func TestBookService_Get(t *testing.T) {
 			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"),
 			expected: nil,
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Get(ctx, bookID).Return(expected, types.ErrorNotFound)
+				repo.EXPECT().Get(gomock.Any(), bookID).Return(expected, types.ErrorNotFound)
 			},
 			expectedError: types.ErrorNotFound,
 		},
@@ -153,7 +153,7 @@ func TestBookService_Get(t *testing.T) {
 
 		insertedBook, err := svc.Get(ctx, testCase.input)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, insertedBook)
@@ -192,7 +192,7 @@ func TestBookService_Update(t *testing.T) {
 				InStock:     true,
 			},
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, book *model.Book, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Update(ctx, bookID, book).Return(expected, nil)
+				repo.EXPECT().Update(gomock.Any(), bookID, book).Return(expected, nil)
 			},
 			expectedError: nil,
 		},
@@ -211,7 +211,7 @@ func TestBookService_Update(t *testing.T) {
 			},
 			expected: nil,
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, book *model.Book, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Update(ctx, bookID, book).Return(expected, types.ErrorNotFound)
+				repo.EXPECT().Update(gomock.Any(), bookID, book).Return(expected, types.ErrorNotFound)
 			},
 			expectedError: types.ErrorNotFound,
 		},
@@ -243,7 +243,7 @@ func TestBookService_Update(t *testing.T) {
 
 		insertedBook, err := svc.Update(ctx, testCase.input, &testCase.toUpdate)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, insertedBook)
@@ -272,7 +272,7 @@ func TestBookService_Delete(t *testing.T) {
 				InStock:     true,
 			},
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Delete(ctx, bookID).Return(expected, nil)
+				repo.EXPECT().Delete(gomock.Any(), bookID).Return(expected, nil)
 			},
 			expectedError: nil,
 		},
@@ -281,7 +281,7 @@ func TestBookService_Delete(t *testing.T) {
 			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"),
 			expected: nil,
 			mockBehavior: func(ctx context.Context, bookID uuid.UUID, expected *model.Book, repo *mock.MockBookerRepository) {
-				repo.EXPECT().Delete(ctx, bookID).Return(expected, types.ErrorNotFound)
+				repo.EXPECT().Delete(gomock.Any(), bookID).Return(expected, types.ErrorNotFound)
 			},
 			expectedError: types.ErrorNotFound,
 		},
@@ -300,9 +300,49 @@ func TestBookService_Delete(t *testing.T) {
 
 		insertedBook, err := svc.Delete(ctx, testCase.input)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, insertedBook)
 	}
 }
+
+func TestBookService_Watch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	book := &model.Book{
+		ID:          uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+		Name:        "Concurrency in Go: Tools and Techniques for Developers",
+		DateOfIssue: "2017",
+		Author:      "Katherine Cox-Buday",
+		Description: `...`,
+		Rating:      model.Decimal{Decimal: decimal.NewFromFloat(99.99)},
+		Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
+		InStock:     true,
+	}
+	repo := mock.NewMockBookerRepository(ctrl)
+	repo.EXPECT().Update(gomock.Any(), book.ID, book).Return(book, nil)
+	repo.EXPECT().Delete(gomock.Any(), book.ID).Return(nil, types.ErrorNotFound)
+	repo.EXPECT().Delete(gomock.Any(), book.ID).Return(book, nil)
+
+	svc := service.NewBookController(repo, service.NewUUIDGenerator())
+	ctx, cancel := context.WithCancel(context.Background())
+	events := svc.Watch(ctx)
+
+	_, err := svc.Update(context.Background(), book.ID, book)
+	assert.NoError(t, err)
+	_, err = svc.Delete(context.Background(), book.ID)
+	assert.Equal(t, types.ErrorNotFound, err)
+	_, err = svc.Delete(context.Background(), book.ID)
+	assert.NoError(t, err)
+
+	// Failed changes are not watched.
+	assert.Equal(t, model.BookEvent{Type: model.BookUpdated, Book: *book}, <-events)
+	assert.Equal(t, model.BookEvent{Type: model.BookDeleted, Book: *book}, <-events)
+
+	cancel()
+	for range events {
+		t.Errorf("No more events are expected")
+	}
+}
@@ -97,6 +97,35 @@ func (mr *MockBookerServiceMockRecorder) Delete(ctx, bookID interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBookerService)(nil).Delete), ctx, bookID)
 }
 
+// List mocks base method
+func (m *MockBookerService) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, after, limit)
+	ret0, _ := ret[0].([]model.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockBookerServiceMockRecorder) List(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockBookerService)(nil).List), ctx, after, limit)
+}
+
+// Watch mocks base method
+func (m *MockBookerService) Watch(ctx context.Context) <-chan model.BookEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", ctx)
+	ret0, _ := ret[0].(<-chan model.BookEvent)
+	return ret0
+}
+
+// Watch indicates an expected call of Watch
+func (mr *MockBookerServiceMockRecorder) Watch(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockBookerService)(nil).Watch), ctx)
+}
+
 // MockGeneratorService is a mock of Generator interface
 type MockGeneratorService struct {
 	ctrl     *gomock.Controller
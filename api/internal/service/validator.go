@@ -1,16 +1,31 @@
 package service
 
 import (
+	"errors"
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/validator"
 
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
 	"github.com/ivyoverflow/pub-sub/api/internal/model"
 )
 
 // Validate checks if received struct is valid.
+// It returns a types.KindValidation error with the JSON name of the first invalid field.
 func Validate(book *model.Book) error {
 	vld := validator.New()
+	vld.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+
 	if err := vld.Struct(book); err != nil {
-		return err
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+			return types.New(types.KindValidation, fieldErrs[0].Field(), err)
+		}
+
+		return types.New(types.KindValidation, "", err)
 	}
 
 	return nil
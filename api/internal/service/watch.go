@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+)
+
+// watchBuffer is the number of events a watcher may fall behind before it is disconnected.
+const watchBuffer = 64
+
+// watchers broadcasts book events to the channels of watchers.
+type watchers struct {
+	mutex    sync.Mutex
+	channels map[chan model.BookEvent]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{channels: make(map[chan model.BookEvent]struct{})}
+}
+
+// add returns a channel that receives events until ctx is done. The channel is closed then,
+// or earlier if the watcher falls behind by more than watchBuffer events.
+func (w *watchers) add(ctx context.Context) <-chan model.BookEvent {
+	channel := make(chan model.BookEvent, watchBuffer)
+
+	w.mutex.Lock()
+	w.channels[channel] = struct{}{}
+	w.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.remove(channel)
+	}()
+
+	return channel
+}
+
+// notify sends the event to every watcher without blocking.
+func (w *watchers) notify(event model.BookEvent) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for channel := range w.channels {
+		select {
+		case channel <- event:
+		default:
+			delete(w.channels, channel)
+			close(channel)
+		}
+	}
+}
+
+func (w *watchers) remove(channel chan model.BookEvent) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.channels[channel]; ok {
+		delete(w.channels, channel)
+		close(channel)
+	}
+}
@@ -0,0 +1,116 @@
+// Package backend contains the logic to open book repositories by their storage name.
+package backend
+
+import (
+	"context"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/instrumented"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/mongo"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/postgres"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/replicated"
+	"github.com/ivyoverflow/pub-sub/platform/health"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Defines supported storage names.
+const (
+	Mongo    = "mongo"
+	Postgres = "postgres"
+)
+
+// Storage is an opened book repository that owns its database connections.
+type Storage struct {
+	storage.Booker
+	closers []func(context.Context) error
+	checks  map[string]health.Check
+}
+
+// Checks returns connectivity checks of the storage databases by storage name.
+func (s *Storage) Checks() map[string]health.Check {
+	return s.checks
+}
+
+// Close closes all database connections of the storage.
+func (s *Storage) Close(ctx context.Context) error {
+	var firstErr error
+	for _, closeFunc := range s.closers {
+		if err := closeFunc(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Open connects to the named storage and returns its book repository.
+func Open(ctx context.Context, name string) (*Storage, error) {
+	switch name {
+	case Mongo:
+		db, err := mongo.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Storage{
+			Booker:  mongo.NewBookRepository(db),
+			closers: []func(context.Context) error{db.Close},
+			checks:  map[string]health.Check{Mongo: db.Ping},
+		}, nil
+	case Postgres:
+		db, err := postgres.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		closePool := func(context.Context) error {
+			return db.Close()
+		}
+
+		return &Storage{
+			Booker:  postgres.NewBookRepository(db),
+			closers: []func(context.Context) error{closePool},
+			checks:  map[string]health.Check{Postgres: db.PingContext},
+		}, nil
+	default:
+		return nil, types.ErrorUnknownStorage
+	}
+}
+
+// New opens the primary storage and, if a secondary storage is configured,
+// wraps both into a replicated book repository. Operations of every storage
+// are recorded in metrics under the storage name.
+func New(ctx context.Context, cfg *Config, metrics *instrumented.Metrics, log *logger.Logger) (*Storage, error) {
+	primary, err := Open(ctx, cfg.Primary)
+	if err != nil {
+		return nil, err
+	}
+
+	primary.Booker = instrumented.NewBookRepository(primary.Booker, cfg.Primary, metrics)
+	if cfg.Secondary == "" {
+		return primary, nil
+	}
+
+	secondary, err := Open(ctx, cfg.Secondary)
+	if err != nil {
+		primary.Close(ctx)
+
+		return nil, err
+	}
+
+	secondary.Booker = instrumented.NewBookRepository(secondary.Booker, cfg.Secondary, metrics)
+
+	checks := make(map[string]health.Check, len(primary.checks)+len(secondary.checks))
+	for _, opened := range []*Storage{primary, secondary} {
+		for name, check := range opened.checks {
+			checks[name] = check
+		}
+	}
+
+	return &Storage{
+		Booker:  replicated.NewBookRepository(primary, secondary, cfg.ShadowRead, log),
+		closers: append(primary.closers, secondary.closers...),
+		checks:  checks,
+	}, nil
+}
@@ -0,0 +1,21 @@
+// Package backend contains the logic to open book repositories by their storage name.
+package backend
+
+import "github.com/kelseyhightower/envconfig"
+
+// Config contains fields that will be used to choose storage backends.
+type Config struct {
+	Primary    string `envconfig:"STORAGE" default:"mongo"`
+	Secondary  string `envconfig:"STORAGE_SECONDARY" default:""`
+	ShadowRead bool   `envconfig:"STORAGE_SHADOW_READ" default:"false"`
+}
+
+// NewConfig returns a new configured Config object, or an error if the environment sets invalid values.
+func NewConfig() (*Config, error) {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
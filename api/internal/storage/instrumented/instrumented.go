@@ -0,0 +1,122 @@
+// Package instrumented contains a book repository decorator that records operations as Prometheus metrics and OpenTelemetry spans.
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// tracerName is the name of the tracer that records repository operations.
+const tracerName = "github.com/ivyoverflow/pub-sub/api/internal/storage/instrumented"
+
+// Metrics contains repository metrics shared by all instrumented repositories.
+type Metrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewMetrics returns a new Metrics object registered in reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "storage_operation_duration_seconds",
+			Help:    "Latency of book repository operations.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"backend", "operation", "result"}),
+	}
+
+	reg.MustRegister(m.duration)
+
+	return m
+}
+
+// BookRepository records latencies and spans of repository operations under the backend name.
+type BookRepository struct {
+	repo    storage.Booker
+	backend string
+	metrics *Metrics
+}
+
+// NewBookRepository returns a new configured BookRepository object.
+func NewBookRepository(repo storage.Booker, backend string, metrics *Metrics) *BookRepository {
+	return &BookRepository{repo, backend, metrics}
+}
+
+// Insert calls Insert repository method.
+func (r *BookRepository) Insert(ctx context.Context, book *model.Book) (*model.Book, error) {
+	ctx, end := r.start(ctx, "insert")
+	insertedBook, err := r.repo.Insert(ctx, book)
+	end(err)
+
+	return insertedBook, err
+}
+
+// Get calls Get repository method.
+func (r *BookRepository) Get(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	ctx, end := r.start(ctx, "get")
+	book, err := r.repo.Get(ctx, bookID)
+	end(err)
+
+	return book, err
+}
+
+// Update calls Update repository method.
+func (r *BookRepository) Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (*model.Book, error) {
+	ctx, end := r.start(ctx, "update")
+	updatedBook, err := r.repo.Update(ctx, bookID, book)
+	end(err)
+
+	return updatedBook, err
+}
+
+// Delete calls Delete repository method.
+func (r *BookRepository) Delete(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	ctx, end := r.start(ctx, "delete")
+	deletedBook, err := r.repo.Delete(ctx, bookID)
+	end(err)
+
+	return deletedBook, err
+}
+
+// List calls List repository method.
+func (r *BookRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	ctx, end := r.start(ctx, "list")
+	books, err := r.repo.List(ctx, after, limit)
+	end(err)
+
+	return books, err
+}
+
+// start starts a span of the operation and returns a function that ends the span
+// and records the operation latency. The result label is "ok" or the kind of the returned error,
+// so that client errors like "not found" can be told apart from database failures.
+func (r *BookRepository) start(ctx context.Context, operation string) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, r.backend+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.backend),
+			attribute.String("db.operation", operation),
+		))
+
+	return ctx, func(err error) {
+		tracing.End(span, err)
+
+		result := "ok"
+		if err != nil {
+			result = types.KindOf(err).String()
+		}
+
+		r.metrics.duration.WithLabelValues(r.backend, operation, result).Observe(time.Since(start).Seconds())
+	}
+}
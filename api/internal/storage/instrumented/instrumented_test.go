@@ -0,0 +1,56 @@
+package instrumented_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/instrumented"
+	mock "github.com/ivyoverflow/pub-sub/api/internal/storage/mock"
+	"github.com/ivyoverflow/pub-sub/platform/metrics"
+)
+
+func TestBookRepository_Get(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "OK",
+			expected: `storage_operation_duration_seconds_count{backend="postgres",operation="get",result="ok"} 1`,
+		},
+		{
+			name:     "Not found",
+			err:      types.ErrorNotFound,
+			expected: `storage_operation_duration_seconds_count{backend="postgres",operation="get",result="not found"} 1`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			bookID := uuid.New()
+			repo := mock.NewMockBookerRepository(ctrl)
+			repo.EXPECT().Get(gomock.Any(), bookID).Return(&model.Book{ID: bookID}, testCase.err)
+
+			reg := metrics.NewRegistry()
+			instrumentedRepo := instrumented.NewBookRepository(repo, "postgres", instrumented.NewMetrics(reg))
+			_, err := instrumentedRepo.Get(context.Background(), bookID)
+			assert.Equal(t, testCase.err, err)
+
+			recorder := httptest.NewRecorder()
+			metrics.Handler(reg).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			assert.Contains(t, recorder.Body.String(), testCase.expected)
+		})
+	}
+}
@@ -96,3 +96,18 @@ func (mr *MockBookerRepositoryMockRecorder) Delete(ctx, bookID interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBookerRepository)(nil).Delete), ctx, bookID)
 }
+
+// List mocks base method
+func (m *MockBookerRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, after, limit)
+	ret0, _ := ret[0].([]model.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockBookerRepositoryMockRecorder) List(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockBookerRepository)(nil).List), ctx, after, limit)
+}
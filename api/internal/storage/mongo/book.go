@@ -3,13 +3,12 @@ package mongo
 
 import (
 	"context"
-	"strings"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
 	"github.com/ivyoverflow/pub-sub/api/internal/model"
 )
 
@@ -32,12 +31,7 @@ func (r *BookRepository) Collection() *mongo.Collection {
 func (r *BookRepository) Insert(ctx context.Context, book *model.Book) (*model.Book, error) {
 	_, err := r.Collection().InsertOne(ctx, book)
 	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "E11000"):
-			return nil, types.ErrorDuplicateValue
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return r.Get(ctx, book.ID)
@@ -49,12 +43,7 @@ func (r *BookRepository) Get(ctx context.Context, bookID uuid.UUID) (*model.Book
 	receivedBook := model.Book{}
 	err := r.Collection().FindOne(ctx, filter).Decode(&receivedBook)
 	if err != nil {
-		switch err {
-		case mongo.ErrNoDocuments:
-			return nil, types.ErrorNotFound
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return &receivedBook, nil
@@ -68,14 +57,7 @@ func (r *BookRepository) Update(ctx context.Context, bookID uuid.UUID, book *mod
 	updatedBook := model.Book{}
 	err := r.Collection().FindOneAndUpdate(ctx, filter, fieldsToUpdate).Decode(&updatedBook)
 	if err != nil {
-		switch {
-		case err == mongo.ErrNoDocuments:
-			return nil, types.ErrorNotFound
-		case strings.Contains(err.Error(), "E11000"):
-			return nil, types.ErrorDuplicateValue
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return r.Get(ctx, updatedBook.ID)
@@ -87,13 +69,25 @@ func (r *BookRepository) Delete(ctx context.Context, bookID uuid.UUID) (*model.B
 	deletedBook := model.Book{}
 	err := r.Collection().FindOneAndDelete(ctx, filter).Decode(&deletedBook)
 	if err != nil {
-		switch err {
-		case mongo.ErrNoDocuments:
-			return nil, types.ErrorNotFound
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return &deletedBook, nil
 }
+
+// List receives up to limit books from the books collection ordered by ID, starting after the passed book ID.
+func (r *BookRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	filter := bson.D{{Key: "id", Value: bson.D{{Key: "$gt", Value: after}}}}
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.Collection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	books := make([]model.Book, 0, limit)
+	if err = cursor.All(ctx, &books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
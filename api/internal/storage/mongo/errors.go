@@ -0,0 +1,56 @@
+// Package mongo contains MongoDB repository implementation.
+package mongo
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+)
+
+// isDuplicateKey reports whether code is one of MongoDB duplicate key error codes.
+func isDuplicateKey(code int) bool {
+	return code == 11000 || code == 11001 || code == 12582
+}
+
+// classify converts MongoDB driver errors into typed errors. Unknown errors are returned as is.
+func classify(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return types.New(types.KindNotFound, "id", err)
+	}
+
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, we := range writeErr.WriteErrors {
+			if isDuplicateKey(we.Code) {
+				return types.New(types.KindDuplicate, indexField(we.Message), err)
+			}
+		}
+	}
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && isDuplicateKey(int(cmdErr.Code)) {
+		return types.New(types.KindDuplicate, indexField(cmdErr.Message), err)
+	}
+
+	return err
+}
+
+// indexField returns the field of a single field index mentioned in a duplicate key error message,
+// for example "... index: name_1 dup key: ..." -> "name".
+func indexField(message string) string {
+	const marker = "index: "
+	start := strings.Index(message, marker)
+	if start < 0 {
+		return ""
+	}
+
+	index := strings.Fields(message[start+len(marker):])
+	if len(index) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(index[0], "_1")
+}
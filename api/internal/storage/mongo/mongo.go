@@ -28,15 +28,24 @@ func New(ctx context.Context) (*DB, error) {
 		return nil, types.ErrorMongoConnectionRefused
 	}
 
-	if err := clt.Ping(ctx, readpref.Primary()); err != nil {
+	db := &DB{clt.Database(cfg.Name)}
+	if err := db.Ping(ctx); err != nil {
 		return nil, types.ErrorMongoConnectionRefused
 	}
 
-	db := clt.Database(cfg.Name)
-
-	if err := RunMigration(ctx, db); err != nil {
+	if err := RunMigration(ctx, db.Database); err != nil {
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	return db, nil
+}
+
+// Ping checks that the primary MongoDB server is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Client().Ping(ctx, readpref.Primary())
+}
+
+// Close disconnects the MongoDB client.
+func (db *DB) Close(ctx context.Context) error {
+	return db.Client().Disconnect(ctx)
 }
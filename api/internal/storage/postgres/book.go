@@ -3,12 +3,9 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
-	"strings"
 
 	"github.com/google/uuid"
 
-	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
 	"github.com/ivyoverflow/pub-sub/api/internal/model"
 )
 
@@ -31,12 +28,7 @@ func (r *BookRepository) Insert(ctx context.Context, book *model.Book) (*model.B
 		book.Description, book.Rating, book.Price, book.InStock)
 	if err := row.Scan(&insertedBook.ID, &insertedBook.Name, &insertedBook.DateOfIssue, &insertedBook.Author,
 		&insertedBook.Description, &insertedBook.Rating, &insertedBook.Price, &insertedBook.InStock); err != nil {
-		switch {
-		case strings.Contains(err.Error(), "unique constraint"):
-			return nil, types.ErrorDuplicateValue
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return &insertedBook, nil
@@ -49,12 +41,7 @@ func (r *BookRepository) Get(ctx context.Context, bookID uuid.UUID) (*model.Book
 	row := r.pg.QueryRowContext(ctx, query, bookID)
 	if err := row.Scan(&book.ID, &book.Name, &book.DateOfIssue, &book.Author, &book.Description,
 		&book.Rating, &book.Price, &book.InStock); err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			return nil, types.ErrorNotFound
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return &book, nil
@@ -68,14 +55,7 @@ func (r *BookRepository) Update(ctx context.Context, bookID uuid.UUID, book *mod
 	row := r.pg.QueryRowContext(ctx, query, book.Name, book.DateOfIssue, book.Author, book.Description, book.Rating, book.Price, book.InStock, bookID)
 	if err := row.Scan(&updatedBook.ID, &updatedBook.Name, &updatedBook.DateOfIssue, &updatedBook.Author, &updatedBook.Description,
 		&updatedBook.Rating, &updatedBook.Price, &updatedBook.InStock); err != nil {
-		switch {
-		case err == sql.ErrNoRows:
-			return nil, types.ErrorNotFound
-		case strings.Contains(err.Error(), "unique constraint"):
-			return nil, types.ErrorDuplicateValue
-		default:
-			return nil, err
-		}
+		return nil, classify(err)
 	}
 
 	return &updatedBook, nil
@@ -88,13 +68,32 @@ func (r *BookRepository) Delete(ctx context.Context, bookID uuid.UUID) (*model.B
 	row := r.pg.QueryRowContext(ctx, query, bookID)
 	if err := row.Scan(&deletedBook.ID, &deletedBook.Name, &deletedBook.DateOfIssue, &deletedBook.Author, &deletedBook.Description,
 		&deletedBook.Rating, &deletedBook.Price, &deletedBook.InStock); err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			return nil, types.ErrorNotFound
-		default:
+		return nil, classify(err)
+	}
+
+	return &deletedBook, nil
+}
+
+// List receives up to limit books from the books table ordered by ID, starting after the passed book ID.
+func (r *BookRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	query := "SELECT * FROM books WHERE id > $1 ORDER BY id LIMIT $2"
+	rows, err := r.pg.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	books := make([]model.Book, 0, limit)
+	for rows.Next() {
+		book := model.Book{}
+		if err = rows.Scan(&book.ID, &book.Name, &book.DateOfIssue, &book.Author, &book.Description,
+			&book.Rating, &book.Price, &book.InStock); err != nil {
 			return nil, err
 		}
+
+		books = append(books, book)
 	}
 
-	return &deletedBook, nil
+	return books, rows.Err()
 }
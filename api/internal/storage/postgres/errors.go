@@ -0,0 +1,48 @@
+// Package postgres contains PostgreSQL repository implementation.
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+)
+
+// PostgreSQL error codes that are classified into typed errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	codeUniqueViolation   pq.ErrorCode = "23505"
+	codeNotNullViolation  pq.ErrorCode = "23502"
+	codeStringTruncation  pq.ErrorCode = "22001"
+	codeNumericOutOfRange pq.ErrorCode = "22003"
+)
+
+// classify converts PostgreSQL driver errors into typed errors. Unknown errors are returned as is.
+func classify(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.New(types.KindNotFound, "id", err)
+	}
+
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		return types.New(types.KindDuplicate, constraintField(pgErr.Table, pgErr.Constraint), err)
+	case codeNotNullViolation, codeStringTruncation, codeNumericOutOfRange:
+		return types.New(types.KindValidation, pgErr.Column, err)
+	default:
+		return err
+	}
+}
+
+// constraintField returns the column of a constraint that follows
+// the default PostgreSQL naming, for example "books_name_key" -> "name".
+func constraintField(table, constraint string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(constraint, table+"_"), "_key")
+}
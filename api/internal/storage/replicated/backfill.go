@@ -0,0 +1,116 @@
+package replicated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Checkpoint stores the ID of the last copied book in a file, so an interrupted backfill can be resumed.
+type Checkpoint struct {
+	path string
+}
+
+// NewCheckpoint returns a new configured Checkpoint object.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path}
+}
+
+// Load returns the saved book ID or uuid.Nil if nothing has been saved yet.
+func (c *Checkpoint) Load() (uuid.UUID, error) {
+	data, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return uuid.Nil, nil
+		}
+
+		return uuid.Nil, err
+	}
+
+	return uuid.Parse(strings.TrimSpace(string(data)))
+}
+
+// Save atomically replaces the saved book ID.
+func (c *Checkpoint) Save(bookID uuid.UUID) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path))
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.WriteString(bookID.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Backfill copies all books from src to dst in batches ordered by book ID.
+// It starts after the book saved in the checkpoint and saves the checkpoint after every batch.
+// Books that already exist in dst are skipped. Backfill returns the number of copied books.
+func Backfill(ctx context.Context, src, dst storage.Booker, cp *Checkpoint, batchSize int, log *logger.Logger) (int, error) {
+	after, err := cp.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	if after != uuid.Nil {
+		log.Info(fmt.Sprintf("Resuming backfill after book <<< %s >>>", after))
+	}
+
+	copied := 0
+	for {
+		var books []model.Book
+		if books, err = src.List(ctx, after, batchSize); err != nil {
+			return copied, err
+		}
+
+		for index := range books {
+			if _, err = dst.Insert(ctx, &books[index]); err != nil {
+				if !errors.Is(err, types.ErrorDuplicateValue) {
+					return copied, err
+				}
+
+				log.Debug(fmt.Sprintf("Book <<< %s >>> already exists, skipped", books[index].ID))
+
+				continue
+			}
+
+			copied++
+		}
+
+		if len(books) == 0 {
+			return copied, nil
+		}
+
+		after = books[len(books)-1].ID
+		if err = cp.Save(after); err != nil {
+			return copied, err
+		}
+
+		log.Info(fmt.Sprintf("Backfilled %d books, last book <<< %s >>>", copied, after))
+
+		if len(books) < batchSize {
+			return copied, nil
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package replicated contains a book repository that keeps two repositories in sync.
+package replicated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// BookRepository writes to both primary and secondary repositories and reads from the primary one.
+// The primary repository is the source of truth: secondary failures are logged and never returned.
+type BookRepository struct {
+	primary    storage.Booker
+	secondary  storage.Booker
+	shadowRead bool
+	log        *logger.Logger
+}
+
+// NewBookRepository returns a new configured BookRepository object.
+func NewBookRepository(primary, secondary storage.Booker, shadowRead bool, log *logger.Logger) *BookRepository {
+	return &BookRepository{primary, secondary, shadowRead, log}
+}
+
+// Insert adds a new book to the primary repository and then copies it to the secondary one.
+func (r *BookRepository) Insert(ctx context.Context, book *model.Book) (*model.Book, error) {
+	insertedBook, err := r.primary.Insert(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = r.secondary.Insert(ctx, insertedBook); err != nil {
+		r.log.Error(fmt.Sprintf("Secondary insert of book <<< %s >>> failed: %s", insertedBook.ID, err.Error()))
+	}
+
+	return insertedBook, nil
+}
+
+// Get receives a book from the primary repository. If shadow reads are enabled,
+// the book is also received from the secondary repository and mismatches are logged.
+func (r *BookRepository) Get(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	book, err := r.primary.Get(ctx, bookID)
+	if r.shadowRead {
+		r.compare(ctx, bookID, book, err)
+	}
+
+	return book, err
+}
+
+// Update updates a book in the primary repository and then in the secondary one.
+// A book missing from the secondary repository is inserted there.
+func (r *BookRepository) Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (*model.Book, error) {
+	updatedBook, err := r.primary.Update(ctx, bookID, book)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.secondary.Update(ctx, bookID, updatedBook)
+	if errors.Is(err, types.ErrorNotFound) {
+		_, err = r.secondary.Insert(ctx, updatedBook)
+	}
+
+	if err != nil {
+		r.log.Error(fmt.Sprintf("Secondary update of book <<< %s >>> failed: %s", bookID, err.Error()))
+	}
+
+	return updatedBook, nil
+}
+
+// Delete deletes a book from the primary repository and then from the secondary one.
+func (r *BookRepository) Delete(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	deletedBook, err := r.primary.Delete(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = r.secondary.Delete(ctx, bookID); err != nil && !errors.Is(err, types.ErrorNotFound) {
+		r.log.Error(fmt.Sprintf("Secondary delete of book <<< %s >>> failed: %s", bookID, err.Error()))
+	}
+
+	return deletedBook, nil
+}
+
+// List receives books from the primary repository.
+func (r *BookRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	return r.primary.List(ctx, after, limit)
+}
+
+func (r *BookRepository) compare(ctx context.Context, bookID uuid.UUID, expected *model.Book, expectedErr error) {
+	if expectedErr != nil && !errors.Is(expectedErr, types.ErrorNotFound) {
+		return
+	}
+
+	shadow, err := r.secondary.Get(ctx, bookID)
+	if err != nil && !errors.Is(err, types.ErrorNotFound) {
+		r.log.Error(fmt.Sprintf("Shadow read of book <<< %s >>> failed: %s", bookID, err.Error()))
+
+		return
+	}
+
+	if !Equal(expected, shadow) {
+		r.log.Warn(fmt.Sprintf("Shadow read of book <<< %s >>> mismatch: primary %+v, secondary %+v", bookID, expected, shadow))
+	}
+}
+
+// Equal reports whether two books have the same field values.
+// Decimal fields are compared by value, so 9.9 and 9.90 are equal.
+func Equal(a, b *model.Book) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.ID == b.ID && a.Name == b.Name && a.DateOfIssue == b.DateOfIssue && a.Author == b.Author &&
+		a.Description == b.Description && a.Rating.Equal(b.Rating.Decimal) && a.Price.Equal(b.Price.Decimal) &&
+		a.InStock == b.InStock
+}
@@ -0,0 +1,312 @@
+package replicated_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	mock "github.com/ivyoverflow/pub-sub/api/internal/storage/mock"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/replicated"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func newBook(id string) *model.Book {
+	return &model.Book{
+		ID:          uuid.MustParse(id),
+		Name:        "Concurrency in Go: Tools and Techniques for Developers",
+		DateOfIssue: "2017",
+		Author:      "Katherine Cox-Buday",
+		Description: `...`,
+		Rating:      model.Decimal{Decimal: decimal.NewFromFloat(99.99)},
+		Price:       model.Decimal{Decimal: decimal.NewFromFloat(199.99)},
+		InStock:     true,
+	}
+}
+
+func TestReplicatedRepository_Insert(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         *model.Book
+		expected      *model.Book
+		mockBehavior  func(*model.Book, *mock.MockBookerRepository, *mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			input:    newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Insert(gomock.Any(), book).Return(book, nil)
+				secondary.EXPECT().Insert(gomock.Any(), book).Return(book, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Secondary failure is ignored",
+			input:    newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Insert(gomock.Any(), book).Return(book, nil)
+				secondary.EXPECT().Insert(gomock.Any(), book).Return(nil, errors.New("something went wrong"))
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Primary failure is returned",
+			input:    newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: nil,
+			mockBehavior: func(book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Insert(gomock.Any(), book).Return(nil, types.ErrorDuplicateValue)
+			},
+			expectedError: types.ErrorDuplicateValue,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			primary := mock.NewMockBookerRepository(ctrl)
+			secondary := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(testCase.input, primary, secondary)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			repo := replicated.NewBookRepository(primary, secondary, false, log)
+			insertedBook, err := repo.Insert(context.Background(), testCase.input)
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expected, insertedBook)
+		})
+	}
+}
+
+func TestReplicatedRepository_Get(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         uuid.UUID
+		shadowRead    bool
+		expected      *model.Book
+		mockBehavior  func(uuid.UUID, *model.Book, *mock.MockBookerRepository, *mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			input:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			shadowRead: false,
+			expected:   newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(bookID uuid.UUID, expected *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Get(gomock.Any(), bookID).Return(expected, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Shadow read mismatch",
+			input:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			shadowRead: true,
+			expected:   newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(bookID uuid.UUID, expected *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Get(gomock.Any(), bookID).Return(expected, nil)
+				secondary.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Book not found",
+			input:      uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"),
+			shadowRead: true,
+			expected:   nil,
+			mockBehavior: func(bookID uuid.UUID, expected *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+				secondary.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: types.ErrorNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			primary := mock.NewMockBookerRepository(ctrl)
+			secondary := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(testCase.input, testCase.expected, primary, secondary)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			repo := replicated.NewBookRepository(primary, secondary, testCase.shadowRead, log)
+			receivedBook, err := repo.Get(context.Background(), testCase.input)
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expected, receivedBook)
+		})
+	}
+}
+
+func TestReplicatedRepository_Update(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         uuid.UUID
+		toUpdate      *model.Book
+		expected      *model.Book
+		mockBehavior  func(uuid.UUID, *model.Book, *mock.MockBookerRepository, *mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			toUpdate: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(bookID uuid.UUID, book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Update(gomock.Any(), bookID, book).Return(book, nil)
+				secondary.EXPECT().Update(gomock.Any(), bookID, book).Return(book, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Missing secondary book is inserted",
+			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			toUpdate: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(bookID uuid.UUID, book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Update(gomock.Any(), bookID, book).Return(book, nil)
+				secondary.EXPECT().Update(gomock.Any(), bookID, book).Return(nil, types.ErrorNotFound)
+				secondary.EXPECT().Insert(gomock.Any(), book).Return(book, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Book not found",
+			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"),
+			toUpdate: newBook("7a2f922c-073a-11eb-adc1-0242ac120004"),
+			expected: nil,
+			mockBehavior: func(bookID uuid.UUID, book *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Update(gomock.Any(), bookID, book).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: types.ErrorNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			primary := mock.NewMockBookerRepository(ctrl)
+			secondary := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(testCase.input, testCase.toUpdate, primary, secondary)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			repo := replicated.NewBookRepository(primary, secondary, false, log)
+			updatedBook, err := repo.Update(context.Background(), testCase.input, testCase.toUpdate)
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expected, updatedBook)
+		})
+	}
+}
+
+func TestReplicatedRepository_Delete(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         uuid.UUID
+		expected      *model.Book
+		mockBehavior  func(uuid.UUID, *model.Book, *mock.MockBookerRepository, *mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			expected: newBook("7a2f922c-073a-11eb-adc1-0242ac120002"),
+			mockBehavior: func(bookID uuid.UUID, expected *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Delete(gomock.Any(), bookID).Return(expected, nil)
+				secondary.EXPECT().Delete(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Book not found",
+			input:    uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120004"),
+			expected: nil,
+			mockBehavior: func(bookID uuid.UUID, expected *model.Book, primary, secondary *mock.MockBookerRepository) {
+				primary.EXPECT().Delete(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: types.ErrorNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			primary := mock.NewMockBookerRepository(ctrl)
+			secondary := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(testCase.input, testCase.expected, primary, secondary)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			repo := replicated.NewBookRepository(primary, secondary, false, log)
+			deletedBook, err := repo.Delete(context.Background(), testCase.input)
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expected, deletedBook)
+		})
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	first := newBook("7a2f922c-073a-11eb-adc1-0242ac120002")
+	second := newBook("7a2f922c-073a-11eb-adc1-0242ac120003")
+	third := newBook("7a2f922c-073a-11eb-adc1-0242ac120004")
+
+	src := mock.NewMockBookerRepository(ctrl)
+	dst := mock.NewMockBookerRepository(ctrl)
+	gomock.InOrder(
+		src.EXPECT().List(gomock.Any(), uuid.Nil, 2).Return([]model.Book{*first, *second}, nil),
+		src.EXPECT().List(gomock.Any(), second.ID, 2).Return([]model.Book{*third}, nil),
+	)
+	dst.EXPECT().Insert(gomock.Any(), first).Return(first, nil)
+	dst.EXPECT().Insert(gomock.Any(), second).Return(nil, types.ErrorDuplicateValue)
+	dst.EXPECT().Insert(gomock.Any(), third).Return(third, nil)
+
+	cp := replicated.NewCheckpoint(t.TempDir() + "/backfill.checkpoint")
+	copied, err := replicated.Backfill(context.Background(), src, dst, cp, 2, log)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copied)
+
+	last, err := cp.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, third.ID, last)
+
+	src.EXPECT().List(gomock.Any(), third.ID, 2).Return([]model.Book{}, nil)
+	copied, err = replicated.Backfill(context.Background(), src, dst, cp, 2, log)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, copied)
+}
@@ -15,4 +15,5 @@ type Booker interface {
 	Get(ctx context.Context, bookID uuid.UUID) (*model.Book, error)
 	Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (*model.Book, error)
 	Delete(ctx context.Context, bookID uuid.UUID) (*model.Book, error)
+	List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error)
 }
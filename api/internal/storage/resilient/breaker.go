@@ -0,0 +1,73 @@
+package resilient
+
+import (
+	"sync"
+	"time"
+)
+
+type state int
+
+const (
+	closed state = iota
+	open
+	halfOpen
+)
+
+// Breaker is a circuit breaker that opens after threshold consecutive failures
+// and lets a single probe call through once the cooldown has passed.
+type Breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     state
+	failures  int
+	openedAt  time.Time
+}
+
+// NewBreaker returns a new closed Breaker object.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made now.
+func (b *Breaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+
+		b.state = halfOpen
+
+		return true
+	case halfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// Success closes the breaker and resets the failure counter.
+func (b *Breaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = closed
+	b.failures = 0
+}
+
+// Failure records a failed call and opens the breaker if the threshold is reached
+// or the probe call of a half-open breaker failed.
+func (b *Breaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		b.state = open
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,29 @@
+// Package resilient contains a book repository decorator with deadlines, retries and a circuit breaker.
+package resilient
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config contains fields that will be used to configure repository call policies.
+type Config struct {
+	ReadTimeout      time.Duration `envconfig:"STORAGE_READ_TIMEOUT" default:"2s"`
+	WriteTimeout     time.Duration `envconfig:"STORAGE_WRITE_TIMEOUT" default:"5s"`
+	Retries          int           `envconfig:"STORAGE_RETRIES" default:"3"`
+	Backoff          time.Duration `envconfig:"STORAGE_RETRY_BACKOFF" default:"50ms"`
+	MaxBackoff       time.Duration `envconfig:"STORAGE_RETRY_MAX_BACKOFF" default:"1s"`
+	BreakerThreshold int           `envconfig:"STORAGE_BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `envconfig:"STORAGE_BREAKER_COOLDOWN" default:"30s"`
+}
+
+// NewConfig returns a new configured Config object, or an error if the environment sets invalid values.
+func NewConfig() (*Config, error) {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
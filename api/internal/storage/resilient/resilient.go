@@ -0,0 +1,173 @@
+// Package resilient contains a book repository decorator with deadlines, retries and a circuit breaker.
+package resilient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// BookRepository wraps a repository with per-operation deadlines, exponential backoff retries
+// of transient errors and a circuit breaker. Once retries are exhausted or the breaker is open,
+// calls fail with an error of types.KindUnavailable.
+//
+// Reads are retried after every transient error. Writes are retried only after the errors IsUnapplied reports,
+// because a write whose connection broke or whose deadline passed may have been committed.
+type BookRepository struct {
+	repo    storage.Booker
+	cfg     *Config
+	breaker *Breaker
+	log     *logger.Logger
+}
+
+// NewBookRepository returns a new configured BookRepository object.
+func NewBookRepository(repo storage.Booker, cfg *Config, log *logger.Logger) *BookRepository {
+	return &BookRepository{repo, cfg, NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown), log}
+}
+
+// Insert calls Insert repository method.
+func (r *BookRepository) Insert(ctx context.Context, book *model.Book) (*model.Book, error) {
+	var insertedBook *model.Book
+	err := r.do(ctx, "insert", r.cfg.WriteTimeout, true, func(ctx context.Context) (err error) {
+		insertedBook, err = r.repo.Insert(ctx, book)
+
+		return err
+	})
+
+	return insertedBook, err
+}
+
+// Get calls Get repository method.
+func (r *BookRepository) Get(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	var book *model.Book
+	err := r.do(ctx, "get", r.cfg.ReadTimeout, false, func(ctx context.Context) (err error) {
+		book, err = r.repo.Get(ctx, bookID)
+
+		return err
+	})
+
+	return book, err
+}
+
+// Update calls Update repository method.
+func (r *BookRepository) Update(ctx context.Context, bookID uuid.UUID, book *model.Book) (*model.Book, error) {
+	var updatedBook *model.Book
+	err := r.do(ctx, "update", r.cfg.WriteTimeout, true, func(ctx context.Context) (err error) {
+		updatedBook, err = r.repo.Update(ctx, bookID, book)
+
+		return err
+	})
+
+	return updatedBook, err
+}
+
+// Delete calls Delete repository method.
+func (r *BookRepository) Delete(ctx context.Context, bookID uuid.UUID) (*model.Book, error) {
+	var deletedBook *model.Book
+	err := r.do(ctx, "delete", r.cfg.WriteTimeout, true, func(ctx context.Context) (err error) {
+		deletedBook, err = r.repo.Delete(ctx, bookID)
+
+		return err
+	})
+
+	return deletedBook, err
+}
+
+// List calls List repository method.
+func (r *BookRepository) List(ctx context.Context, after uuid.UUID, limit int) ([]model.Book, error) {
+	var books []model.Book
+	err := r.do(ctx, "list", r.cfg.ReadTimeout, false, func(ctx context.Context) (err error) {
+		books, err = r.repo.List(ctx, after, limit)
+
+		return err
+	})
+
+	return books, err
+}
+
+// do makes the call with retries of transient errors, or of unapplied errors only if the call is a write.
+func (r *BookRepository) do(ctx context.Context, operation string, timeout time.Duration, write bool,
+	call func(context.Context) error) error {
+	if !r.breaker.Allow() {
+		return types.ErrorUnavailable
+	}
+
+	backoff := r.cfg.Backoff
+	var err error
+	var transient bool
+	for attempt := 0; ; attempt++ {
+		transient, err = r.attempt(ctx, timeout, call)
+		if !transient || attempt >= r.cfg.Retries || (write && !IsUnapplied(err)) {
+			break
+		}
+
+		r.log.Debug(fmt.Sprintf("Retrying <<< %s >>> in %s after transient error: %s", operation, backoff, err.Error()))
+		if !sleep(ctx, backoff) {
+			break
+		}
+
+		if backoff *= 2; backoff > r.cfg.MaxBackoff {
+			backoff = r.cfg.MaxBackoff
+		}
+	}
+
+	switch {
+	case err == nil, isClientError(err), ctx.Err() != nil:
+		r.breaker.Success()
+
+		return err
+	case transient:
+		r.breaker.Failure()
+
+		return types.New(types.KindUnavailable, "", err)
+	default:
+		r.breaker.Failure()
+
+		return err
+	}
+}
+
+// attempt makes a single call bounded by timeout and reports whether its error is transient.
+func (r *BookRepository) attempt(ctx context.Context, timeout time.Duration, call func(context.Context) error) (bool, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := call(attemptCtx)
+	if err == nil {
+		return false, nil
+	}
+
+	timedOut := attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+
+	return timedOut || IsRetryable(err), err
+}
+
+// sleep waits for the passed duration and returns false if the context is done earlier.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isClientError reports whether err is caused by the request rather than by the database.
+func isClientError(err error) bool {
+	switch types.KindOf(err) {
+	case types.KindNotFound, types.KindDuplicate, types.KindValidation, types.KindBadRequest:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,260 @@
+package resilient_test
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/ivyoverflow/pub-sub/api/internal/lib/types"
+	"github.com/ivyoverflow/pub-sub/api/internal/model"
+	mock "github.com/ivyoverflow/pub-sub/api/internal/storage/mock"
+	"github.com/ivyoverflow/pub-sub/api/internal/storage/resilient"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func newConfig() *resilient.Config {
+	return &resilient.Config{
+		ReadTimeout:      time.Second,
+		WriteTimeout:     time.Second,
+		Retries:          2,
+		Backoff:          time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    error
+		expected bool
+	}{
+		{
+			name:     "Postgres serialization failure",
+			input:    &pq.Error{Code: "40001"},
+			expected: true,
+		},
+		{
+			name:     "Postgres connection failure",
+			input:    &pq.Error{Code: "08006"},
+			expected: true,
+		},
+		{
+			name:     "Postgres unique violation",
+			input:    &pq.Error{Code: "23505"},
+			expected: false,
+		},
+		{
+			name:     "Mongo transient transaction error",
+			input:    mongo.CommandError{Labels: []string{"TransientTransactionError"}},
+			expected: true,
+		},
+		{
+			name:     "Mongo duplicate key",
+			input:    mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}},
+			expected: false,
+		},
+		{
+			name:     "Connection reset",
+			input:    syscall.ECONNRESET,
+			expected: true,
+		},
+		{
+			name:     "Not found",
+			input:    types.ErrorNotFound,
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, resilient.IsRetryable(testCase.input))
+		})
+	}
+}
+
+func TestIsUnapplied(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    error
+		expected bool
+	}{
+		{name: "Postgres serialization failure", input: &pq.Error{Code: "40001"}, expected: true},
+		{name: "Postgres connection failure", input: &pq.Error{Code: "08006"}},
+		{name: "Mongo transient transaction error", input: mongo.CommandError{Labels: []string{"TransientTransactionError"}},
+			expected: true},
+		{name: "Mongo network error", input: mongo.CommandError{Labels: []string{"NetworkError"}}},
+		{name: "Connection refused", input: syscall.ECONNREFUSED, expected: true},
+		{name: "Connection reset", input: syscall.ECONNRESET},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, resilient.IsUnapplied(testCase.input))
+		})
+	}
+}
+
+func TestResilientRepository_Update(t *testing.T) {
+	bookID := uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002")
+	book := &model.Book{ID: bookID, Name: "Concurrency in Go: Tools and Techniques for Developers"}
+	testCases := []struct {
+		name          string
+		expected      *model.Book
+		mockBehavior  func(*mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:     "Rolled back write is retried",
+			expected: book,
+			mockBehavior: func(repo *mock.MockBookerRepository) {
+				gomock.InOrder(
+					repo.EXPECT().Update(gomock.Any(), bookID, book).Return(nil, &pq.Error{Code: "40001"}),
+					repo.EXPECT().Update(gomock.Any(), bookID, book).Return(book, nil),
+				)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Write that may be committed is not retried",
+			expected: nil,
+			mockBehavior: func(repo *mock.MockBookerRepository) {
+				repo.EXPECT().Update(gomock.Any(), bookID, book).Return(nil, syscall.ECONNRESET)
+			},
+			expectedError: types.ErrorUnavailable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(repo)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			updatedBook, err := resilient.NewBookRepository(repo, newConfig(), log).Update(context.Background(), bookID, book)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, testCase.expected, updatedBook)
+		})
+	}
+}
+
+func TestResilientRepository_Get(t *testing.T) {
+	bookID := uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002")
+	book := &model.Book{ID: bookID, Name: "Concurrency in Go: Tools and Techniques for Developers"}
+	testCases := []struct {
+		name          string
+		expected      *model.Book
+		mockBehavior  func(*mock.MockBookerRepository)
+		expectedError error
+	}{
+		{
+			name:     "Transient error is retried",
+			expected: book,
+			mockBehavior: func(repo *mock.MockBookerRepository) {
+				gomock.InOrder(
+					repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, &pq.Error{Code: "40001"}),
+					repo.EXPECT().Get(gomock.Any(), bookID).Return(book, nil),
+				)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Retries are exhausted",
+			expected: nil,
+			mockBehavior: func(repo *mock.MockBookerRepository) {
+				repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, syscall.ECONNRESET).Times(3)
+			},
+			expectedError: types.ErrorUnavailable,
+		},
+		{
+			name:     "Book not found is not retried",
+			expected: nil,
+			mockBehavior: func(repo *mock.MockBookerRepository) {
+				repo.EXPECT().Get(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+			},
+			expectedError: types.ErrorNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mock.NewMockBookerRepository(ctrl)
+			testCase.mockBehavior(repo)
+			log, err := logger.New()
+			if err != nil {
+				t.Errorf("Logger initialization throws an error: %v", err)
+			}
+
+			receivedBook, err := resilient.NewBookRepository(repo, newConfig(), log).Get(context.Background(), bookID)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, testCase.expected, receivedBook)
+		})
+	}
+}
+
+func TestResilientRepository_Breaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	bookID := uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120002")
+	cfg := newConfig()
+	cfg.Retries = 0
+	inner := mock.NewMockBookerRepository(ctrl)
+	repo := resilient.NewBookRepository(inner, cfg, log)
+
+	inner.EXPECT().Delete(gomock.Any(), bookID).Return(nil, errors.New("something went wrong")).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err = repo.Delete(context.Background(), bookID)
+		assert.EqualError(t, err, "something went wrong")
+	}
+
+	_, err = repo.Delete(context.Background(), bookID)
+	assert.Equal(t, types.ErrorUnavailable, err)
+
+	time.Sleep(cfg.BreakerCooldown)
+
+	inner.EXPECT().Delete(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+	_, err = repo.Delete(context.Background(), bookID)
+	assert.Equal(t, types.ErrorNotFound, err)
+
+	inner.EXPECT().Delete(gomock.Any(), bookID).Return(nil, types.ErrorNotFound)
+	_, err = repo.Delete(context.Background(), bookID)
+	assert.Equal(t, types.ErrorNotFound, err)
+}
@@ -0,0 +1,88 @@
+package resilient
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Mongo error labels of errors that can be safely retried.
+var mongoRetryableLabels = []string{"TransientTransactionError", "RetryableWriteError", "NetworkError"}
+
+// Postgres error codes of errors that can be safely retried.
+var pgRetryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// Postgres error codes of errors raised before a statement changes anything, or whose transaction is rolled back.
+var pgUnappliedCodes = map[pq.ErrorCode]bool{
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P03": true, // cannot_connect_now
+}
+
+type labeled interface {
+	HasErrorLabel(label string) bool
+}
+
+// IsRetryable reports whether err is a transient database error: a Postgres serialization failure,
+// a lost connection, a network reset or a Mongo error with a transient label.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		return pgRetryableCodes[pgErr.Code] || pgErr.Code.Class() == "08"
+	}
+
+	var mongoErr labeled
+	if errors.As(err, &mongoErr) {
+		for _, label := range mongoRetryableLabels {
+			if mongoErr.HasErrorLabel(label) {
+				return true
+			}
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// IsUnapplied reports whether err is a transient database error known to happen before a write is applied:
+// a connection that could not be established or a Postgres transaction that was rolled back. Retrying such a write
+// cannot apply it twice, unlike retrying a write after a lost connection or a timeout, which may come after the commit.
+func IsUnapplied(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		return pgUnappliedCodes[pgErr.Code]
+	}
+
+	var mongoErr labeled
+	if errors.As(err, &mongoErr) && mongoErr.HasErrorLabel("TransientTransactionError") {
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED)
+}
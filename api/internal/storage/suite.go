@@ -29,6 +29,7 @@ func (s *Suite) Run(t *testing.T) {
 	s.testGet(t)
 	s.testUpdate(t)
 	s.testDelete(t)
+	s.testList(t)
 }
 
 func (s *Suite) testInsert(t *testing.T) {
@@ -125,7 +126,7 @@ func (s *Suite) testInsert(t *testing.T) {
 		ctx := context.Background()
 		insertedBook, err := s.repo.Insert(ctx, &testCases[index].input)
 		if err != nil {
-			assert.Equal(t, testCases[index].expectedError, err)
+			assert.ErrorIs(t, err, testCases[index].expectedError)
 		}
 
 		assert.Equal(t, testCases[index].expected, insertedBook)
@@ -172,7 +173,7 @@ func (s *Suite) testGet(t *testing.T) {
 		ctx := context.Background()
 		receivedBook, err := s.repo.Get(ctx, testCase.input)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, receivedBook)
@@ -266,7 +267,7 @@ func (s *Suite) testUpdate(t *testing.T) {
 		ctx := context.Background()
 		updatedBook, err := s.repo.Update(ctx, testCases[index].input, &testCases[index].toUpdate)
 		if err != nil {
-			assert.Equal(t, testCases[index].expectedError, err)
+			assert.ErrorIs(t, err, testCases[index].expectedError)
 		}
 
 		assert.Equal(t, testCases[index].expected, updatedBook)
@@ -313,9 +314,55 @@ func (s *Suite) testDelete(t *testing.T) {
 		ctx := context.Background()
 		deletedBook, err := s.repo.Delete(ctx, testCase.input)
 		if err != nil {
-			assert.Equal(t, testCase.expectedError, err)
+			assert.ErrorIs(t, err, testCase.expectedError)
 		}
 
 		assert.Equal(t, testCase.expected, deletedBook)
 	}
 }
+
+func (s *Suite) testList(t *testing.T) {
+	testCases := []struct {
+		name          string
+		after         uuid.UUID
+		limit         int
+		expected      []model.Book
+		expectedError error
+	}{
+		{
+			name:  "OK",
+			after: uuid.Nil,
+			limit: 10,
+			expected: []model.Book{
+				{
+					ID:          uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
+					Name:        "Introducing Go: Build Reliable, Scalable Programs",
+					DateOfIssue: "2016",
+					Author:      "Caleb Doxsey",
+					Description: `...`,
+					Rating:      model.Decimal{Decimal: decimal.NewFromFloat(45.99)},
+					Price:       model.Decimal{Decimal: decimal.NewFromFloat(129.24)},
+					InStock:     true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Nothing after the last book",
+			after:         uuid.MustParse("7a2f922c-073a-11eb-adc1-0242ac120003"),
+			limit:         10,
+			expected:      []model.Book{},
+			expectedError: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ctx := context.Background()
+		books, err := s.repo.List(ctx, testCase.after, testCase.limit)
+		if err != nil {
+			assert.ErrorIs(t, err, testCase.expectedError)
+		}
+
+		assert.Equal(t, testCase.expected, books)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 
-	"github.com/ivyoverflow/pub-sub/listenter/internal/client"
-	"github.com/ivyoverflow/pub-sub/listenter/internal/config"
-	"github.com/ivyoverflow/pub-sub/listenter/internal/logger"
+	"github.com/ivyoverflow/pub-sub/listener/internal/client"
+	"github.com/ivyoverflow/pub-sub/listener/internal/config"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
 func main() {
@@ -28,8 +30,15 @@ func main() {
 		return
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background(), "listener", cfg.TracingExporter)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	defer shutdownTracing(context.Background())
+
 	clt := client.New(log, cfg)
 	if err := clt.Run(topic); err != nil {
-		log.Fatal(err.Error())
+		log.Error(err.Error())
 	}
 }
@@ -3,8 +3,11 @@ module github.com/ivyoverflow/pub-sub/listener
 go 1.15
 
 require (
-	go.uber.org/zap v1.16.0 // indirect
-	golang.org/x/net v0.0.0-20201224014010-6772e930b67b
+	github.com/ivyoverflow/pub-sub/platform v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	go.uber.org/zap v1.16.0
+	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781
 )
 
 replace github.com/ivyoverflow/pub-sub/platform => ../platform
@@ -2,15 +2,24 @@
 package client
 
 import (
+	"context"
 	"fmt"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"golang.org/x/net/websocket"
 
-	"github.com/ivyoverflow/pub-sub/listenter/internal/config"
-	"github.com/ivyoverflow/pub-sub/listenter/internal/logger"
-	"github.com/ivyoverflow/pub-sub/listenter/internal/model"
+	"github.com/ivyoverflow/pub-sub/listener/internal/config"
+	"github.com/ivyoverflow/pub-sub/listener/internal/model"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
+// tracerName is the name of the tracer that records received messages.
+const tracerName = "github.com/ivyoverflow/pub-sub/listener/internal/client"
+
 // Client represents application client.
 type Client struct {
 	log *logger.Logger
@@ -27,16 +36,28 @@ func New(log *logger.Logger, cfg *config.Config) *Client {
 
 // Run runs application client.
 func (client *Client) Run(topic string) error {
-	ws, err := websocket.Dial(fmt.Sprintf("ws://%s:%s/subscribe", client.cfg.Addr, client.cfg.Port), "",
+	wsConfig, err := websocket.NewConfig(fmt.Sprintf("ws://%s:%s/subscribe", client.cfg.Addr, client.cfg.Port),
 		fmt.Sprintf("http://%s:%s", client.cfg.Addr, client.cfg.Port))
 	if err != nil {
 		return err
 	}
 
+	if client.cfg.AccessToken != "" {
+		wsConfig.Header.Set("Authorization", "Bearer "+client.cfg.AccessToken)
+	}
+
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return err
+	}
+
 	defer ws.Close()
 
 	request := &model.Request{
-		Topic: topic,
+		Type:   "subscribe",
+		ID:     topic,
+		Topic:  topic,
+		Filter: client.cfg.Filter,
 	}
 
 	if err := websocket.JSON.Send(ws, request); err != nil {
@@ -49,6 +70,35 @@ func (client *Client) Run(topic string) error {
 			return err
 		}
 
-		client.log.Info(fmt.Sprintf("Client received <<< %s >> message", response.Message))
+		switch response.Type {
+		case "message":
+			client.receive(topic, response)
+		case "error":
+			client.log.Error("Subscription failed",
+				zap.String("topic", topic),
+				zap.Int("status_code", response.Error.StatusCode),
+				zap.String("error", response.Error.Message))
+		}
 	}
 }
+
+// receive logs the message within a span that continues the trace of its delivery.
+func (client *Client) receive(topic string, response *model.Response) {
+	ctx := tracing.Extract(context.Background(), response.TraceContext)
+	_, span := otel.Tracer(tracerName).Start(ctx, "websocket receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "websocket"),
+			attribute.String("messaging.destination", topic),
+		))
+	defer span.End()
+
+	client.log.Info("Message received",
+		zap.String("topic", topic),
+		zap.Any("message", response.Message),
+		zap.Bool("retained", response.Retained),
+		zap.String("reply_to", response.ReplyTo),
+		zap.String("correlation_id", response.CorrelationID),
+		zap.Any("headers", response.Headers),
+		zap.Stringer("trace_id", span.SpanContext().TraceID()))
+}
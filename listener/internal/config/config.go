@@ -9,12 +9,21 @@ import (
 type Config struct {
 	Addr string
 	Port string
+	// TracingExporter is the span exporter: "otlp", "stdout" or empty to disable exporting.
+	TracingExporter string
+	// AccessToken is the API key or JWT sent to the notifier as a bearer token. Empty sends no credentials.
+	AccessToken string
+	// Filter is the expression the received messages must match, like "price < 20". Empty receives every message.
+	Filter string
 }
 
 // New returrns a new configured Config object.
 func New() *Config {
 	return &Config{
-		Addr: os.Getenv("ADDR"),
-		Port: os.Getenv("PORT"),
+		Addr:            os.Getenv("ADDR"),
+		Port:            os.Getenv("PORT"),
+		TracingExporter: os.Getenv("TRACING_EXPORTER"),
+		AccessToken:     os.Getenv("ACCESS_TOKEN"),
+		Filter:          os.Getenv("FILTER"),
 	}
 }
@@ -1,7 +1,12 @@
 // Package model contains the described structures that will be used in the project.
 package model
 
-// Request struct represents the publish request body to the server.
+// Request struct represents the subscribe command sent to the server.
+// ID names the subscription and is echoed by the frames of the subscription.
+// Filter, if set, is the expression the messages of the subscription must match.
 type Request struct {
-	Topic string `json:"topic"`
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Topic  string `json:"topic"`
+	Filter string `json:"filter,omitempty"`
 }
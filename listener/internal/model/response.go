@@ -1,7 +1,25 @@
 // Package model contains the described structures that will be used in the project.
 package model
 
-// Response struct represents the response body from the server.
+// Response struct represents a frame from the server: a delivered message or the answer to a command.
 type Response struct {
-	Message interface{} `json:"message"`
+	Type     string      `json:"type"`
+	ID       string      `json:"id"`
+	Seq      uint64      `json:"seq"`
+	Message  interface{} `json:"message"`
+	Retained bool        `json:"retained"`
+	// ReplyTo is the topic a reply is published to, with the same CorrelationID.
+	ReplyTo       string `json:"replyTo"`
+	CorrelationID string `json:"correlationId"`
+	// Headers are the headers set by the publisher of the message.
+	Headers map[string]string `json:"headers"`
+	// TraceContext carries the W3C trace context of the delivery.
+	TraceContext map[string]string `json:"traceContext"`
+	Error        *Error            `json:"error"`
+}
+
+// Error struct represents the error of a frame.
+type Error struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
 }
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"os"
+
 	"github.com/ivyoverflow/pub-sub/notifier/internal/config"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/server"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/shutdown"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
 func main() {
@@ -12,14 +17,38 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
-	cfg := config.New()
+	cfg, err := config.New()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
 	if cfg.Addr == "" || cfg.Port == "" {
 		log.Fatal("Environment variables ADDR and PORT not found")
 		return
 	}
 
-	svr := server.New(cfg, log)
-	if err := svr.Run(); err != nil {
+	shutdownTracing, err := tracing.Init(context.Background(), "notifier", cfg.TracingExporter)
+	if err != nil {
 		log.Fatal(err.Error())
 	}
+
+	ctx, stop := shutdown.Notify(context.Background())
+	defer stop()
+
+	svr := server.New(cfg, log)
+	runErr := svr.Run(ctx)
+	if runErr != nil {
+		log.Error(runErr.Error())
+	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("The server is stopped")
+	// A failed server exits with an error after the cleanup, so that supervisors restart it.
+	if runErr != nil {
+		stop()
+		os.Exit(1)
+	}
 }
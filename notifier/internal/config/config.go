@@ -2,19 +2,265 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/mqtt"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+)
+
+// Defines default values of optional environment variables.
+const (
+	defaultShutdownTimeout = 15 * time.Second
+	defaultHealthTimeout   = 2 * time.Second
+	defaultMaxPending      = 10000
+	defaultDeliveryTimeout = 30 * time.Second
+	defaultMaxBodyBytes    = 1 << 20
+	defaultRequestTimeout  = 10 * time.Second
+	defaultCORSMaxAge      = 10 * time.Minute
+	defaultDedupWindow     = 5 * time.Minute
+	defaultPingInterval    = 30 * time.Second
+	defaultPongTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 10 * time.Second
+	defaultMaxMessageBytes = 4 << 10
+	defaultHistorySize     = 100
+	defaultHeartbeat       = 15 * time.Second
+	defaultPollTimeout     = 30 * time.Second
+	defaultReplyTimeout    = 30 * time.Second
+	defaultWebhookWorkers  = 4
+	defaultWebhookQueue    = 1000
+	defaultWebhookTimeout  = 10 * time.Second
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookMaxDelay = time.Minute
+	defaultWebhookDisable  = 10
+	defaultWebhookLogSize  = 50
+	defaultMQTTConnect     = 10 * time.Second
+	defaultMQTTWrite       = 10 * time.Second
+	defaultMQTTMaxPacket   = 256 << 10
+	defaultMQTTMaxInflight = 100
 )
 
-// Config contains Addr and Port fields that will be used to configure server.
+// Config contains fields that will be used to configure server.
 type Config struct {
-	Addr string
-	Port string
+	Addr            string
+	Port            string
+	ShutdownTimeout time.Duration
+	HealthTimeout   time.Duration
+	// MaxPending is the number of undelivered messages at which the notifier reports itself as not ready.
+	MaxPending int
+	// MaxSubscribers is the number of subscribers at which the notifier reports itself as not ready.
+	// Zero means no limit.
+	MaxSubscribers int
+	// DeliveryTimeout is the time a subscriber has to receive a message before it is dropped.
+	// Zero means no timeout.
+	DeliveryTimeout time.Duration
+	// MaxUnacked is the number of unacknowledged messages at which deliveries of a websocket subscription pause.
+	// Zero means subscribers do not acknowledge messages.
+	MaxUnacked int
+	// PingInterval is the time between websocket pings, and PongTimeout the time a subscriber has to answer
+	// a ping before its connection is closed. A zero PingInterval disables pings and idle connections are kept.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// WriteTimeout is the time a subscriber has to receive a frame before its connection is closed.
+	WriteTimeout time.Duration
+	// MaxMessageBytes is the size of the largest command a subscriber may send.
+	MaxMessageBytes int64
+	// Compression negotiates the permessage-deflate extension with subscribers that support it.
+	Compression bool
+	// HistorySize is the number of the latest messages of every topic that event stream and long-poll
+	// subscribers may resume from. Zero keeps none.
+	HistorySize int
+	// HistoryTopics is the number of the most recently published topics whose history and retained message are kept.
+	// Zero keeps every topic.
+	HistoryTopics int
+	// HeartbeatInterval is the time between comments that keep idle event streams open through proxies.
+	HeartbeatInterval time.Duration
+	// PollTimeout is the time a long-poll request waits for a message.
+	PollTimeout time.Duration
+	// ReplyTimeout is the longest time a /request waits for a reply.
+	ReplyTimeout time.Duration
+	// Webhook configures the delivery of messages to webhook subscriptions.
+	Webhook *webhook.Config
+	// WebhookAllowedNetworks are internal networks that webhooks may target.
+	WebhookAllowedNetworks []string
+	// MQTTPort is the port of the MQTT 3.1.1 listener. Empty disables it.
+	MQTTPort string
+	MQTT     *mqtt.Config
+	// DedupWindow is the time the IDs of published messages are remembered to drop duplicates.
+	// Zero disables deduplication.
+	DedupWindow time.Duration
+	// TracingExporter is the span exporter: "otlp", "stdout" or empty to disable exporting.
+	TracingExporter string
+	// MetricsTopics are the topic patterns that label the notifier metrics, other topics are labeled "other".
+	MetricsTopics []string
+	// CORSAllowedOrigins are origins allowed to call the notifier, "*" allows any.
+	CORSAllowedOrigins []string
+	CORSMaxAge         time.Duration
+	MaxBodyBytes       int64
+	// RequestTimeout limits the /publish requests. Websocket subscriptions are not limited.
+	RequestTimeout time.Duration
+	// TrustedProxies are proxy networks whose X-Forwarded-For headers are trusted.
+	TrustedProxies []string
+	// PublishRateLimit is the limit of every client on every topic, like "10/s". Empty disables it.
+	PublishRateLimit string
+	// PublishRateLimitTopics overrides PublishRateLimit per topic pattern, like "news.*:1/s".
+	PublishRateLimitTopics []string
+	// RateLimitRedisURL is the Redis server that shares limits between replicas. Empty keeps them in memory.
+	RateLimitRedisURL string
+	// ValidateResponses replaces /publish responses that do not match the OpenAPI document with 500.
+	// It is meant for tests.
+	ValidateResponses bool
+	// Auth configures authentication of the /publish and /subscribe routes.
+	Auth *auth.Config
+}
+
+// ErrInvalidEnv is returned when an environment variable is set to a value that cannot be parsed.
+var ErrInvalidEnv = errors.New("invalid environment variable")
+
+// New returrns a new configured Config object, or an error if the environment sets invalid values.
+// Unset variables take their defaults.
+func New() (*Config, error) {
+	var e env
+	cfg := &Config{
+		Addr:            os.Getenv("ADDR"),
+		Port:            os.Getenv("PORT"),
+		ShutdownTimeout: e.duration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		HealthTimeout:   e.duration("HEALTH_TIMEOUT", defaultHealthTimeout),
+		MaxPending:      e.int("MAX_PENDING_DELIVERIES", defaultMaxPending),
+		MaxSubscribers:  e.int("MAX_SUBSCRIBERS", 0),
+		DeliveryTimeout: e.duration("DELIVERY_TIMEOUT", defaultDeliveryTimeout),
+		MaxUnacked:      e.int("MAX_UNACKED_MESSAGES", 0),
+		DedupWindow:     e.duration("DEDUP_WINDOW", defaultDedupWindow),
+		TracingExporter: os.Getenv("TRACING_EXPORTER"),
+		MetricsTopics:   listEnv("METRICS_TOPICS"),
+
+		PingInterval:    e.duration("WS_PING_INTERVAL", defaultPingInterval),
+		PongTimeout:     e.duration("WS_PONG_TIMEOUT", defaultPongTimeout),
+		WriteTimeout:    e.duration("WS_WRITE_TIMEOUT", defaultWriteTimeout),
+		MaxMessageBytes: int64(e.int("WS_MAX_MESSAGE_BYTES", defaultMaxMessageBytes)),
+		Compression:     e.bool("WS_COMPRESSION"),
+
+		HistorySize:       e.int("HISTORY_SIZE", defaultHistorySize),
+		HistoryTopics:     e.int("HISTORY_TOPICS", service.DefaultHistoryTopics),
+		HeartbeatInterval: e.duration("SSE_HEARTBEAT_INTERVAL", defaultHeartbeat),
+		PollTimeout:       e.duration("POLL_TIMEOUT", defaultPollTimeout),
+		ReplyTimeout:      e.duration("REPLY_TIMEOUT", defaultReplyTimeout),
+
+		Webhook: &webhook.Config{
+			Workers:      e.int("WEBHOOK_WORKERS", defaultWebhookWorkers),
+			QueueSize:    e.int("WEBHOOK_QUEUE_SIZE", defaultWebhookQueue),
+			Timeout:      e.duration("WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+			MaxAttempts:  e.int("WEBHOOK_MAX_ATTEMPTS", defaultWebhookAttempts),
+			Backoff:      e.duration("WEBHOOK_RETRY_BACKOFF", defaultWebhookBackoff),
+			MaxBackoff:   e.duration("WEBHOOK_MAX_BACKOFF", defaultWebhookMaxDelay),
+			DisableAfter: e.int("WEBHOOK_DISABLE_AFTER", defaultWebhookDisable),
+			LogSize:      e.int("WEBHOOK_LOG_SIZE", defaultWebhookLogSize),
+		},
+		WebhookAllowedNetworks: listEnv("WEBHOOK_ALLOWED_NETWORKS"),
+
+		MQTTPort: os.Getenv("MQTT_PORT"),
+		MQTT: &mqtt.Config{
+			ConnectTimeout: e.duration("MQTT_CONNECT_TIMEOUT", defaultMQTTConnect),
+			WriteTimeout:   e.duration("MQTT_WRITE_TIMEOUT", defaultMQTTWrite),
+			MaxPacketBytes: e.int("MQTT_MAX_PACKET_BYTES", defaultMQTTMaxPacket),
+			MaxInflight:    e.int("MQTT_MAX_INFLIGHT", defaultMQTTMaxInflight),
+		},
+
+		CORSAllowedOrigins: listEnv("CORS_ALLOWED_ORIGINS"),
+		CORSMaxAge:         e.duration("CORS_MAX_AGE", defaultCORSMaxAge),
+		MaxBodyBytes:       int64(e.int("MAX_BODY_BYTES", defaultMaxBodyBytes)),
+		RequestTimeout:     e.duration("REQUEST_TIMEOUT", defaultRequestTimeout),
+		TrustedProxies:     listEnv("TRUSTED_PROXIES"),
+		Auth:               auth.NewConfig(),
+
+		PublishRateLimit:       os.Getenv("PUBLISH_RATE_LIMIT"),
+		PublishRateLimitTopics: listEnv("PUBLISH_RATE_LIMIT_TOPICS"),
+		RateLimitRedisURL:      os.Getenv("RATE_LIMIT_REDIS_URL"),
+
+		ValidateResponses: e.bool("OPENAPI_VALIDATE_RESPONSES"),
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return cfg, nil
+}
+
+// env reads environment variables and keeps the error of the first invalid value.
+type env struct {
+	err error
+}
+
+// fail keeps the error of the invalid value of the environment variable, unless an error is kept already.
+func (e *env) fail(key string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%w %s: %v", ErrInvalidEnv, key, err)
+	}
 }
 
-// New returrns a new configured Config object.
-func New() *Config {
-	return &Config{
-		Addr: os.Getenv("ADDR"),
-		Port: os.Getenv("PORT"),
+// duration returns the duration from the environment variable or def if it is unset or invalid.
+func (e *env) duration(key string, def time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
 	}
+
+	value, err := time.ParseDuration(s)
+	if err != nil {
+		e.fail(key, err)
+
+		return def
+	}
+
+	return value
+}
+
+// int returns the integer from the environment variable or def if it is unset or invalid.
+func (e *env) int(key string, def int) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		e.fail(key, err)
+
+		return def
+	}
+
+	return value
+}
+
+// bool returns the boolean from the environment variable or false if it is unset or invalid.
+func (e *env) bool(key string) bool {
+	s := os.Getenv(key)
+	if s == "" {
+		return false
+	}
+
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		e.fail(key, err)
+	}
+
+	return value
+}
+
+// listEnv returns the comma-separated values of the environment variable or nil if it is unset.
+func listEnv(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
 }
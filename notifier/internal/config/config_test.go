@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name  string
+		key   string
+		value string
+		valid bool
+	}{
+		{name: "Unset duration", key: "DELIVERY_TIMEOUT", value: "", valid: true},
+		{name: "Valid duration", key: "DELIVERY_TIMEOUT", value: "3s", valid: true},
+		{name: "Invalid duration", key: "DELIVERY_TIMEOUT", value: "3 seconds"},
+		{name: "Invalid integer", key: "MAX_PENDING_DELIVERIES", value: "many"},
+		{name: "Invalid boolean", key: "WS_COMPRESSION", value: "sometimes"},
+	}
+
+	for _, testCase := range testCases {
+		os.Setenv(testCase.key, testCase.value)
+		cfg, err := config.New()
+		os.Unsetenv(testCase.key)
+
+		if !testCase.valid {
+			if !errors.Is(err, config.ErrInvalidEnv) {
+				t.Errorf("%s: New returns %v, expected %v", testCase.name, err, config.ErrInvalidEnv)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: New throws an error: %v", testCase.name, err)
+		}
+
+		if testCase.value == "3s" && cfg.DeliveryTimeout != 3*time.Second {
+			t.Errorf("%s: DeliveryTimeout is %v, expected 3s", testCase.name, cfg.DeliveryTimeout)
+		}
+	}
+}
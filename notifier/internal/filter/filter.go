@@ -0,0 +1,332 @@
+// Package filter implements the expressions that subscribers use to receive only some messages of a topic,
+// like price < 20 && inStock == true. Names are paths into the JSON message, like book.price, and names
+// starting with "$" are the headers of the message, like $topic, or the headers set by its publisher,
+// like $headers.region. Expressions compare values with ==, !=,
+// <, <=, > and >=, and combine conditions with &&, || and !. A name alone is true if its value is true.
+// Missing values are null, and comparing values of different types is false, so evaluating never fails.
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidFilter is wrapped by the errors of expressions that do not compile.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// PublisherHeader prefixes the names of the headers set by publishers, like "$headers.region".
+const PublisherHeader = "$headers."
+
+// headers are the names of the headers of every message.
+var headers = map[string]struct{}{
+	"$topic":         {},
+	"$seq":           {},
+	"$retained":      {},
+	"$replyTo":       {},
+	"$correlationId": {},
+}
+
+// Message is a message a filter is evaluated on. Body is the JSON-decoded message, as returned by Decode,
+// and Headers are the header values by header name, like "$topic" or "$headers.region".
+type Message struct {
+	Headers map[string]interface{}
+	Body    interface{}
+}
+
+// Filter is a compiled expression. It is safe for concurrent use.
+type Filter struct {
+	expr string
+	root node
+}
+
+// Compile parses the expression and checks that it is a condition whose comparisons may be true.
+func Compile(expr string) (*Filter, error) {
+	if len(expr) > MaxLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidFilter, MaxLength)
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+
+	if !root.kind().boolean() {
+		return nil, fmt.Errorf("%w: the filter is not a condition", ErrInvalidFilter)
+	}
+
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// String returns the expression of the filter.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether the message satisfies the filter.
+func (f *Filter) Match(m *Message) bool {
+	return truthy(f.root.eval(m))
+}
+
+// Decode returns the message as JSON values: maps, slices, float64 numbers, strings, booleans and nil.
+// Raw JSON messages are parsed, other values are converted through JSON, so that numbers of Go maps become float64,
+// and raw messages that are not JSON stay strings.
+func Decode(message interface{}) interface{} {
+	switch message := message.(type) {
+	case nil, bool, float64, string:
+		return message
+	case json.RawMessage:
+		var body interface{}
+		if err := json.Unmarshal(message, &body); err != nil {
+			return string(message)
+		}
+
+		return body
+	default:
+		encoded, err := json.Marshal(message)
+		if err != nil {
+			return nil
+		}
+
+		var body interface{}
+		if err = json.Unmarshal(encoded, &body); err != nil {
+			return nil
+		}
+
+		return body
+	}
+}
+
+// kind is the type of the values of a node, known at compile time. Fields and headers may have any type.
+type kind int
+
+const (
+	kindAny kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindNull
+)
+
+// boolean reports whether values of the kind may be conditions.
+func (k kind) boolean() bool {
+	return k == kindAny || k == kindBool
+}
+
+// node is a part of an expression tree.
+type node interface {
+	eval(m *Message) interface{}
+	kind() kind
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l *literal) eval(*Message) interface{} {
+	return l.value
+}
+
+func (l *literal) kind() kind {
+	switch l.value.(type) {
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		return kindString
+	default:
+		return kindNull
+	}
+}
+
+// field is a path into the message body.
+type field struct {
+	path []string
+}
+
+func (f *field) eval(m *Message) interface{} {
+	value := m.Body
+	for _, name := range f.path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		value = object[name]
+	}
+
+	return value
+}
+
+func (f *field) kind() kind {
+	return kindAny
+}
+
+type header struct {
+	name string
+}
+
+func (h *header) eval(m *Message) interface{} {
+	return m.Headers[h.name]
+}
+
+func (h *header) kind() kind {
+	return kindAny
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(m *Message) interface{} {
+	return !truthy(n.operand.eval(m))
+}
+
+func (n *notNode) kind() kind {
+	return kindBool
+}
+
+// logicalNode is && if and is set, || otherwise. The right operand is evaluated only if needed.
+type logicalNode struct {
+	left, right node
+	and         bool
+}
+
+func (n *logicalNode) eval(m *Message) interface{} {
+	if truthy(n.left.eval(m)) != n.and {
+		return !n.and
+	}
+
+	return truthy(n.right.eval(m))
+}
+
+func (n *logicalNode) kind() kind {
+	return kindBool
+}
+
+func logical(left, right node, and bool, pos int) (node, error) {
+	if !left.kind().boolean() || !right.kind().boolean() {
+		operator := "||"
+		if and {
+			operator = "&&"
+		}
+
+		return nil, fmt.Errorf("%w: %s needs conditions at position %d", ErrInvalidFilter, operator, pos)
+	}
+
+	return &logicalNode{left: left, right: right, and: and}, nil
+}
+
+// comparison operators.
+type operator int
+
+const (
+	opEqual operator = iota
+	opNotEqual
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+)
+
+var comparisons = map[string]operator{
+	"==": opEqual,
+	"!=": opNotEqual,
+	"<":  opLess,
+	"<=": opLessEqual,
+	">":  opGreater,
+	">=": opGreaterEqual,
+}
+
+type comparisonNode struct {
+	op          operator
+	left, right node
+}
+
+func (n *comparisonNode) eval(m *Message) interface{} {
+	left, right := n.left.eval(m), n.right.eval(m)
+	switch n.op {
+	case opEqual:
+		return equal(left, right)
+	case opNotEqual:
+		return !equal(left, right)
+	}
+
+	switch left := left.(type) {
+	case float64:
+		if right, ok := right.(float64); ok {
+			return order(n.op, left < right, left == right)
+		}
+	case string:
+		if right, ok := right.(string); ok {
+			return order(n.op, left < right, left == right)
+		}
+	}
+
+	return false
+}
+
+func (n *comparisonNode) kind() kind {
+	return kindBool
+}
+
+// compare checks that the operands may be compared: ordering needs numbers or strings, and literals
+// of different types are never equal.
+func compare(op operator, left, right node, t token) (node, error) {
+	l, r := left.kind(), right.kind()
+	if op != opEqual && op != opNotEqual {
+		for _, k := range []kind{l, r} {
+			if k == kindBool || k == kindNull {
+				return nil, fmt.Errorf("%w: %s needs numbers or strings at position %d", ErrInvalidFilter, t.text, t.pos)
+			}
+		}
+	}
+
+	if l != kindAny && r != kindAny && l != r {
+		return nil, fmt.Errorf("%w: %s compares values of different types at position %d", ErrInvalidFilter, t.text, t.pos)
+	}
+
+	return &comparisonNode{op: op, left: left, right: right}, nil
+}
+
+func order(op operator, less, equal bool) bool {
+	switch op {
+	case opLess:
+		return less
+	case opLessEqual:
+		return less || equal
+	case opGreater:
+		return !less && !equal
+	default:
+		return !less
+	}
+}
+
+// equal compares JSON values. Objects and arrays are equal to nothing.
+func equal(left, right interface{}) bool {
+	switch left := left.(type) {
+	case nil:
+		return right == nil
+	case bool, float64, string:
+		return left == right
+	default:
+		return false
+	}
+}
+
+func truthy(value interface{}) bool {
+	b, ok := value.(bool)
+
+	return ok && b
+}
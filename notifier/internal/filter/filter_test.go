@@ -0,0 +1,174 @@
+package filter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/filter"
+)
+
+// book is the message the filters of the tests are evaluated on.
+var book = &filter.Message{
+	Headers: map[string]interface{}{"$topic": "books", "$seq": float64(7), "$retained": false, "$headers.region": "eu"},
+	Body: filter.Decode(json.RawMessage(`{
+		"title": "1984",
+		"price": 12.5,
+		"inStock": true,
+		"author": {"name": "George Orwell", "born": 1903},
+		"tags": ["dystopia"],
+		"isbn": null
+	}`)),
+}
+
+func TestFilter_Match(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `price < 20 && inStock == true`, expected: true},
+		{expr: `price < 10 || inStock`, expected: true},
+		{expr: `price >= 12.5 && price <= 12.5`, expected: true},
+		{expr: `price > 12.5`, expected: false},
+		{expr: `price != 12.5`, expected: false},
+		{expr: `!inStock`, expected: false},
+		{expr: `!(price < 10)`, expected: true},
+		{expr: `title == "1984" && author.name == 'George Orwell'`, expected: true},
+		{expr: `author.born < 1900`, expected: false},
+		{expr: `title > "1000"`, expected: true},
+		{expr: `$topic == "books" && $seq > 5 && !$retained`, expected: true},
+		{expr: `$headers.region == "eu"`, expected: true},
+		{expr: `$headers.source == null`, expected: true},
+		{expr: `isbn == null`, expected: true},
+		{expr: `missing == null`, expected: true},
+		{expr: `missing.field == null`, expected: true},
+		{expr: `missing < 20`, expected: false},
+		{expr: `title < 20`, expected: false},
+		{expr: `title`, expected: false},
+		{expr: `tags == "dystopia"`, expected: false},
+		{expr: `price < 20 && (title == "Dune" || author.born == 1903)`, expected: true},
+		{expr: `price < 20 && title == "Dune" || author.born == 1903`, expected: true},
+		{expr: `price > -1e3`, expected: true},
+	}
+
+	for _, testCase := range testCases {
+		f, err := filter.Compile(testCase.expr)
+		require.NoError(t, err, testCase.expr)
+		assert.Equal(t, testCase.expected, f.Match(book), testCase.expr)
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: ``, expected: "invalid filter: unexpected end of filter at position 0"},
+		{expr: `price <`, expected: "invalid filter: unexpected end of filter at position 7"},
+		{expr: `price < 20 &&`, expected: "invalid filter: unexpected end of filter at position 13"},
+		{expr: `(price < 20`, expected: "invalid filter: unexpected end of filter at position 11"},
+		{expr: `price < 20)`, expected: "invalid filter: unexpected ) at position 10"},
+		{expr: `price = 20`, expected: "invalid filter: unexpected character '=' at position 6"},
+		{expr: `price < 20 inStock`, expected: "invalid filter: unexpected inStock at position 11"},
+		{expr: `price < 20 "cheap"`, expected: `invalid filter: unexpected "cheap" at position 11`},
+		{expr: `title == "1984`, expected: "invalid filter: unterminated string at position 9"},
+		{expr: `title == "\x"`, expected: "invalid filter: invalid escape at position 10"},
+		{expr: `price < 1.2.3`, expected: "invalid filter: invalid number 1.2.3 at position 8"},
+		{expr: `$price < 20`, expected: "invalid filter: unknown header $price at position 0"},
+		{expr: `$headers == "eu"`, expected: "invalid filter: unknown header $headers at position 0"},
+		{expr: `$headers.geo.region == "eu"`, expected: "invalid filter: unknown header $headers.geo.region at position 0"},
+		{expr: `author..name == 1`, expected: "invalid filter: invalid path author..name at position 0"},
+		{expr: `price < true`, expected: "invalid filter: < needs numbers or strings at position 6"},
+		{expr: `price >= null`, expected: "invalid filter: >= needs numbers or strings at position 6"},
+		{expr: `20 == "20"`, expected: "invalid filter: == compares values of different types at position 3"},
+		{expr: `price < 20 && 20`, expected: "invalid filter: && needs conditions at position 11"},
+		{expr: `!"yes"`, expected: "invalid filter: ! needs a condition at position 0"},
+		{expr: `"yes"`, expected: "invalid filter: the filter is not a condition"},
+		{expr: strings.Repeat("(", 40) + "inStock" + strings.Repeat(")", 40), expected: "invalid filter: nesting deeper than 32 at position 32"},
+		{expr: strings.Repeat("a", filter.MaxLength+1), expected: "invalid filter: longer than 1024 bytes"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := filter.Compile(testCase.expr)
+		if assert.Error(t, err, testCase.expr) {
+			assert.True(t, errors.Is(err, filter.ErrInvalidFilter), testCase.expr)
+			assert.Equal(t, testCase.expected, err.Error(), testCase.expr)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  interface{}
+		expected interface{}
+	}{
+		{name: "JSON value", message: map[string]interface{}{"price": 12.5}, expected: map[string]interface{}{"price": 12.5}},
+		{name: "Go map", message: map[string]interface{}{"price": 12}, expected: map[string]interface{}{"price": float64(12)}},
+		{name: "Raw JSON", message: json.RawMessage(`{"price":12}`), expected: map[string]interface{}{"price": float64(12)}},
+		{name: "Raw text", message: json.RawMessage(`on`), expected: "on"},
+		{name: "Go value", message: struct{ Price int }{12}, expected: map[string]interface{}{"Price": float64(12)}},
+		{name: "Integer", message: 12, expected: float64(12)},
+		{name: "Not JSON", message: make(chan int), expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, filter.Decode(testCase.message), testCase.name)
+	}
+}
+
+// benchmarks are filters of increasing cost.
+var benchmarks = []struct {
+	name string
+	expr string
+}{
+	{name: "Field", expr: `inStock`},
+	{name: "Comparison", expr: `price < 20`},
+	{name: "Conjunction", expr: `price < 20 && inStock == true`},
+	{name: "Nested path", expr: `author.name == "George Orwell" && author.born < 1950`},
+	{name: "Header", expr: `$topic == "books" && $seq > 5`},
+	{name: "Complex", expr: `(price < 10 || price >= 12 && price < 13) && !(title == "Dune") && author.born > 1900 && inStock`},
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := filter.Compile(benchmark.expr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFilter_Match measures the cost of a filter per delivered message.
+func BenchmarkFilter_Match(b *testing.B) {
+	for _, benchmark := range benchmarks {
+		f, err := filter.Compile(benchmark.expr)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.Match(book)
+			}
+		})
+	}
+}
+
+// BenchmarkDecode measures the cost of decoding a message once per publish for its filtered subscribers.
+func BenchmarkDecode(b *testing.B) {
+	raw := json.RawMessage(`{"title":"1984","price":12.5,"inStock":true,"author":{"name":"George Orwell","born":1903}}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		filter.Decode(raw)
+	}
+}
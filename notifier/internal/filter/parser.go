@@ -0,0 +1,339 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Defines the limits of expressions, so that compiling and evaluating a filter is cheap.
+const (
+	MaxLength = 1024
+	maxDepth  = 32
+)
+
+// token kinds.
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenHeader
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+	// number is the value of number tokens, and text is the unquoted value of string tokens.
+	number float64
+}
+
+// describe returns the token as it is named in errors.
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return t.text
+	}
+}
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '$' || c == '_' || isLetter(c):
+			start := i
+			if c == '$' {
+				i++
+			}
+
+			for i < len(expr) && (expr[i] == '_' || expr[i] == '.' || isLetter(expr[i]) || isDigit(expr[i])) {
+				i++
+			}
+
+			kind := tokenIdent
+			if c == '$' {
+				kind = tokenHeader
+			}
+
+			tokens = append(tokens, token{kind: kind, text: expr[start:i], pos: start})
+		case isDigit(c) || (c == '-' && i+1 < len(expr) && isDigit(expr[i+1])):
+			start := i
+			i++
+			for i < len(expr) && (isDigit(expr[i]) || strings.IndexByte(".eE+-", expr[i]) >= 0) {
+				// Signs are part of a number only after an exponent.
+				if (expr[i] == '+' || expr[i] == '-') && expr[i-1] != 'e' && expr[i-1] != 'E' {
+					break
+				}
+
+				i++
+			}
+
+			number, err := strconv.ParseFloat(expr[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid number %s at position %d", ErrInvalidFilter, expr[start:i], start)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], pos: start, number: number})
+		case c == '"' || c == '\'':
+			text, end, err := unquote(expr, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end
+		default:
+			operator := ""
+			for _, op := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expr[i:], op) {
+					operator = op
+
+					break
+				}
+			}
+
+			if operator == "" {
+				return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidFilter, expr[i], i)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: i})
+			i += len(operator)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// unquote returns the string literal starting at start and the position after it.
+func unquote(expr string, start int) (string, int, error) {
+	quote := expr[start]
+	var b strings.Builder
+	for i := start + 1; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(expr):
+			i++
+			switch expr[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(expr[i])
+			default:
+				return "", 0, fmt.Errorf("%w: invalid escape at position %d", ErrInvalidFilter, i-1)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidFilter, start)
+}
+
+func isLetter(c byte) bool {
+	return c < unicode.MaxASCII && unicode.IsLetter(rune(c))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser builds the expression tree with recursive descent:
+//
+//	or         = and { "||" and }
+//	and        = not { "&&" not }
+//	not        = "!" not | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand    = number | string | "true" | "false" | "null" | path | header | "(" or ")"
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// accept consumes the next token if it is the operator.
+func (p *parser) accept(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) unexpected(t token) error {
+	return fmt.Errorf("%w: unexpected %s at position %d", ErrInvalidFilter, t.describe(), t.pos)
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		pos := p.tokens[p.pos-1].pos
+		var right node
+		if right, err = p.and(); err == nil {
+			left, err = logical(left, right, false, pos)
+		}
+	}
+
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	for err == nil && p.accept("&&") {
+		pos := p.tokens[p.pos-1].pos
+		var right node
+		if right, err = p.not(); err == nil {
+			left, err = logical(left, right, true, pos)
+		}
+	}
+
+	return left, err
+}
+
+func (p *parser) not() (node, error) {
+	t := p.peek()
+	if !p.accept("!") {
+		return p.comparison()
+	}
+
+	if err := p.enter(t); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	if !operand.kind().boolean() {
+		return nil, fmt.Errorf("%w: ! needs a condition at position %d", ErrInvalidFilter, t.pos)
+	}
+
+	return &notNode{operand}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return left, nil
+	}
+
+	op, ok := comparisons[t.text]
+	if !ok {
+		return left, nil
+	}
+
+	p.next()
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(op, left, right, t)
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &literal{t.number}, nil
+	case tokenString:
+		return &literal{t.text}, nil
+	case tokenHeader:
+		// Publishers set any headers, so every publisher header name is known.
+		name := strings.TrimPrefix(t.text, PublisherHeader)
+		if _, ok := headers[t.text]; !ok && (name == t.text || name == "" || strings.Contains(name, ".")) {
+			return nil, fmt.Errorf("%w: unknown header %s at position %d", ErrInvalidFilter, t.text, t.pos)
+		}
+
+		return &header{t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{true}, nil
+		case "false":
+			return &literal{false}, nil
+		case "null":
+			return &literal{nil}, nil
+		}
+
+		path := strings.Split(t.text, ".")
+		for _, name := range path {
+			if name == "" {
+				return nil, fmt.Errorf("%w: invalid path %s at position %d", ErrInvalidFilter, t.text, t.pos)
+			}
+		}
+
+		return &field{path}, nil
+	case tokenOperator:
+		if t.text != "(" {
+			break
+		}
+
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokenOperator || closing.text != ")" {
+			return nil, p.unexpected(closing)
+		}
+
+		return inner, nil
+	}
+
+	return nil, p.unexpected(t)
+}
+
+// enter limits the nesting of the expression, so that deep expressions cannot exhaust the stack.
+func (p *parser) enter(t token) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("%w: nesting deeper than %d at position %d", ErrInvalidFilter, maxDepth, t.pos)
+	}
+
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
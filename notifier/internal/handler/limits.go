@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
+)
+
+// topicLimit is the publish limit of topics matching the pattern.
+type topicLimit struct {
+	pattern string
+	limit   ratelimit.Limit
+}
+
+// PublishLimits limits the messages every client publishes to every topic.
+type PublishLimits struct {
+	store  ratelimit.Store
+	def    ratelimit.Limit
+	topics []topicLimit
+}
+
+// NewPublishLimits returns a new PublishLimits object that keeps buckets in store. Topics are limited by the first
+// of the "<pattern>:<limit>" entries of topics that matches them, like "news.*:10/s", or by def if none matches.
+func NewPublishLimits(store ratelimit.Store, def string, topics []string) (*PublishLimits, error) {
+	defaultLimit, err := ratelimit.ParseLimit(def)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &PublishLimits{store: store, def: defaultLimit}
+	for _, entry := range topics {
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return nil, errors.New(entry + ": " + ratelimit.ErrInvalidLimit.Error())
+		}
+
+		limit, err := ratelimit.ParseLimit(entry[i+1:])
+		if err != nil {
+			return nil, errors.New(entry + ": " + err.Error())
+		}
+
+		limits.topics = append(limits.topics, topicLimit{pattern: strings.TrimSpace(entry[:i]), limit: limit})
+	}
+
+	return limits, nil
+}
+
+// limit returns the limit of the topic.
+func (l *PublishLimits) limit(topic string) ratelimit.Limit {
+	for _, t := range l.topics {
+		if auth.Match(t.pattern, topic) {
+			return t.limit
+		}
+	}
+
+	return l.def
+}
+
+// allow reports whether the client of the request may publish to the topic. Otherwise it responds with 429.
+// Nil limits allow everything.
+func (l *PublishLimits) allow(rw http.ResponseWriter, r *http.Request, topic string, log *logger.Logger) bool {
+	if l == nil {
+		return true
+	}
+
+	return ratelimit.Allow(rw, r, l.store, "publish:"+topic, l.limit(topic), log)
+}
+
+// Allow reports whether the client identified by key, as returned by auth.PrincipalKey, may publish to the topic.
+// It shares the buckets of /publish, so clients of other protocols are limited the same way. Nil limits and
+// a failing store allow everything, like the limits of /publish.
+func (l *PublishLimits) Allow(ctx context.Context, key, topic string, log *logger.Logger) bool {
+	if l == nil {
+		return true
+	}
+
+	limit := l.limit(topic)
+	if limit.IsZero() {
+		return true
+	}
+
+	res, err := l.store.Take(ctx, key+":publish:"+topic, limit)
+	if err != nil {
+		log.Error("Rate limit check failed", zap.Error(err))
+
+		return true
+	}
+
+	return res.Allowed
+}
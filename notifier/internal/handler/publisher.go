@@ -2,37 +2,232 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 
 	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// Defines the errors of invalid publish requests.
+var (
+	ErrInvalidTTL       = errors.New("ttl must be a positive duration, like 90s or 2h")
+	ErrInvalidDelay     = errors.New("delay must be a positive duration, like 90s or 2h")
+	ErrDelayAndDeliver  = errors.New("delay and deliverAt must not be set together")
+	ErrScheduledRequest = errors.New("requests must not be delayed")
+	ErrInvalidTimeout   = errors.New("timeout must be a positive duration up to the reply timeout")
+	ErrInvalidHeader    = errors.New("header names must be made of letters, digits and underscores")
 )
 
+// ErrRequestCanceled is returned when the client cancels a request before its reply arrives.
+var ErrRequestCanceled = errors.New("the request was canceled before a reply arrived")
+
+// statusClientClosedRequest is the nonstandard status that logs report for requests canceled by their client.
+const statusClientClosedRequest = 499
+
+// headerName matches the names of publisher headers, which filters refer to as $headers.name.
+var headerName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
 // Publisher struct contains all handlers for publisher.
 type Publisher struct {
-	svc *service.Notifier
-	log *logger.Logger
+	svc          *service.Notifier
+	limits       *PublishLimits
+	schemas      *schema.Registry
+	replyTimeout time.Duration
+	log          *logger.Logger
 }
 
-// NewPublisher returns a new configured Publisher object.
-func NewPublisher(svc *service.Notifier, log *logger.Logger) *Publisher {
-	return &Publisher{svc, log}
+// NewPublisher returns a new configured Publisher object. Nil limits do not limit publishing, and nil schemas
+// do not validate messages. Requests wait for a reply up to replyTimeout.
+func NewPublisher(svc *service.Notifier, limits *PublishLimits, schemas *schema.Registry, replyTimeout time.Duration,
+	log *logger.Logger) *Publisher {
+	return &Publisher{svc, limits, schemas, replyTimeout, log}
 }
 
-// Publish processes /publish route.
+// Publish processes /publish route. The message is published only if the authenticated principal
+// may publish to the topic and has not exceeded the publish limit of the topic, and if the message matches
+// the latest schema of the topic. Otherwise it is rejected with 400 and the reason. A message whose ID,
+// or Idempotency-Key header if it has none, was published to the topic before is acknowledged but dropped.
+// Delayed messages are acknowledged when they are scheduled. Replies may be published to any inbox.
 func (h *Publisher) Publish(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Add("content-type", "application/json")
-	request := model.PublishRequest{}
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.log.Error(err.Error())
-		fmt.Fprintf(rw, `{"error": {"statusCode": %d, "message": "%s"}}`, http.StatusBadRequest, err.Error())
+	log := logger.FromContext(r.Context(), h.log)
+	request, opts, ok := h.accept(rw, r, service.IsInbox, log)
+	if !ok {
+		return
+	}
+
+	opts.ID = request.ID
+	if opts.ID == "" {
+		opts.ID = r.Header.Get(idempotency.Header)
+	}
+
+	if !h.svc.PublishWith(r.Context(), request.Topic, request.Message, opts) {
+		log.Debug("Duplicate message dropped", zap.String("topic", request.Topic), zap.String("id", opts.ID))
+		rw.Header().Set(idempotency.ReplayedHeader, "true")
+
+		return
+	}
+
+	log.Debug("Message published", zap.String("topic", request.Topic), zap.Any("message", request.Message))
+}
+
+// Request processes /request route. The message is published like by Publish with a temporary inbox
+// as its replyTo, and the first reply is the response. The timeout query parameter, like 5s, shortens
+// the wait for the reply. Requests that no subscriber received fail with 503, and requests without
+// a reply in time with 504.
+func (h *Publisher) Request(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Add("content-type", "application/json")
+	log := logger.FromContext(r.Context(), h.log)
+	request, opts, ok := h.accept(rw, r, func(string) bool { return false }, log)
+	if !ok {
+		return
+	}
+
+	if !opts.DeliverAt.IsZero() {
+		middleware.WriteError(rw, http.StatusBadRequest, ErrScheduledRequest.Error())
+
+		return
+	}
+
+	timeout := h.replyTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 || timeout > h.replyTimeout {
+			middleware.WriteError(rw, http.StatusBadRequest, ErrInvalidTimeout.Error())
+
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	reply, err := h.svc.Request(ctx, request.Topic, request.Message, opts)
+	switch {
+	case errors.Is(err, service.ErrNoResponders):
+		log.Info("Request has no responders", zap.String("topic", request.Topic))
+		middleware.WriteError(rw, http.StatusServiceUnavailable, err.Error())
+
+		return
+	case errors.Is(err, service.ErrNoReply):
+		log.Info("Request has no reply", zap.String("topic", request.Topic), zap.Duration("timeout", timeout))
+		middleware.WriteError(rw, http.StatusGatewayTimeout, err.Error())
+
+		return
+	case err != nil:
+		// The client is usually gone, but the response is written for the proxies and logs that are not.
+		log.Info("Request canceled by the client", zap.String("topic", request.Topic),
+			zap.Int("status", statusClientClosedRequest), zap.Error(err))
+		middleware.WriteError(rw, http.StatusServiceUnavailable, ErrRequestCanceled.Error())
 
 		return
 	}
 
-	h.svc.Publish(request.Topic, request.Message)
-	h.log.Debug(fmt.Sprintf("The publisher sends a new message <<< %s >>> to the <<< %s >>> topic", request.Message, request.Topic))
+	frame, span := deliver("http", reply.Topic, reply)
+	err = json.NewEncoder(rw).Encode(frame)
+	tracing.End(span, err)
+}
+
+// accept decodes the publish request and checks that the principal may publish to the topic, or that the topic
+// is allowed anyway, that the publish limit of the topic is not exceeded and that the message matches the schema
+// of the topic. Otherwise it responds with the error.
+func (h *Publisher) accept(rw http.ResponseWriter, r *http.Request, allowed func(topic string) bool,
+	log *logger.Logger) (*model.PublishRequest, *service.PublishOptions, bool) {
+	request := &model.PublishRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		log.Error("Request body decoding failed", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return nil, nil, false
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	if !allowed(request.Topic) && (!ok || !principal.CanPublish(request.Topic)) {
+		log.Info("Publishing is forbidden", zap.String("topic", request.Topic))
+		middleware.WriteError(rw, http.StatusForbidden, "publishing to the topic is forbidden")
+
+		return nil, nil, false
+	}
+
+	// Inboxes are single-use topics, so they are not limited.
+	if !service.IsInbox(request.Topic) && !h.limits.allow(rw, r, request.Topic, h.log) {
+		return nil, nil, false
+	}
+
+	opts, err := publishOptions(request)
+	if err != nil {
+		log.Info("Invalid publish options", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return nil, nil, false
+	}
+
+	if err = h.schemas.Validate(request.Topic, request.Message); err != nil {
+		log.Info("Message does not match the schema", zap.String("topic", request.Topic), zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return nil, nil, false
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("messaging.destination", request.Topic))
+
+	return request, opts, true
+}
+
+// publishOptions returns the retention, TTL, publishing time, reply topic and headers of the request.
+func publishOptions(request *model.PublishRequest) (*service.PublishOptions, error) {
+	opts := &service.PublishOptions{
+		Retain:        request.Retain,
+		ReplyTo:       request.ReplyTo,
+		CorrelationID: request.CorrelationID,
+		Headers:       request.Headers,
+	}
+	for name := range request.Headers {
+		if !headerName.MatchString(name) {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	if request.TTL != "" {
+		ttl, err := time.ParseDuration(request.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, ErrInvalidTTL
+		}
+
+		opts.TTL = ttl
+	}
+
+	if request.Delay != "" {
+		if request.DeliverAt != nil {
+			return nil, ErrDelayAndDeliver
+		}
+
+		delay, err := time.ParseDuration(request.Delay)
+		if err != nil || delay <= 0 {
+			return nil, ErrInvalidDelay
+		}
+
+		opts.DeliverAt = time.Now().Add(delay)
+	}
+
+	if request.DeliverAt != nil {
+		opts.DeliverAt = *request.DeliverAt
+	}
+
+	return opts, nil
 }
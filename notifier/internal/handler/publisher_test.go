@@ -3,15 +3,23 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
 )
 
 func TestPublish_handler(t *testing.T) {
@@ -30,28 +38,63 @@ func TestPublish_handler(t *testing.T) {
 			body:     `{"topic":"games","message":"New Indiana Jones Game Coming From Bethesda"}`,
 			expected: "",
 		},
+		{
+			name:     "Forbidden topic",
+			body:     `{"topic":"secrets","message":"..."}`,
+			expected: `{"error":{"statusCode":403,"message":"publishing to the topic is forbidden"}}`,
+		},
 		{
 			name:     "Wrong JSON field type",
 			body:     `{"topic":1,"message":"Hello World!"}`,
-			expected: `{"error": {"statusCode": 400, "message": "json: cannot unmarshal number into Go struct field PublishRequest.topic of type string"}}`,
+			expected: `{"error":{"statusCode":400,"message":"json: cannot unmarshal number into Go struct field PublishRequest.topic of type string"}}`,
 		},
 		{
 			name:     "Empty request body",
 			body:     ``,
-			expected: `{"error": {"statusCode": 400, "message": "EOF"}}`,
+			expected: `{"error":{"statusCode":400,"message":"EOF"}}`,
+		},
+		{
+			name:     "Scheduled",
+			body:     `{"topic":"news","message":"...","ttl":"1h","delay":"2h"}`,
+			expected: "",
+		},
+		{
+			name:     "Headers",
+			body:     `{"topic":"news","message":"...","headers":{"region":"eu","source_id":"7"}}`,
+			expected: "",
+		},
+		{
+			name:     "Invalid header",
+			body:     `{"topic":"news","message":"...","headers":{"x-region":"eu"}}`,
+			expected: `{"error":{"statusCode":400,"message":"header names must be made of letters, digits and underscores"}}`,
+		},
+		{
+			name:     "Invalid TTL",
+			body:     `{"topic":"news","message":"...","ttl":"1 hour"}`,
+			expected: `{"error":{"statusCode":400,"message":"ttl must be a positive duration, like 90s or 2h"}}`,
+		},
+		{
+			name:     "Negative delay",
+			body:     `{"topic":"news","message":"...","delay":"-1m"}`,
+			expected: `{"error":{"statusCode":400,"message":"delay must be a positive duration, like 90s or 2h"}}`,
+		},
+		{
+			name:     "Delay and deliverAt",
+			body:     `{"topic":"news","message":"...","delay":"1m","deliverAt":"2030-01-01T00:00:00Z"}`,
+			expected: `{"error":{"statusCode":400,"message":"delay and deliverAt must not be set together"}}`,
 		},
 	}
 
-	svc := service.NewNotifier()
+	svc := service.NewNotifier(0, 0, 0, nil)
 	for _, testCase := range testCases {
 		log, err := logger.New()
 		if err != nil {
 			t.Errorf("Logger initialization throws an error: %v", err)
 		}
 
-		handl := handler.NewPublisher(svc, log)
+		handl := handler.NewPublisher(svc, nil, nil, time.Second, log)
 		mux := http.NewServeMux()
-		mux.HandleFunc("/publish", handl.Publish)
+		mux.Handle("/publish", authorized(http.HandlerFunc(handl.Publish)))
 
 		rec := httptest.NewRecorder()
 		req, err := http.NewRequest("POST", "/publish", bytes.NewBufferString(testCase.body))
@@ -64,3 +107,200 @@ func TestPublish_handler(t *testing.T) {
 		assert.Equal(t, rec.Body.String(), testCase.expected)
 	}
 }
+
+func TestPublish_rateLimit(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	limits, err := handler.NewPublishLimits(ratelimit.NewMemoryStore(), "", []string{"news:1/m"})
+	if err != nil {
+		t.Fatalf("Publish limits initialization throws an error: %v", err)
+	}
+
+	handl := authorized(http.HandlerFunc(handler.NewPublisher(service.NewNotifier(0, 0, 0, nil), limits, nil, time.Second, log).Publish))
+	publish := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handl.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish", bytes.NewBufferString(body)))
+
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, publish(`{"topic":"news","message":"..."}`).Code)
+
+	rec := publish(`{"topic":"news","message":"..."}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// Topics without a limit are not limited.
+	assert.Equal(t, http.StatusOK, publish(`{"topic":"games","message":"..."}`).Code)
+	assert.Equal(t, http.StatusOK, publish(`{"topic":"games","message":"..."}`).Code)
+}
+
+func TestPublish_duplicate(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, time.Minute, 0, nil)
+	messages := svc.Subscribe("news")
+	handl := authorized(http.HandlerFunc(handler.NewPublisher(svc, nil, nil, time.Second, log).Publish))
+	publish := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/publish", bytes.NewBufferString(body))
+		req.Header.Set(idempotency.Header, key)
+		handl.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	assert.Empty(t, publish("", `{"id":"1","topic":"news","message":"first"}`).Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, "first", (<-messages).Message)
+
+	rec := publish("", `{"id":"1","topic":"news","message":"first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(idempotency.ReplayedHeader))
+
+	// The Idempotency-Key header is the ID of messages without one.
+	publish("key", `{"topic":"news","message":"second"}`)
+	assert.Equal(t, "second", (<-messages).Message)
+	assert.Equal(t, "true", publish("key", `{"topic":"news","message":"second"}`).Header().Get(idempotency.ReplayedHeader))
+}
+
+func TestPublish_retained(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/publish", bytes.NewBufferString(`{"topic":"news","message":"first","retain":true}`))
+	authorized(http.HandlerFunc(handler.NewPublisher(svc, nil, nil, time.Second, log).Publish)).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	messages := svc.Subscribe("news")
+	defer svc.Unsubscribe("news", messages)
+
+	envelope := <-messages
+	assert.Equal(t, "first", envelope.Message)
+	assert.True(t, envelope.Retained)
+}
+
+func TestPublish_schema(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	registry := schema.NewRegistry()
+	_, _, err = registry.Register("news", json.RawMessage(`{"type":"object","required":["title"],"properties":{"title":{"type":"string"}}}`), "")
+	assert.NoError(t, err)
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	messages := svc.Subscribe("news")
+	defer svc.Unsubscribe("news", messages)
+
+	handl := authorized(http.HandlerFunc(handler.NewPublisher(svc, nil, registry, time.Second, log).Publish))
+	publish := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handl.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish", bytes.NewBufferString(body)))
+
+		return rec
+	}
+
+	rec := publish(`{"topic":"news","message":{"title":1}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t,
+		`{"error":{"statusCode":400,"message":"message does not match the schema version 1: message.title must be of type string"}}`,
+		rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, publish(`{"topic":"news","message":"..."}`).Code)
+
+	// Only the valid message is published.
+	assert.Equal(t, http.StatusOK, publish(`{"topic":"news","message":{"title":"..."}}`).Code)
+	assert.Equal(t, map[string]interface{}{"title": "..."}, (<-messages).Message)
+	// Topics without a schema accept any message.
+	assert.Equal(t, http.StatusOK, publish(`{"topic":"games","message":1}`).Code)
+}
+
+func TestPublish_request(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Errorf("Logger initialization throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	publisher := handler.NewPublisher(svc, nil, nil, time.Second, log)
+	request := authorized(http.HandlerFunc(publisher.Request))
+	// The responder may not publish to the news topic, but it may reply to inboxes.
+	reply := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal := &auth.Principal{Subject: "responder"}
+		publisher.Publish(rw, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+
+	channel := svc.Subscribe("news")
+	defer svc.Unsubscribe("news", channel)
+
+	go func() {
+		envelope := <-channel
+		body, _ := json.Marshal(&model.PublishRequest{
+			Topic:         envelope.ReplyTo,
+			Message:       "pong",
+			CorrelationID: envelope.CorrelationID,
+		})
+		reply.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/publish", bytes.NewReader(body)))
+	}()
+
+	rec := httptest.NewRecorder()
+	request.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/request", bytes.NewBufferString(`{"topic":"news","message":"ping","correlationId":"1"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	frame := model.Frame{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &frame))
+	assert.Equal(t, "pong", frame.Message)
+	assert.Equal(t, "1", frame.CorrelationID)
+
+	testCases := []struct {
+		name     string
+		url      string
+		body     string
+		expected int
+	}{
+		{name: "No reply", url: "/request?timeout=10ms", body: `{"topic":"news","message":"ping"}`, expected: http.StatusGatewayTimeout},
+		{name: "No responders", url: "/request", body: `{"topic":"games","message":"ping"}`, expected: http.StatusServiceUnavailable},
+		{name: "Inbox", url: "/request", body: `{"topic":"_inbox.1","message":"ping"}`, expected: http.StatusForbidden},
+		{name: "Delayed", url: "/request", body: `{"topic":"news","message":"ping","delay":"1m"}`, expected: http.StatusBadRequest},
+		{name: "Timeout too long", url: "/request?timeout=1m", body: `{"topic":"news","message":"ping"}`, expected: http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		rec = httptest.NewRecorder()
+		request.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, testCase.url, bytes.NewBufferString(testCase.body)))
+		assert.Equal(t, testCase.expected, rec.Code, testCase.name)
+	}
+
+	// A request canceled by its client gets an error rather than an empty response.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/request", bytes.NewBufferString(`{"topic":"news","message":"ping"}`))
+	request.ServeHTTP(rec, req.WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, `{"error":{"statusCode":503,"message":"the request was canceled before a reply arrived"}}`,
+		rec.Body.String())
+}
+
+// authorized binds to the request context a principal that may publish and subscribe to the news and games topics.
+func authorized(next http.Handler) http.Handler {
+	principal := &auth.Principal{
+		Subject:   "test",
+		Publish:   []string{"news", "games"},
+		Subscribe: []string{"news", "games"},
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(rw, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
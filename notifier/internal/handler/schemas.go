@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// ErrInvalidVersion is returned when the version query parameter is neither a positive number nor latest.
+var ErrInvalidVersion = errors.New("version must be a positive number or latest")
+
+// Schemas struct contains all handlers for topic schemas.
+type Schemas struct {
+	registry *schema.Registry
+	log      *logger.Logger
+}
+
+// NewSchemas returns a new configured Schemas object.
+func NewSchemas(registry *schema.Registry, log *logger.Logger) *Schemas {
+	return &Schemas{registry, log}
+}
+
+// ServeHTTP processes /schemas route. The topic query parameter names the topic, because topics may contain slashes.
+// Principals with the schema-admin role that may publish to the topic manage its schemas, so plain publishers
+// cannot lift the validation of their own messages, and principals that may publish or subscribe to it read them:
+//
+//	POST   /schemas?topic=...                  registers a new schema version
+//	GET    /schemas?topic=...                  lists the schema versions
+//	GET    /schemas?topic=...&version=...      returns a schema version, or the latest one
+//	DELETE /schemas?topic=...                  deletes the schema versions
+func (h *Schemas) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	topic := r.URL.Query().Get("topic")
+	if topic == "" {
+		middleware.WriteError(rw, http.StatusBadRequest, schema.ErrTopicRequired.Error())
+
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	canPublish := ok && principal.CanPublish(topic)
+	canManage := canPublish && principal.HasRole(auth.RoleSchemaAdmin)
+	switch r.Method {
+	case http.MethodPost:
+		if !canManage {
+			log.Info("Registering a schema is forbidden", zap.String("topic", topic))
+			middleware.WriteError(rw, http.StatusForbidden, "managing the schemas of the topic is forbidden")
+
+			return
+		}
+
+		h.register(rw, r, topic, log)
+	case http.MethodGet:
+		if !canPublish && (!ok || !principal.CanSubscribe(topic)) {
+			middleware.WriteError(rw, http.StatusForbidden, "reading the schemas of the topic is forbidden")
+
+			return
+		}
+
+		value := r.URL.Query().Get("version")
+		if value == "" {
+			schemas, err := h.registry.Get(topic)
+			h.respond(rw, http.StatusOK, schemas, err, log)
+
+			return
+		}
+
+		n := 0
+		if value != "latest" {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil || n <= 0 {
+				middleware.WriteError(rw, http.StatusBadRequest, ErrInvalidVersion.Error())
+
+				return
+			}
+		}
+
+		version, err := h.registry.Version(topic, n)
+		h.respond(rw, http.StatusOK, version, err, log)
+	case http.MethodDelete:
+		if !canManage {
+			log.Info("Deleting schemas is forbidden", zap.String("topic", topic))
+			middleware.WriteError(rw, http.StatusForbidden, "managing the schemas of the topic is forbidden")
+
+			return
+		}
+
+		if err := h.registry.Delete(topic); err != nil {
+			h.respond(rw, 0, nil, err, log)
+
+			return
+		}
+
+		log.Debug("Schemas deleted", zap.String("topic", topic))
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		notAllowed(rw, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+// register adds the schema of the request as the next version of the topic. It responds with 201,
+// or with 200 if the schema is the latest version already, and with 409 if the schema breaks the compatibility.
+func (h *Schemas) register(rw http.ResponseWriter, r *http.Request, topic string, log *logger.Logger) {
+	request := model.SchemaRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Info("Request body decoding failed", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	version, created, err := h.registry.Register(topic, request.Schema, schema.Compatibility(request.Compatibility))
+	if err != nil {
+		h.respond(rw, 0, nil, err, log)
+
+		return
+	}
+
+	if !created {
+		writeJSON(rw, http.StatusOK, version, log)
+
+		return
+	}
+
+	log.Debug("Schema registered", zap.String("topic", topic), zap.Int("version", version.Version))
+	writeJSON(rw, http.StatusCreated, version, log)
+}
+
+// respond writes the value with the status code, or the error of the schema registry.
+func (h *Schemas) respond(rw http.ResponseWriter, statusCode int, value interface{}, err error, log *logger.Logger) {
+	switch {
+	case errors.Is(err, schema.ErrNotFound):
+		middleware.WriteError(rw, http.StatusNotFound, err.Error())
+	case errors.Is(err, schema.ErrIncompatible):
+		log.Info("Incompatible schema rejected", zap.Error(err))
+		middleware.WriteError(rw, http.StatusConflict, err.Error())
+	case err != nil:
+		log.Info("Invalid schema rejected", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(rw, statusCode, value, log)
+	}
+}
@@ -0,0 +1,158 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func TestSchemas(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	registry := schema.NewRegistry()
+	admin := &auth.Principal{Subject: "admin", Roles: []string{auth.RoleSchemaAdmin}, Publish: []string{"news", "games"}}
+	schemas := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handler.NewSchemas(registry, log).ServeHTTP(rw, r.WithContext(auth.NewContext(r.Context(), admin)))
+	})
+	testCases := []struct {
+		name               string
+		method             string
+		url                string
+		body               string
+		expectedStatusCode int
+		expected           string
+	}{
+		{
+			name:               "Register",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=news",
+			body:               `{"schema":{"type":"object","properties":{"title":{"type":"string"}}}}`,
+			expectedStatusCode: http.StatusCreated,
+			expected:           `"version":1,"schema":{"properties":{"title":{"type":"string"}},"type":"object"}`,
+		},
+		{
+			name:               "Register again",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=news",
+			body:               `{"schema":{"properties":{"title":{"type":"string"}},"type":"object"}}`,
+			expectedStatusCode: http.StatusOK,
+			expected:           `"version":1`,
+		},
+		{
+			name:               "Incompatible",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=news",
+			body:               `{"schema":{"type":"object","required":["title"]}}`,
+			expectedStatusCode: http.StatusConflict,
+			expected: `{"error":{"statusCode":409,"message":` +
+				`"incompatible schema: not backward compatible with version 1: message.title is required"}}`,
+		},
+		{
+			name:               "Other compatibility",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=news",
+			body:               `{"schema":{"type":"object","required":["title"],"properties":{"title":{"type":"string"}}},"compatibility":"forward"}`,
+			expectedStatusCode: http.StatusCreated,
+			expected:           `"version":2`,
+		},
+		{
+			name:               "Invalid schema",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=news",
+			body:               `{"schema":{"type":"list"}}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expected: `{"error":{"statusCode":400,"message":` +
+				`"invalid schema: schema.type must be string, number, integer, boolean, object or array"}}`,
+		},
+		{
+			name:               "Forbidden topic",
+			method:             http.MethodPost,
+			url:                "/schemas?topic=secrets",
+			body:               `{"schema":{}}`,
+			expectedStatusCode: http.StatusForbidden,
+			expected:           `{"error":{"statusCode":403,"message":"managing the schemas of the topic is forbidden"}}`,
+		},
+		{
+			name:               "List",
+			method:             http.MethodGet,
+			url:                "/schemas?topic=news",
+			expectedStatusCode: http.StatusOK,
+			expected:           `"compatibility":"forward","versions":[{"topic":"news","version":1`,
+		},
+		{
+			name:               "Latest",
+			method:             http.MethodGet,
+			url:                "/schemas?topic=news&version=latest",
+			expectedStatusCode: http.StatusOK,
+			expected:           `"version":2`,
+		},
+		{
+			name:               "Invalid version",
+			method:             http.MethodGet,
+			url:                "/schemas?topic=news&version=0",
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"version must be a positive number or latest"}}`,
+		},
+		{
+			name:               "Missing topic",
+			method:             http.MethodGet,
+			url:                "/schemas",
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"topic is required"}}`,
+		},
+		{
+			name:               "Delete",
+			method:             http.MethodDelete,
+			url:                "/schemas?topic=news",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "Deleted schemas",
+			method:             http.MethodGet,
+			url:                "/schemas?topic=news&version=1",
+			expectedStatusCode: http.StatusNotFound,
+			expected:           `{"error":{"statusCode":404,"message":"schema is not found"}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		schemas.ServeHTTP(rec, httptest.NewRequest(testCase.method, testCase.url, strings.NewReader(testCase.body)))
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		assert.Contains(t, rec.Body.String(), testCase.expected, testCase.name)
+	}
+}
+
+func TestSchemas_publisher(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	// Publishers without the schema-admin role read the schemas of their topics but do not manage them.
+	schemas := authorized(handler.NewSchemas(schema.NewRegistry(), log))
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		schemas.ServeHTTP(rec, httptest.NewRequest(method, "/schemas?topic=news", strings.NewReader(`{"schema":{}}`)))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code, method)
+		assert.Equal(t, `{"error":{"statusCode":403,"message":"managing the schemas of the topic is forbidden"}}`,
+			rec.Body.String(), method)
+	}
+
+	rec := httptest.NewRecorder()
+	schemas.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schemas?topic=news", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
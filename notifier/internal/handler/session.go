@@ -0,0 +1,415 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/filter"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// outboxSize is the number of frames a session buffers for its writer.
+const outboxSize = 16
+
+// outgoing is a frame waiting for the writer. The span of a delivered message ends once the frame is sent.
+// A closing outgoing makes the writer send a close frame and stop.
+type outgoing struct {
+	frame   *model.Frame
+	span    trace.Span
+	closing bool
+}
+
+// session is a subscriber websocket and its subscriptions. Commands are read by the goroutine of the handler,
+// messages of every subscription are forwarded by a goroutine of the subscription, and all frames are sent
+// by a single writer goroutine, so frames never interleave. The writer also pings the subscriber,
+// and the reader disconnects subscribers that stop answering.
+type session struct {
+	ws            *websocket.Conn
+	svc           *service.Notifier
+	principal     *auth.Principal
+	cfg           *SubscriberConfig
+	log           *logger.Logger
+	subscriptions map[string]*subscription
+	outbox        chan outgoing
+	// done is closed when the session ends and written when the writer stops.
+	done    chan struct{}
+	written chan struct{}
+	wg      sync.WaitGroup
+}
+
+// subscription is a subscription of a session to a topic. It is only changed by the goroutine
+// of the handler, except for the counters of sent and acknowledged messages.
+type subscription struct {
+	id      string
+	topic   string
+	channel chan service.Envelope
+	// stop is closed to stop forwarding and finished when forwarding has stopped.
+	stop     chan struct{}
+	finished chan struct{}
+	mutex    sync.Mutex
+	sent     uint64
+	acked    uint64
+	ackSent  chan struct{}
+}
+
+func newSession(ws *websocket.Conn, svc *service.Notifier, principal *auth.Principal, cfg *SubscriberConfig,
+	log *logger.Logger) *session {
+	return &session{
+		ws:            ws,
+		svc:           svc,
+		principal:     principal,
+		cfg:           cfg,
+		log:           log,
+		subscriptions: make(map[string]*subscription),
+		outbox:        make(chan outgoing, outboxSize),
+		done:          make(chan struct{}),
+		written:       make(chan struct{}),
+	}
+}
+
+// run reads and handles commands until the connection is closed, then unsubscribes from every topic.
+func (s *session) run() {
+	go s.write()
+	defer func() {
+		for _, sub := range s.subscriptions {
+			s.svc.Unsubscribe(sub.topic, sub.channel)
+			close(sub.stop)
+		}
+
+		s.wg.Wait()
+		close(s.done)
+		<-s.written
+	}()
+
+	if s.cfg.MaxMessageBytes > 0 {
+		s.ws.SetReadLimit(s.cfg.MaxMessageBytes)
+	}
+
+	s.ws.SetPongHandler(func(string) error {
+		return s.extendReadDeadline()
+	})
+
+	for {
+		if err := s.extendReadDeadline(); err != nil {
+			s.log.Debug("Subscriber disconnected", zap.Error(err))
+
+			return
+		}
+
+		_, data, err := s.ws.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				s.log.Info("Subscriber stopped answering pings")
+			case websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+				s.log.Info("Subscriber connection failed", zap.Error(err))
+			default:
+				s.log.Debug("Subscriber disconnected", zap.Error(err))
+			}
+
+			return
+		}
+
+		command := model.Command{}
+		if err := json.Unmarshal(data, &command); err != nil {
+			s.log.Info("Command decoding failed", zap.Error(err))
+			s.fail("", http.StatusBadRequest, err.Error())
+
+			continue
+		}
+
+		switch command.Type {
+		case model.CommandSubscribe, "":
+			s.subscribe(&command)
+		case model.CommandUnsubscribe:
+			s.unsubscribe(&command)
+		case model.CommandPing:
+			s.send(&model.Frame{Type: model.FramePong, ID: command.ID})
+		case model.CommandAck:
+			s.ack(&command)
+		default:
+			s.fail(command.ID, http.StatusBadRequest, "unknown command type")
+		}
+	}
+}
+
+func (s *session) subscribe(command *model.Command) {
+	if command.Type == "" && command.ID == "" {
+		command.ID = command.Topic
+	}
+
+	switch {
+	case command.Topic == "":
+		s.fail(command.ID, http.StatusBadRequest, "topic is required")
+
+		return
+	case command.ID == "":
+		s.fail(command.ID, http.StatusBadRequest, "subscription ID is required")
+
+		return
+	case s.subscriptions[command.ID] != nil:
+		s.fail(command.ID, http.StatusConflict, "subscription ID is in use")
+
+		return
+	case s.principal == nil || !s.principal.CanSubscribe(command.Topic):
+		s.log.Info("Subscription is forbidden", zap.String("topic", command.Topic))
+		s.fail(command.ID, http.StatusForbidden, "subscribing to the topic is forbidden")
+
+		return
+	}
+
+	var f *filter.Filter
+	if command.Filter != "" {
+		var err error
+		if f, err = filter.Compile(command.Filter); err != nil {
+			s.fail(command.ID, http.StatusBadRequest, err.Error())
+
+			return
+		}
+	}
+
+	sub := &subscription{
+		id:       command.ID,
+		topic:    command.Topic,
+		channel:  s.svc.SubscribeFilter(command.Topic, f),
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
+		ackSent:  make(chan struct{}, 1),
+	}
+	s.subscriptions[sub.id] = sub
+	s.log.Debug("Subscribed", zap.String("topic", sub.topic), zap.String("subscription", sub.id))
+	// The answer is queued before forwarding starts, so it precedes the messages of the subscription.
+	s.send(&model.Frame{Type: model.FrameSubscribed, ID: sub.id, Topic: sub.topic})
+	s.wg.Add(1)
+	go s.forward(sub)
+}
+
+func (s *session) unsubscribe(command *model.Command) {
+	sub, ok := s.subscriptions[command.ID]
+	if !ok {
+		s.fail(command.ID, http.StatusNotFound, "subscription is not found")
+
+		return
+	}
+
+	s.svc.Unsubscribe(sub.topic, sub.channel)
+	close(sub.stop)
+	<-sub.finished
+	delete(s.subscriptions, sub.id)
+	s.log.Debug("Unsubscribed", zap.String("topic", sub.topic), zap.String("subscription", sub.id))
+	// No message of the subscription follows the answer, because forwarding has finished.
+	s.send(&model.Frame{Type: model.FrameUnsubscribed, ID: sub.id, Topic: sub.topic})
+}
+
+func (s *session) ack(command *model.Command) {
+	sub, ok := s.subscriptions[command.ID]
+	if !ok {
+		s.fail(command.ID, http.StatusNotFound, "subscription is not found")
+
+		return
+	}
+
+	if err := sub.ack(command.Seq); err != nil {
+		s.fail(command.ID, http.StatusBadRequest, err.Error())
+	}
+}
+
+// forward sends the messages of the subscription to the writer until the subscription is stopped.
+// Messages that expired while the subscription waited for acknowledgements are dropped.
+func (s *session) forward(sub *subscription) {
+	defer s.wg.Done()
+	defer close(sub.finished)
+
+	for sub.waitCredit(s.cfg.MaxUnacked) {
+		select {
+		case <-sub.stop:
+			return
+		case envelope := <-sub.channel:
+			if s.svc.Expired(envelope) {
+				continue
+			}
+
+			frame, span := s.message(sub, envelope)
+			select {
+			case s.outbox <- outgoing{frame: frame, span: span}:
+			case <-sub.stop:
+				span.End()
+
+				return
+			case <-s.written:
+				span.End()
+
+				return
+			}
+		}
+	}
+}
+
+// message returns the frame of a delivered message, numbered within its subscription, and the span of its delivery.
+func (s *session) message(sub *subscription, envelope service.Envelope) (*model.Frame, trace.Span) {
+	frame, span := deliver("websocket", sub.topic, envelope)
+	frame.ID = sub.id
+	frame.Seq = sub.next()
+
+	return frame, span
+}
+
+// send queues the frame for the writer. Frames are dropped once the writer has stopped.
+func (s *session) send(frame *model.Frame) {
+	select {
+	case s.outbox <- outgoing{frame: frame}:
+	case <-s.written:
+	}
+}
+
+// fail sends an error frame that answers the command with the ID.
+func (s *session) fail(id string, statusCode int, message string) {
+	s.send(&model.Frame{Type: model.FrameError, ID: id, Error: &model.Error{StatusCode: statusCode, Message: message}})
+}
+
+// goAway queues the shutdown notice followed by a close frame with the "going away" status.
+func (s *session) goAway(ctx context.Context) {
+	notice := &model.Frame{
+		Type:  model.FrameError,
+		Error: &model.Error{StatusCode: http.StatusServiceUnavailable, Message: "server is going away"},
+	}
+	for _, out := range []outgoing{{frame: notice}, {closing: true}} {
+		select {
+		case s.outbox <- out:
+		case <-s.written:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends the queued frames and pings until the session ends, a close frame is sent or sending fails.
+// A failed connection is closed, so the handler stops reading commands too.
+func (s *session) write() {
+	defer close(s.written)
+
+	var ping <-chan time.Time
+	if s.cfg.PingInterval > 0 {
+		ticker := time.NewTicker(s.cfg.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ping:
+			if err := s.writeMessage(websocket.PingMessage, nil); err != nil {
+				s.log.Info("Ping sending failed", zap.Error(err))
+				s.ws.Close()
+
+				return
+			}
+		case out := <-s.outbox:
+			if out.closing {
+				s.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "going away"))
+
+				return
+			}
+
+			data, err := json.Marshal(out.frame)
+			if err == nil {
+				err = s.writeMessage(websocket.TextMessage, data)
+			}
+
+			if out.span != nil {
+				tracing.End(out.span, err)
+			}
+
+			if err != nil {
+				s.log.Error("Frame sending failed", zap.String("type", out.frame.Type), zap.Error(err))
+				s.ws.Close()
+
+				return
+			}
+		}
+	}
+}
+
+// writeMessage writes a websocket message within the write timeout. It is only called by the writer.
+func (s *session) writeMessage(messageType int, data []byte) error {
+	if s.cfg.WriteTimeout > 0 {
+		s.ws.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	}
+
+	return s.ws.WriteMessage(messageType, data)
+}
+
+// extendReadDeadline gives the subscriber another ping interval and pong timeout to show it is alive.
+func (s *session) extendReadDeadline() error {
+	if s.cfg.PingInterval <= 0 {
+		return nil
+	}
+
+	return s.ws.SetReadDeadline(time.Now().Add(s.cfg.PingInterval + s.cfg.PongTimeout))
+}
+
+// next returns the sequence number of the next message of the subscription.
+func (sub *subscription) next() uint64 {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	sub.sent++
+
+	return sub.sent
+}
+
+// ack acknowledges the messages up to and including seq. Acknowledgements of older messages are ignored.
+func (sub *subscription) ack(seq uint64) error {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	if seq > sub.sent {
+		return errors.New("the message is not delivered yet")
+	}
+
+	if seq > sub.acked {
+		sub.acked = seq
+		select {
+		case sub.ackSent <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// waitCredit blocks while maxUnacked messages are not acknowledged. It reports false if the subscription
+// is stopped meanwhile. A zero maxUnacked never blocks.
+func (sub *subscription) waitCredit(maxUnacked int) bool {
+	for {
+		sub.mutex.Lock()
+		unacked := sub.sent - sub.acked
+		sub.mutex.Unlock()
+		if maxUnacked <= 0 || unacked < uint64(maxUnacked) {
+			return true
+		}
+
+		select {
+		case <-sub.stop:
+			return false
+		case <-sub.ackSent:
+		}
+	}
+}
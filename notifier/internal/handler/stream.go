@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// Events processes /subscribe/sse route. It streams the messages of the topic query parameter as server-sent
+// events whose IDs are the sequence numbers of the messages in the topic. A client that reconnects with
+// the Last-Event-ID header first receives the kept messages published after that event.
+func (h *Subscriber) Events(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	topic := r.URL.Query().Get("topic")
+	if !h.authorize(rw, r, topic, log) {
+		return
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		middleware.WriteError(rw, http.StatusInternalServerError, "streaming is not supported")
+
+		return
+	}
+
+	var lastID uint64
+	resume := r.Header.Get("Last-Event-ID")
+	if resume != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(resume, 10, 64); err != nil {
+			middleware.WriteError(rw, http.StatusBadRequest, "Last-Event-ID is not a message sequence number")
+
+			return
+		}
+	}
+
+	channel := h.svc.Subscribe(topic)
+	defer h.svc.Unsubscribe(topic, channel)
+
+	var missed []service.Envelope
+	if resume != "" {
+		missed, _ = h.svc.History(topic, lastID)
+	}
+
+	rw.Header().Set("content-type", "text/event-stream")
+	rw.Header().Set("cache-control", "no-cache")
+	// Disables response buffering of nginx proxies.
+	rw.Header().Set("x-accel-buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Messages published while the history was read are received twice, so the replayed ones are skipped.
+	var replayed uint64
+	for _, envelope := range missed {
+		if err := h.writeEvent(rw, topic, envelope); err != nil {
+			log.Debug("Event stream closed", zap.Error(err))
+
+			return
+		}
+
+		replayed = envelope.Seq
+	}
+
+	flusher.Flush()
+	log.Debug("Event stream subscribed", zap.String("topic", topic), zap.Int("replayed", len(missed)))
+
+	var heartbeat <-chan time.Time
+	if h.cfg.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(h.cfg.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.closed:
+			return
+		case <-heartbeat:
+			_, err = fmt.Fprint(rw, ": heartbeat\n\n")
+		case envelope := <-channel:
+			if envelope.Seq <= replayed {
+				continue
+			}
+
+			err = h.writeEvent(rw, topic, envelope)
+		}
+
+		if err != nil {
+			log.Debug("Event stream closed", zap.Error(err))
+
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the message as an event with the message frame as its data.
+func (h *Subscriber) writeEvent(rw http.ResponseWriter, topic string, envelope service.Envelope) error {
+	frame, span := deliver("sse", topic, envelope)
+	data, err := json.Marshal(frame)
+	if err == nil {
+		_, err = fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", envelope.Seq, model.FrameMessage, data)
+	}
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// Poll processes /poll route. It responds with the kept messages of the topic query parameter published
+// after the cursor query parameter, or waits up to the poll timeout for the next message if there are none.
+// The cursor of the response is passed to the next request; a request without a cursor waits for the next message.
+func (h *Subscriber) Poll(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	topic := r.URL.Query().Get("topic")
+	if !h.authorize(rw, r, topic, log) {
+		return
+	}
+
+	var cursor uint64
+	resume := r.URL.Query().Get("cursor")
+	if resume != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(resume, 10, 64); err != nil {
+			middleware.WriteError(rw, http.StatusBadRequest, "cursor is not a message sequence number")
+
+			return
+		}
+	}
+
+	channel := h.svc.Subscribe(topic)
+	defer h.svc.Unsubscribe(topic, channel)
+
+	envelopes, last := h.svc.History(topic, cursor)
+	if resume == "" {
+		envelopes = nil
+	}
+
+	if len(envelopes) > 0 {
+		h.writePoll(rw, topic, envelopes, last)
+
+		return
+	}
+
+	timer := time.NewTimer(h.cfg.PollTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.closed:
+			h.writePoll(rw, topic, nil, last)
+
+			return
+		case <-timer.C:
+			h.writePoll(rw, topic, nil, last)
+
+			return
+		case envelope := <-channel:
+			// Messages published while the history was read are received too, so the ones it covered are skipped.
+			if resume == "" || envelope.Seq > last {
+				h.writePoll(rw, topic, []service.Envelope{envelope}, last)
+
+				return
+			}
+		}
+	}
+}
+
+// writePoll responds with the messages and the cursor that follows them.
+func (h *Subscriber) writePoll(rw http.ResponseWriter, topic string, envelopes []service.Envelope, cursor uint64) {
+	if len(envelopes) > 0 {
+		cursor = envelopes[len(envelopes)-1].Seq
+	}
+
+	response := model.PollResponse{Messages: []*model.Frame{}, Cursor: cursor}
+	spans := make([]trace.Span, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		frame, span := deliver("poll", topic, envelope)
+		response.Messages = append(response.Messages, frame)
+		spans = append(spans, span)
+	}
+
+	rw.Header().Set("content-type", "application/json")
+	err := json.NewEncoder(rw).Encode(&response)
+	for _, span := range spans {
+		tracing.End(span, err)
+	}
+}
+
+// authorize reports whether the principal of the request may subscribe to the topic.
+// Otherwise it responds with the error.
+func (h *Subscriber) authorize(rw http.ResponseWriter, r *http.Request, topic string, log *logger.Logger) bool {
+	select {
+	case <-h.closed:
+		middleware.WriteError(rw, http.StatusServiceUnavailable, "server is going away")
+
+		return false
+	default:
+	}
+
+	if topic == "" {
+		middleware.WriteError(rw, http.StatusBadRequest, "topic is required")
+
+		return false
+	}
+
+	if principal, ok := auth.FromContext(r.Context()); !ok || !principal.CanSubscribe(topic) {
+		log.Info("Subscription is forbidden", zap.String("topic", topic))
+		middleware.WriteError(rw, http.StatusForbidden, "subscribing to the topic is forbidden")
+
+		return false
+	}
+
+	return true
+}
+
+// deliver returns the frame of a message delivered over the transport and the span of its delivery,
+// which continues the publisher's trace. The trace context of the span is sent along with the message,
+// so the subscriber can continue the trace.
+func deliver(system, topic string, envelope service.Envelope) (*model.Frame, trace.Span) {
+	ctx := tracing.Extract(context.Background(), envelope.TraceContext)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, system+" deliver",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", system),
+			attribute.String("messaging.destination", topic),
+		))
+
+	return &model.Frame{
+		Type:          model.FrameMessage,
+		Topic:         topic,
+		Seq:           envelope.Seq,
+		Message:       envelope.Message,
+		Retained:      envelope.Retained,
+		ReplyTo:       envelope.ReplyTo,
+		CorrelationID: envelope.CorrelationID,
+		Headers:       envelope.Headers,
+		TraceContext:  tracing.Inject(ctx),
+	}, span
+}
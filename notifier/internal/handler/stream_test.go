@@ -0,0 +1,250 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func TestEvents_errors(t *testing.T) {
+	testCases := []struct {
+		name               string
+		url                string
+		lastEventID        string
+		expectedStatusCode int
+		expected           string
+	}{
+		{
+			name:               "Topic is missing",
+			url:                "/subscribe/sse",
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"topic is required"}}`,
+		},
+		{
+			name:               "Forbidden topic",
+			url:                "/subscribe/sse?topic=secrets",
+			expectedStatusCode: http.StatusForbidden,
+			expected:           `{"error":{"statusCode":403,"message":"subscribing to the topic is forbidden"}}`,
+		},
+		{
+			name:               "Invalid Last-Event-ID",
+			url:                "/subscribe/sse?topic=news",
+			lastEventID:        "first",
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"Last-Event-ID is not a message sequence number"}}`,
+		},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	sub := handler.NewSubscriber(service.NewNotifier(0, 0, 10, nil), &handler.SubscriberConfig{}, log)
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+		if testCase.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", testCase.lastEventID)
+		}
+
+		rec := httptest.NewRecorder()
+		authorized(http.HandlerFunc(sub.Events)).ServeHTTP(rec, req)
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		assert.Equal(t, testCase.expected, rec.Body.String(), testCase.name)
+	}
+}
+
+func TestEvents_resume(t *testing.T) {
+	srv, svc, sub := serveSubscriber(t, &handler.SubscriberConfig{})
+	for _, message := range []string{"first", "second", "third"} {
+		svc.Publish(context.Background(), "news", message)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/subscribe/sse?topic=news", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("content-type"))
+
+	events := bufio.NewReader(resp.Body)
+	assert.Equal(t, "id: 2\nevent: message\ndata: {\"type\":\"message\",\"topic\":\"news\",\"seq\":2,\"message\":\"second\"}\n\n",
+		readEvent(t, events))
+	assert.Equal(t, "id: 3\nevent: message\ndata: {\"type\":\"message\",\"topic\":\"news\",\"seq\":3,\"message\":\"third\"}\n\n",
+		readEvent(t, events))
+
+	svc.Publish(context.Background(), "news", "fourth")
+	assert.Equal(t, "id: 4\nevent: message\ndata: {\"type\":\"message\",\"topic\":\"news\",\"seq\":4,\"message\":\"fourth\"}\n\n",
+		readEvent(t, events))
+
+	// Shutdown ends the stream.
+	require.NoError(t, sub.Shutdown(context.Background()))
+	_, err = events.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestEvents_order(t *testing.T) {
+	srv, svc, _ := serveSubscriber(t, &handler.SubscriberConfig{})
+	resp, err := http.Get(srv.URL + "/subscribe/sse?topic=news")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	const messages = 100
+	for i := 0; i < messages; i++ {
+		svc.Publish(context.Background(), "news", i)
+	}
+
+	// Clients resume from the last event ID they received, so the IDs must increase.
+	events := bufio.NewReader(resp.Body)
+	for i := 1; i <= messages; i++ {
+		assert.True(t, strings.HasPrefix(readEvent(t, events), fmt.Sprintf("id: %d\n", i)), "event %d", i)
+	}
+}
+
+func TestEvents_heartbeat(t *testing.T) {
+	srv, _, _ := serveSubscriber(t, &handler.SubscriberConfig{HeartbeatInterval: 10 * time.Millisecond})
+
+	resp, err := http.Get(srv.URL + "/subscribe/sse?topic=news")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, ": heartbeat\n\n", readEvent(t, bufio.NewReader(resp.Body)))
+}
+
+func TestPoll(t *testing.T) {
+	testCases := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expected           string
+	}{
+		{
+			name:               "Kept messages",
+			query:              "?topic=news&cursor=1",
+			expectedStatusCode: http.StatusOK,
+			expected: `{"messages":[{"type":"message","topic":"news","seq":2,"message":"second"},` +
+				`{"type":"message","topic":"news","seq":3,"message":"third"}],"cursor":3}`,
+		},
+		{
+			name:               "No message in time",
+			query:              "?topic=news&cursor=3",
+			expectedStatusCode: http.StatusOK,
+			expected:           `{"messages":[],"cursor":3}`,
+		},
+		{
+			name:               "Cursor before a restart",
+			query:              "?topic=news&cursor=10",
+			expectedStatusCode: http.StatusOK,
+			expected: `{"messages":[{"type":"message","topic":"news","seq":1,"message":"first"},` +
+				`{"type":"message","topic":"news","seq":2,"message":"second"},` +
+				`{"type":"message","topic":"news","seq":3,"message":"third"}],"cursor":3}`,
+		},
+		{
+			name:               "Without cursor",
+			query:              "?topic=news",
+			expectedStatusCode: http.StatusOK,
+			expected:           `{"messages":[],"cursor":3}`,
+		},
+		{
+			name:               "Invalid cursor",
+			query:              "?topic=news&cursor=-1",
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"cursor is not a message sequence number"}}`,
+		},
+		{
+			name:               "Forbidden topic",
+			query:              "?topic=secrets",
+			expectedStatusCode: http.StatusForbidden,
+			expected:           `{"error":{"statusCode":403,"message":"subscribing to the topic is forbidden"}}`,
+		},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, 0, 10, nil)
+	for _, message := range []string{"first", "second", "third"} {
+		svc.Publish(context.Background(), "news", message)
+	}
+
+	sub := handler.NewSubscriber(svc, &handler.SubscriberConfig{PollTimeout: 10 * time.Millisecond}, log)
+	for _, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		authorized(http.HandlerFunc(sub.Poll)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/poll"+testCase.query, nil))
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		assert.Equal(t, testCase.expected, strings.TrimSpace(rec.Body.String()), testCase.name)
+	}
+}
+
+func TestPoll_wait(t *testing.T) {
+	srv, svc, _ := serveSubscriber(t, &handler.SubscriberConfig{PollTimeout: time.Second})
+	svc.Publish(context.Background(), "news", "first")
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(srv.URL + "/poll?topic=news&cursor=1")
+		if err != nil {
+			t.Errorf("Poll request throws an error: %v", err)
+		}
+
+		responses <- resp
+	}()
+
+	// The request is waiting once it subscribed to the topic.
+	for svc.Stats().Subscribers == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	svc.Publish(context.Background(), "news", "second")
+	resp := <-responses
+	require.NotNil(t, resp)
+
+	defer resp.Body.Close()
+
+	response := model.PollResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	require.Len(t, response.Messages, 1)
+	assert.Equal(t, "second", response.Messages[0].Message)
+	assert.Equal(t, uint64(2), response.Cursor)
+}
+
+// readEvent reads the lines of an event up to the blank line that ends it.
+func readEvent(t *testing.T, events *bufio.Reader) string {
+	t.Helper()
+
+	event := ""
+	for !strings.HasSuffix(event, "\n\n") {
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+
+		event += line
+	}
+
+	return event
+}
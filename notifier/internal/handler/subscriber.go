@@ -2,53 +2,187 @@
 package handler
 
 import (
-	"fmt"
+	"context"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
-	"golang.org/x/net/websocket"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
 
-	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
 )
 
+// tracerName is the name of the tracer that records websocket deliveries.
+const tracerName = "github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+
+// SubscriberConfig configures the websocket sessions of subscribers.
+type SubscriberConfig struct {
+	// MaxUnacked is the number of unacknowledged messages at which deliveries of a subscription pause.
+	// Zero does not wait for acknowledgements.
+	MaxUnacked int
+	// PingInterval is the time between pings. A subscriber that answers neither a ping nor sends a command
+	// within PingInterval and PongTimeout is disconnected. Zero disables pings and keeps idle subscribers.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// WriteTimeout is the time a subscriber has to receive a frame. Zero means no timeout.
+	WriteTimeout time.Duration
+	// MaxMessageBytes is the size of the largest command. Larger commands close the connection
+	// with the 1009 status code. Zero means no limit.
+	MaxMessageBytes int64
+	// Compression negotiates the permessage-deflate extension.
+	Compression bool
+	// HeartbeatInterval is the time between comments sent to idle event streams. Zero disables them.
+	HeartbeatInterval time.Duration
+	// PollTimeout is the time a long-poll request waits for a message.
+	PollTimeout time.Duration
+	// AllowedOrigins are the origins of other sites whose pages may open websocket sessions, "*" allows any.
+	// Pages of the notifier origin and clients that send no Origin header, which are not browsers, may always open them.
+	AllowedOrigins []string
+}
+
 // Subscriber struct contains all handler for subscriber.
 type Subscriber struct {
-	svc *service.Notifier
-	log *logger.Logger
+	svc      *service.Notifier
+	cfg      *SubscriberConfig
+	upgrader *websocket.Upgrader
+	log      *logger.Logger
+	mutex    sync.Mutex
+	sessions map[*session]struct{}
+	closing  bool
+	// closed is closed by Shutdown to end event streams and long polls.
+	closed chan struct{}
+	wg     sync.WaitGroup
 }
 
 // NewSubscriber returns a new Subscriber object.
-func NewSubscriber(svc *service.Notifier, log *logger.Logger) *Subscriber {
-	return &Subscriber{svc, log}
+func NewSubscriber(svc *service.Notifier, cfg *SubscriberConfig, log *logger.Logger) *Subscriber {
+	return &Subscriber{
+		svc: svc,
+		cfg: cfg,
+		upgrader: &websocket.Upgrader{
+			EnableCompression: cfg.Compression,
+			CheckOrigin:       checkOrigin(cfg.AllowedOrigins),
+		},
+		log:      log,
+		sessions: make(map[*session]struct{}),
+		closed:   make(chan struct{}),
+	}
 }
 
-// Subscribe processes /subscribe route.
-func (h *Subscriber) Subscribe(ws *websocket.Conn) {
-	for {
-		request := model.SubscribeRequest{}
-		if err := websocket.JSON.Receive(ws, &request); err != nil {
-			h.log.Error(err.Error())
-			fmt.Fprintf(ws, `{"error": {"statusCode": %d, "message": "%s"}}`, http.StatusBadRequest, err.Error())
+// checkOrigin returns the origin check of websocket handshakes, which browsers send from any page.
+// It allows requests without an Origin header, requests from the notifier origin and requests from the allowed origins.
+func checkOrigin(allowedOrigins []string) func(*http.Request) bool {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			return func(*http.Request) bool { return true }
+		}
 
-			return
+		allowed[origin] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed[origin] {
+			return true
 		}
 
-		channel := h.svc.Subscribe(request.Topic)
-		h.log.Debug(fmt.Sprintf("The user subscribed to the <<< %s >>> topic", request.Topic))
-		go func(channel chan interface{}) {
-			for message := range channel {
-				response := model.SuccessResponse{
-					Message: message,
-				}
+		u, err := url.Parse(origin)
+
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// Subscribe processes /subscribe route. Every connection is a session that multiplexes subscriptions
+// to several topics, see model.Command and model.Frame for the protocol. Subscriptions to topics
+// the authenticated principal may not subscribe to are rejected with an error frame,
+// and the connection stays open for other commands.
+func (h *Subscriber) Subscribe(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	ws, err := h.upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		// The upgrader has responded with the error.
+		log.Info("Websocket upgrade failed", zap.Error(err))
+
+		return
+	}
+
+	defer ws.Close()
 
-				if err := websocket.JSON.Send(ws, &response); err != nil {
-					h.log.Error(err.Error())
-					fmt.Fprintf(ws, `{"error": {"statusCode": %d, "message": "%s"}}`, http.StatusInternalServerError, err.Error())
+	principal, _ := auth.FromContext(r.Context())
+	s := newSession(ws, h.svc, principal, h.cfg, log)
+	if !h.register(s) {
+		go s.write()
+		s.goAway(context.Background())
+		<-s.written
 
-					return
-				}
-			}
-		}(channel)
+		return
 	}
+
+	defer h.unregister(s)
+
+	s.run()
+}
+
+// Shutdown sends a "going away" notice and a close frame to every connected subscriber
+// and waits until their connections are closed or ctx is done. Connections that are still
+// open when ctx is done are closed forcibly. Event streams and long polls end immediately.
+// New subscribers are rejected after Shutdown is called.
+func (h *Subscriber) Shutdown(ctx context.Context) error {
+	h.mutex.Lock()
+	if !h.closing {
+		h.closing = true
+		close(h.closed)
+	}
+
+	for s := range h.sessions {
+		go s.goAway(ctx)
+	}
+	h.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		h.mutex.Lock()
+		for s := range h.sessions {
+			s.ws.Close()
+		}
+		h.mutex.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+func (h *Subscriber) register(s *session) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closing {
+		return false
+	}
+
+	h.sessions[s] = struct{}{}
+	h.wg.Add(1)
+
+	return true
+}
+
+func (h *Subscriber) unregister(s *session) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.sessions, s)
+	h.wg.Done()
 }
@@ -2,78 +2,406 @@
 package handler_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
-	"golang.org/x/net/websocket"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
 )
 
 func TestSubscribe_handler(t *testing.T) {
 	testCases := []struct {
 		name     string
 		subInput string
-		pubInput string
 		expected string
 	}{
 		{
 			name:     "OK",
 			subInput: `{"topic": "news"}`,
-			pubInput: `{"topic": "news", "message": "..."}`,
-			expected: ``,
+			expected: `{"type":"subscribed","id":"news","topic":"news"}`,
 		},
 		{
-			name:     "OK",
-			subInput: `{"topic": "games"}`,
-			pubInput: `{"topic": "games", "message": "..."}`,
-			expected: ``,
+			name:     "OK with ID",
+			subInput: `{"type": "subscribe", "id": "s1", "topic": "games"}`,
+			expected: `{"type":"subscribed","id":"s1","topic":"games"}`,
+		},
+		{
+			name:     "Ping",
+			subInput: `{"type": "ping", "id": "p1"}`,
+			expected: `{"type":"pong","id":"p1"}`,
 		},
 		{
 			name:     "Wrong JSON field type",
 			subInput: `{"topic": 1}`,
-			expected: `{"error": {"statusCode": 400, "message": "json: cannot unmarshal number into Go struct field SubscribeRequest.topic of type string"}}`,
+			expected: `{"type":"error","error":{"statusCode":400,"message":"json: cannot unmarshal number into Go struct field Command.topic of type string"}}`,
 		},
 		{
 			name:     "Empty request body",
 			subInput: ``,
-			expected: `{"error": {"statusCode": 400, "message": "unexpected end of JSON input"}}`,
+			expected: `{"type":"error","error":{"statusCode":400,"message":"unexpected end of JSON input"}}`,
+		},
+		{
+			name:     "Topic is missing",
+			subInput: `{"type": "subscribe", "id": "s1"}`,
+			expected: `{"type":"error","id":"s1","error":{"statusCode":400,"message":"topic is required"}}`,
+		},
+		{
+			name:     "ID is missing",
+			subInput: `{"type": "subscribe", "topic": "news"}`,
+			expected: `{"type":"error","error":{"statusCode":400,"message":"subscription ID is required"}}`,
+		},
+		{
+			name:     "Unknown subscription",
+			subInput: `{"type": "unsubscribe", "id": "s1"}`,
+			expected: `{"type":"error","id":"s1","error":{"statusCode":404,"message":"subscription is not found"}}`,
+		},
+		{
+			name:     "Unknown command",
+			subInput: `{"type": "publish", "id": "c1"}`,
+			expected: `{"type":"error","id":"c1","error":{"statusCode":400,"message":"unknown command type"}}`,
+		},
+		{
+			name:     "Forbidden topic",
+			subInput: `{"type": "subscribe", "id": "s1", "topic": "secrets"}`,
+			expected: `{"type":"error","id":"s1","error":{"statusCode":403,"message":"subscribing to the topic is forbidden"}}`,
 		},
 	}
 
 	for _, testCase := range testCases {
-		svc := service.NewNotifier()
-		log, err := logger.New()
-		if err != nil {
-			t.Errorf("Logger initialization throws an error: %v", err)
-		}
+		t.Run(testCase.name, func(t *testing.T) {
+			ws, _, _ := dialSubscriber(t, &handler.SubscriberConfig{})
+
+			send(t, ws, testCase.subInput)
+			assert.Equal(t, testCase.expected, receiveString(t, ws))
+		})
+	}
+}
+
+func TestSubscribe_multiplexed(t *testing.T) {
+	ws, svc, _ := dialSubscriber(t, &handler.SubscriberConfig{})
+
+	for _, command := range []string{
+		`{"type": "subscribe", "id": "first", "topic": "news"}`,
+		`{"type": "subscribe", "id": "second", "topic": "news"}`,
+		`{"type": "subscribe", "id": "third", "topic": "games"}`,
+	} {
+		send(t, ws, command)
+		assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
+	}
+
+	svc.Publish(context.Background(), "news", "...")
+	svc.Publish(context.Background(), "games", "...")
+
+	received := map[string]model.Frame{}
+	for len(received) < 3 {
+		frame := receiveMessage(t, ws)
+		received[frame.ID] = frame
+	}
+
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: "first", Topic: "news", Seq: 1, Message: "..."}, received["first"])
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: "second", Topic: "news", Seq: 1, Message: "..."}, received["second"])
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: "third", Topic: "games", Seq: 1, Message: "..."}, received["third"])
+}
+
+func TestSubscribe_filter(t *testing.T) {
+	ws, svc, _ := dialSubscriber(t, &handler.SubscriberConfig{})
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "news", "filter": "price <"}`)
+	assert.Equal(t,
+		`{"type":"error","id":"s1","error":{"statusCode":400,"message":"invalid filter: unexpected end of filter at position 7"}}`,
+		receiveString(t, ws))
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "news", "filter": "price < 20 && $topic == 'news'"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
+
+	svc.Publish(context.Background(), "news", map[string]interface{}{"price": 25})
+	svc.Publish(context.Background(), "news", map[string]interface{}{"price": 15})
+	assert.Equal(t, map[string]interface{}{"price": float64(15)}, receiveMessage(t, ws).Message)
+
+	// Publisher headers are delivered and may be filtered on.
+	send(t, ws, `{"type": "subscribe", "id": "s2", "topic": "games", "filter": "$headers.region == 'eu'"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
 
-		pub := handler.NewPublisher(svc, log)
-		sub := handler.NewSubscriber(svc, log)
-		mux := http.NewServeMux()
-		mux.Handle("/subscribe", websocket.Handler(sub.Subscribe))
-		mux.HandleFunc("/publish", pub.Publish)
+	svc.PublishWith(context.Background(), "games", "us", &service.PublishOptions{Headers: map[string]string{"region": "us"}})
+	svc.PublishWith(context.Background(), "games", "eu", &service.PublishOptions{Headers: map[string]string{"region": "eu"}})
+	frame := receiveMessage(t, ws)
+	assert.Equal(t, "eu", frame.Message)
+	assert.Equal(t, map[string]string{"region": "eu"}, frame.Headers)
+}
+
+func TestSubscribe_unsubscribe(t *testing.T) {
+	ws, svc, _ := dialSubscriber(t, &handler.SubscriberConfig{})
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "news"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "games"}`)
+	assert.Equal(t, `{"type":"error","id":"s1","error":{"statusCode":409,"message":"subscription ID is in use"}}`,
+		receiveString(t, ws))
+
+	send(t, ws, `{"type": "unsubscribe", "id": "s1"}`)
+	assert.Equal(t, `{"type":"unsubscribed","id":"s1","topic":"news"}`, receiveString(t, ws))
+	assert.Equal(t, 0, svc.Stats().Subscribers)
+
+	// The ID is free again once the subscription is gone.
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "games"}`)
+	assert.Equal(t, `{"type":"subscribed","id":"s1","topic":"games"}`, receiveString(t, ws))
+}
+
+func TestSubscribe_ack(t *testing.T) {
+	ws, svc, _ := dialSubscriber(t, &handler.SubscriberConfig{MaxUnacked: 1})
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "news"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
+
+	svc.Publish(context.Background(), "news", "first")
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: "s1", Topic: "news", Seq: 1, Message: "first"}, receiveMessage(t, ws))
+
+	send(t, ws, `{"type": "ack", "id": "s1", "seq": 2}`)
+	assert.Equal(t, `{"type":"error","id":"s1","error":{"statusCode":400,"message":"the message is not delivered yet"}}`,
+		receiveString(t, ws))
+
+	// The second message waits for the acknowledgement of the first one, so the pong comes first.
+	svc.Publish(context.Background(), "news", "second")
+	send(t, ws, `{"type": "ping", "id": "p1"}`)
+	assert.Equal(t, `{"type":"pong","id":"p1"}`, receiveString(t, ws))
+
+	send(t, ws, `{"type": "ack", "id": "s1", "seq": 1}`)
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: "s1", Topic: "news", Seq: 2, Message: "second"}, receiveMessage(t, ws))
+}
+
+func TestSubscribe_shutdown(t *testing.T) {
+	ws, _, sub := dialSubscriber(t, &handler.SubscriberConfig{})
 
-		subSrv := httptest.NewServer(websocket.Handler(sub.Subscribe))
-		defer subSrv.Close()
+	send(t, ws, `{"topic": "news"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
 
-		pubSrv := httptest.NewServer(http.HandlerFunc(pub.Publish))
-		defer pubSrv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- sub.Shutdown(ctx)
+	}()
+
+	assert.Equal(t, `{"type":"error","error":{"statusCode":503,"message":"server is going away"}}`, receiveString(t, ws))
+
+	// The client answers the close frame while reading it.
+	_, _, err := ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	assert.NoError(t, <-shutdownErr)
+}
+
+func TestSubscribe_ping(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pong     bool
+		expected bool
+	}{
+		{
+			name:     "Pongs keep the connection",
+			pong:     true,
+			expected: true,
+		},
+		{
+			name:     "Missing pongs close the connection",
+			pong:     false,
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ws, _, _ := dialSubscriber(t, &handler.SubscriberConfig{
+				PingInterval: 20 * time.Millisecond,
+				PongTimeout:  20 * time.Millisecond,
+			})
 
-		url := "ws" + strings.TrimPrefix(subSrv.URL, "http")
-		ws, err := websocket.Dial(url, "", subSrv.URL)
-		if err != nil {
-			t.Errorf("Websocket connection throws an error: %v", err)
+			pings := 0
+			ws.SetPingHandler(func(data string) error {
+				pings++
+				if !testCase.pong {
+					return nil
+				}
+
+				return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+			})
+
+			// Pings are handled while reading, and only a closed connection ends the read early.
+			ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			_, _, err := ws.ReadMessage()
+
+			var netErr net.Error
+			assert.Equal(t, testCase.expected, errors.As(err, &netErr) && netErr.Timeout(), err)
+			assert.NotZero(t, pings)
+		})
+	}
+}
+
+func TestSubscribe_limits(t *testing.T) {
+	ws, _, _ := dialSubscriber(t, &handler.SubscriberConfig{MaxMessageBytes: 64})
+
+	send(t, ws, `{"type": "ping", "id": "p1"}`)
+	assert.Equal(t, `{"type":"pong","id":"p1"}`, receiveString(t, ws))
+
+	send(t, ws, `{"type": "subscribe", "id": "s1", "topic": "`+strings.Repeat("news", 16)+`"}`)
+	_, _, err := ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), err)
+}
+
+func TestSubscribe_compression(t *testing.T) {
+	srv, _, _ := serveSubscriber(t, &handler.SubscriberConfig{Compression: true})
+
+	dialer := &websocket.Dialer{EnableCompression: true}
+	ws, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/subscribe", nil)
+	require.NoError(t, err)
+
+	defer ws.Close()
+
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	send(t, ws, `{"type": "ping", "id": "p1"}`)
+	assert.Equal(t, `{"type":"pong","id":"p1"}`, receiveString(t, ws))
+}
+
+func TestSubscribe_origin(t *testing.T) {
+	srv, _, _ := serveSubscriber(t, &handler.SubscriberConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	testCases := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{name: "No origin", expected: true},
+		{name: "Allowed origin", origin: "https://app.example.com", expected: true},
+		{name: "Same origin", origin: srv.URL, expected: true},
+		{name: "Other origin", origin: "https://evil.example.com"},
+	}
+
+	for _, testCase := range testCases {
+		header := http.Header{}
+		if testCase.origin != "" {
+			header.Set("Origin", testCase.origin)
 		}
 
-		defer ws.Close()
+		ws, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/subscribe", header)
+		if !testCase.expected {
+			assert.Equal(t, websocket.ErrBadHandshake, err, testCase.name)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, testCase.name)
+			resp.Body.Close()
 
-		if err := websocket.Message.Send(ws, testCase.subInput); err != nil {
-			t.Errorf("Websocket request throws an error: %v", err)
+			continue
 		}
+
+		if assert.NoError(t, err, testCase.name) {
+			ws.Close()
+		}
+	}
+}
+
+func TestSubscribe_traceContext(t *testing.T) {
+	if _, err := tracing.Init(context.Background(), "test", tracing.ExporterNone); err != nil {
+		t.Fatalf("Tracing initialization throws an error: %v", err)
 	}
+
+	// Other tests expect messages without trace context.
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ws, svc, _ := dialSubscriber(t, &handler.SubscriberConfig{})
+
+	send(t, ws, `{"topic": "news"}`)
+	assert.Equal(t, model.FrameSubscribed, receiveFrame(t, ws).Type)
+
+	traceparent := "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"
+	svc.Publish(tracing.Extract(context.Background(), map[string]string{"traceparent": traceparent}), "news", "...")
+
+	frame := receiveFrame(t, ws)
+	assert.Equal(t, "...", frame.Message)
+	assert.Contains(t, frame.TraceContext["traceparent"], "0102030405060708090a0b0c0d0e0f10")
+}
+
+// serveSubscriber serves the routes of a new Subscriber for the principal of authorized.
+func serveSubscriber(t *testing.T, cfg *handler.SubscriberConfig) (*httptest.Server, *service.Notifier, *handler.Subscriber) {
+	t.Helper()
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, 0, 10, nil)
+	sub := handler.NewSubscriber(svc, cfg, log)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscribe", sub.Subscribe)
+	mux.HandleFunc("/subscribe/sse", sub.Events)
+	mux.HandleFunc("/poll", sub.Poll)
+	subSrv := httptest.NewServer(authorized(mux))
+	t.Cleanup(subSrv.Close)
+
+	return subSrv, svc, sub
+}
+
+// dialSubscriber connects to a new Subscriber served for the principal of authorized.
+func dialSubscriber(t *testing.T, cfg *handler.SubscriberConfig) (*websocket.Conn, *service.Notifier, *handler.Subscriber) {
+	t.Helper()
+
+	subSrv, svc, sub := serveSubscriber(t, cfg)
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(subSrv.URL, "http")+"/subscribe", nil)
+	if err != nil {
+		t.Fatalf("Websocket connection throws an error: %v", err)
+	}
+
+	t.Cleanup(func() { ws.Close() })
+
+	return ws, svc, sub
+}
+
+func send(t *testing.T, ws *websocket.Conn, command string) {
+	t.Helper()
+
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(command)))
+}
+
+func receiveString(t *testing.T, ws *websocket.Conn) string {
+	t.Helper()
+
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := ws.ReadMessage()
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func receiveFrame(t *testing.T, ws *websocket.Conn) model.Frame {
+	t.Helper()
+
+	frame := model.Frame{}
+	require.NoError(t, json.Unmarshal([]byte(receiveString(t, ws)), &frame))
+
+	return frame
+}
+
+// receiveMessage receives a frame without its trace context, which depends on the tracing setup of other tests.
+func receiveMessage(t *testing.T, ws *websocket.Conn) model.Frame {
+	t.Helper()
+
+	frame := receiveFrame(t, ws)
+	frame.TraceContext = nil
+
+	return frame
 }
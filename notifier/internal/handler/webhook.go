@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// Webhooks struct contains all handlers for webhook subscriptions.
+type Webhooks struct {
+	manager *webhook.Manager
+	log     *logger.Logger
+}
+
+// NewWebhooks returns a new configured Webhooks object.
+func NewWebhooks(manager *webhook.Manager, log *logger.Logger) *Webhooks {
+	return &Webhooks{manager, log}
+}
+
+// ServeHTTP processes /webhooks routes. Webhooks belong to the authenticated principal that created them:
+//
+//	POST   /webhooks                   creates a webhook
+//	GET    /webhooks                   lists the webhooks
+//	GET    /webhooks/{id}              returns a webhook
+//	DELETE /webhooks/{id}              deletes a webhook
+//	POST   /webhooks/{id}/enable       enables a disabled webhook
+//	GET    /webhooks/{id}/deliveries   lists the latest delivery attempts
+func (h *Webhooks) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.log)
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		middleware.WriteError(rw, http.StatusForbidden, "managing webhooks is forbidden")
+
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		switch r.Method {
+		case http.MethodPost:
+			h.create(rw, r, principal, log)
+		case http.MethodGet:
+			writeJSON(rw, http.StatusOK, h.manager.List(principal.Subject), log)
+		default:
+			notAllowed(rw, http.MethodGet, http.MethodPost)
+		}
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			hook, err := h.manager.Get(principal.Subject, parts[0])
+			h.respond(rw, http.StatusOK, hook, err, log)
+		case http.MethodDelete:
+			if err := h.manager.Delete(principal.Subject, parts[0]); err != nil {
+				h.respond(rw, 0, nil, err, log)
+
+				return
+			}
+
+			log.Debug("Webhook deleted", zap.String("webhook", parts[0]))
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			notAllowed(rw, http.MethodGet, http.MethodDelete)
+		}
+	case len(parts) == 2 && parts[1] == "enable":
+		if r.Method != http.MethodPost {
+			notAllowed(rw, http.MethodPost)
+
+			return
+		}
+
+		hook, err := h.manager.Enable(principal.Subject, parts[0])
+		h.respond(rw, http.StatusOK, hook, err, log)
+	case len(parts) == 2 && parts[1] == "deliveries":
+		if r.Method != http.MethodGet {
+			notAllowed(rw, http.MethodGet)
+
+			return
+		}
+
+		deliveries, err := h.manager.Deliveries(principal.Subject, parts[0])
+		h.respond(rw, http.StatusOK, deliveries, err, log)
+	default:
+		middleware.WriteError(rw, http.StatusNotFound, "route is not found")
+	}
+}
+
+// create adds a webhook if the principal may subscribe to its topic pattern.
+func (h *Webhooks) create(rw http.ResponseWriter, r *http.Request, principal *auth.Principal, log *logger.Logger) {
+	request := model.WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Info("Request body decoding failed", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if request.Topic != "" && !principal.CanSubscribe(request.Topic) {
+		log.Info("Webhook subscription is forbidden", zap.String("topic", request.Topic))
+		middleware.WriteError(rw, http.StatusForbidden, "subscribing to the topic is forbidden")
+
+		return
+	}
+
+	hook, err := h.manager.Create(principal.Subject, &request)
+	if err != nil {
+		log.Info("Webhook creation failed", zap.Error(err))
+		middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	log.Debug("Webhook created", zap.String("webhook", hook.ID), zap.String("topic", hook.Topic))
+	rw.Header().Set("location", "/webhooks/"+hook.ID)
+	writeJSON(rw, http.StatusCreated, hook, log)
+}
+
+// respond writes the value with the status code, or the error of the webhook manager.
+func (h *Webhooks) respond(rw http.ResponseWriter, statusCode int, value interface{}, err error, log *logger.Logger) {
+	if errors.Is(err, webhook.ErrNotFound) {
+		middleware.WriteError(rw, http.StatusNotFound, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		log.Error("Webhook request failed", zap.Error(err))
+		middleware.WriteError(rw, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	writeJSON(rw, statusCode, value, log)
+}
+
+// writeJSON writes the value as the JSON body of the response.
+func writeJSON(rw http.ResponseWriter, statusCode int, value interface{}, log *logger.Logger) {
+	rw.Header().Set("content-type", "application/json")
+	rw.WriteHeader(statusCode)
+	if err := json.NewEncoder(rw).Encode(value); err != nil {
+		log.Error("Response encoding failed", zap.Error(err))
+	}
+}
+
+// notAllowed responds with 405 and the methods allowed on the route.
+func notAllowed(rw http.ResponseWriter, methods ...string) {
+	rw.Header().Set("allow", strings.Join(methods, ", "))
+	middleware.WriteError(rw, http.StatusMethodNotAllowed, "method is not allowed")
+}
@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func TestWebhooks(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	manager := webhook.New(service.NewNotifier(0, 0, 0, nil), &webhook.Config{Timeout: time.Second}, http.DefaultClient, log)
+	defer manager.Shutdown(context.Background())
+
+	webhooks := authorized(handler.NewWebhooks(manager, log))
+	rec := httptest.NewRecorder()
+	webhooks.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks",
+		bytes.NewBufferString(`{"topic":"news","url":"https://example.com/hooks","secret":"0123456789abcdef"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	created := model.Webhook{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.Equal(t, "/webhooks/"+created.ID, rec.Header().Get("location"))
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	testCases := []struct {
+		name               string
+		method             string
+		url                string
+		body               string
+		expectedStatusCode int
+		expected           string
+	}{
+		{
+			name:               "Forbidden topic",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			body:               `{"topic":"secrets","url":"https://example.com/hooks","secret":"0123456789abcdef"}`,
+			expectedStatusCode: http.StatusForbidden,
+			expected:           `{"error":{"statusCode":403,"message":"subscribing to the topic is forbidden"}}`,
+		},
+		{
+			name:               "Short secret",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			body:               `{"topic":"news","url":"https://example.com/hooks","secret":"secret"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expected:           `{"error":{"statusCode":400,"message":"secret must be at least 16 characters long"}}`,
+		},
+		{
+			name:               "List",
+			method:             http.MethodGet,
+			url:                "/webhooks",
+			expectedStatusCode: http.StatusOK,
+			expected:           `"id":"` + created.ID + `"`,
+		},
+		{
+			name:               "Get",
+			method:             http.MethodGet,
+			url:                "/webhooks/" + created.ID,
+			expectedStatusCode: http.StatusOK,
+			expected:           `"enabled":true`,
+		},
+		{
+			name:               "Deliveries",
+			method:             http.MethodGet,
+			url:                "/webhooks/" + created.ID + "/deliveries",
+			expectedStatusCode: http.StatusOK,
+			expected:           `[]`,
+		},
+		{
+			name:               "Enable",
+			method:             http.MethodPost,
+			url:                "/webhooks/" + created.ID + "/enable",
+			expectedStatusCode: http.StatusOK,
+			expected:           `"failures":0`,
+		},
+		{
+			name:               "Method not allowed",
+			method:             http.MethodPut,
+			url:                "/webhooks/" + created.ID,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expected:           `{"error":{"statusCode":405,"message":"method is not allowed"}}`,
+		},
+		{
+			name:               "Unknown webhook",
+			method:             http.MethodGet,
+			url:                "/webhooks/unknown",
+			expectedStatusCode: http.StatusNotFound,
+			expected:           `{"error":{"statusCode":404,"message":"webhook is not found"}}`,
+		},
+		{
+			name:               "Delete",
+			method:             http.MethodDelete,
+			url:                "/webhooks/" + created.ID,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "Deleted webhook",
+			method:             http.MethodDelete,
+			url:                "/webhooks/" + created.ID,
+			expectedStatusCode: http.StatusNotFound,
+			expected:           `{"error":{"statusCode":404,"message":"webhook is not found"}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		webhooks.ServeHTTP(rec, httptest.NewRequest(testCase.method, testCase.url, strings.NewReader(testCase.body)))
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+		assert.Contains(t, rec.Body.String(), testCase.expected, testCase.name)
+	}
+}
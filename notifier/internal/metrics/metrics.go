@@ -0,0 +1,127 @@
+// Package metrics contains Prometheus metrics of the notifier.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+)
+
+// OtherTopics is the topic label of the topics that match none of the patterns.
+const OtherTopics = "other"
+
+// Notifier exports notifier events as Prometheus metrics. It implements service.Observer and mqtt.Observer.
+// Topics are labeled with the first of the patterns they match, so the number of series stays bounded
+// however many topics clients publish to.
+type Notifier struct {
+	patterns    []string
+	published   *prometheus.CounterVec
+	subscribers *prometheus.GaugeVec
+	pending     prometheus.Gauge
+	delivery    *prometheus.HistogramVec
+	dropped     *prometheus.CounterVec
+	duplicated  *prometheus.CounterVec
+	expired     *prometheus.CounterVec
+	rejected    *prometheus.CounterVec
+}
+
+// NewNotifier returns a new Notifier object registered in reg. Topics are labeled with the first of the patterns
+// they match, where "*" matches any characters, or with OtherTopics.
+func NewNotifier(reg prometheus.Registerer, patterns []string) *Notifier {
+	m := &Notifier{
+		patterns: patterns,
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_published_messages_total",
+			Help: "Number of messages published to the topics of a pattern.",
+		}, []string{"topic"}),
+		subscribers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "notifier_subscribers",
+			Help: "Number of active subscribers of the topics of a pattern.",
+		}, []string{"topic"}),
+		pending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "notifier_pending_deliveries",
+			Help: "Number of messages waiting to be received by subscribers.",
+		}),
+		delivery: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "notifier_delivery_duration_seconds",
+			Help:    "Time from publishing a message to its receiving by a subscriber.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"topic"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_dropped_messages_total",
+			Help: "Number of messages that were not delivered to a subscriber.",
+		}, []string{"topic"}),
+		duplicated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_duplicate_messages_total",
+			Help: "Number of messages that were not published because their ID was published before.",
+		}, []string{"topic"}),
+		expired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_expired_messages_total",
+			Help: "Number of messages whose TTL expired before they reached a subscriber.",
+		}, []string{"topic"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_rejected_messages_total",
+			Help: "Number of messages that were dropped before publishing, by the reason.",
+		}, []string{"topic", "reason"}),
+	}
+
+	reg.MustRegister(m.published, m.subscribers, m.pending, m.delivery, m.dropped, m.duplicated, m.expired, m.rejected)
+
+	return m
+}
+
+// label returns the topic label of the topic.
+func (m *Notifier) label(topic string) string {
+	for _, pattern := range m.patterns {
+		if auth.Match(pattern, topic) {
+			return pattern
+		}
+	}
+
+	return OtherTopics
+}
+
+// Published counts the published message and queues a delivery per subscriber.
+func (m *Notifier) Published(topic string, subscribers int) {
+	m.published.WithLabelValues(m.label(topic)).Inc()
+	m.pending.Add(float64(subscribers))
+}
+
+// Subscribed increments the number of topic subscribers.
+func (m *Notifier) Subscribed(topic string) {
+	m.subscribers.WithLabelValues(m.label(topic)).Inc()
+}
+
+// Unsubscribed decrements the number of topic subscribers.
+func (m *Notifier) Unsubscribed(topic string) {
+	m.subscribers.WithLabelValues(m.label(topic)).Dec()
+}
+
+// Delivered records the delivery latency.
+func (m *Notifier) Delivered(topic string, latency time.Duration) {
+	m.pending.Dec()
+	m.delivery.WithLabelValues(m.label(topic)).Observe(latency.Seconds())
+}
+
+// Dropped counts the dropped message.
+func (m *Notifier) Dropped(topic string) {
+	m.pending.Dec()
+	m.dropped.WithLabelValues(m.label(topic)).Inc()
+}
+
+// Duplicated counts the duplicate message.
+func (m *Notifier) Duplicated(topic string) {
+	m.duplicated.WithLabelValues(m.label(topic)).Inc()
+}
+
+// Expired counts the expired message.
+func (m *Notifier) Expired(topic string) {
+	m.expired.WithLabelValues(m.label(topic)).Inc()
+}
+
+// Rejected counts the message dropped before publishing. It implements mqtt.Observer.
+func (m *Notifier) Rejected(topic, reason string) {
+	m.rejected.WithLabelValues(m.label(topic), reason).Inc()
+}
@@ -0,0 +1,38 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/metrics"
+)
+
+func TestNotifier_topicLabel(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewNotifier(reg, []string{"news.*", "news.sport"})
+	for _, topic := range []string{"news.sport", "news.weather", "orders.1", "orders.2", "_inbox.x"} {
+		m.Published(topic, 0)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather throws an error: %v", err)
+	}
+
+	published := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "notifier_published_messages_total" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			published[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+		}
+	}
+
+	expected := map[string]float64{"news.*": 2, metrics.OtherTopics: 3}
+	if len(published) != len(expected) || published["news.*"] != 2 || published[metrics.OtherTopics] != 3 {
+		t.Errorf("Published messages are %v, expected %v", published, expected)
+	}
+}
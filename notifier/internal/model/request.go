@@ -1,13 +1,67 @@
 // Package model contains the described structures that will be used in the project.
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // PublishRequest struct represents the publish request body to the server.
+// A message with the ID of a message published to the topic within the dedup window is dropped.
+// A retained message is delivered to every new subscriber of the topic until another retained message
+// replaces it, and a retained null or empty string message removes it.
+// TTL and Delay are durations like "90s" or "2h". A message is published after Delay or at DeliverAt,
+// and it is not delivered once TTL has passed since it was published. Subscribers answer a message with
+// a ReplyTo by publishing to that topic with the same CorrelationID. Headers are passed to subscribers,
+// which may filter the messages on them, and their names are made of letters, digits and underscores.
 type PublishRequest struct {
-	Topic   string      `json:"topic"`
-	Message interface{} `json:"message"`
+	ID        string      `json:"id,omitempty"`
+	Topic     string      `json:"topic"`
+	Message   interface{} `json:"message"`
+	Retain    bool        `json:"retain,omitempty"`
+	TTL       string      `json:"ttl,omitempty"`
+	Delay     string      `json:"delay,omitempty"`
+	DeliverAt *time.Time  `json:"deliverAt,omitempty"`
+
+	ReplyTo       string `json:"replyTo,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Defines the commands a subscriber sends over its websocket.
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandPing        = "ping"
+	CommandAck         = "ack"
+)
+
+// Command struct represents a frame sent by a subscriber. ID is chosen by the client: it names the subscription
+// of subscribe, unsubscribe and ack commands, and it is echoed by every frame caused by the command.
+// Frames without a type, like {"topic": "news"}, are subscribe commands whose ID defaults to the topic.
+type Command struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Topic string `json:"topic,omitempty"`
+	// Seq of an ack command acknowledges the messages of the subscription up to and including Seq.
+	Seq uint64 `json:"seq,omitempty"`
+	// Filter of a subscribe command is the expression the messages of the subscription must match.
+	Filter string `json:"filter,omitempty"`
+}
+
+// WebhookRequest struct represents the request body of a webhook subscription. Messages of the topics
+// matching Topic, where "*" matches any characters, are posted to URL and signed with Secret.
+type WebhookRequest struct {
+	Topic  string `json:"topic"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
 }
 
-// SubscribeRequest struct represents the subscribe request body to the server.
-type SubscribeRequest struct {
-	Topic string `json:"topic"`
+// SchemaRequest struct represents the request body of a topic schema: a JSON Schema that the messages published
+// to the topic must match, and the compatibility that every new schema of the topic must keep with the latest one.
+// An empty Compatibility keeps the compatibility of the topic.
+type SchemaRequest struct {
+	Schema        json.RawMessage `json:"schema"`
+	Compatibility string          `json:"compatibility,omitempty"`
 }
@@ -1,7 +1,89 @@
 // Package model contains the described structures that will be used in the project.
 package model
 
-// SuccessResponse struct represents the response body from the server.
-type SuccessResponse struct {
-	Message interface{} `json:"message"`
+import (
+	"encoding/json"
+	"time"
+)
+
+// Defines the types of frames sent to subscribers.
+const (
+	FrameMessage      = "message"
+	FrameSubscribed   = "subscribed"
+	FrameUnsubscribed = "unsubscribed"
+	FramePong         = "pong"
+	FrameError        = "error"
+)
+
+// Frame struct represents a frame sent to a subscriber: a delivered message or the answer to a command.
+// Messages carry the ID of their subscription and a sequence number that starts at 1 for every subscription.
+// Retained is set on the retained message of the topic that is delivered when subscribing.
+type Frame struct {
+	Type     string      `json:"type"`
+	ID       string      `json:"id,omitempty"`
+	Topic    string      `json:"topic,omitempty"`
+	Seq      uint64      `json:"seq,omitempty"`
+	Message  interface{} `json:"message,omitempty"`
+	Retained bool        `json:"retained,omitempty"`
+	// ReplyTo is the topic the subscriber publishes its answer to, with the same CorrelationID.
+	ReplyTo       string `json:"replyTo,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Headers are the headers set by the publisher of the message.
+	Headers map[string]string `json:"headers,omitempty"`
+	// TraceContext carries the W3C trace context of the delivery, for example {"traceparent": "00-..."}.
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+	Error        *Error            `json:"error,omitempty"`
+}
+
+// Error struct represents the error of a frame, in the format of the error responses of the HTTP routes.
+type Error struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+}
+
+// PollResponse struct represents the response of a long poll: the messages published after the cursor
+// of the request, and the cursor of the next request.
+type PollResponse struct {
+	Messages []*Frame `json:"messages"`
+	Cursor   uint64   `json:"cursor"`
+}
+
+// Webhook struct represents a webhook subscription. Its secret is never sent back.
+// A webhook is disabled after repeated failed deliveries, and Failures counts the failed deliveries in a row.
+type Webhook struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	URL       string    `json:"url"`
+	Enabled   bool      `json:"enabled"`
+	Failures  int       `json:"failures"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Delivery struct represents an attempt to post a message to a webhook. StatusCode is zero
+// if the request failed without a response.
+type Delivery struct {
+	ID         string    `json:"id"`
+	Topic      string    `json:"topic"`
+	Seq        uint64    `json:"seq"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   float64   `json:"durationSeconds"`
+	Time       time.Time `json:"time"`
+}
+
+// SchemaVersion struct represents a version of the schema of a topic. Versions start at 1 for every topic.
+type SchemaVersion struct {
+	Topic     string          `json:"topic"`
+	Version   int             `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// TopicSchemas struct represents the schema versions of a topic from the oldest to the latest,
+// which the messages published to the topic must match.
+type TopicSchemas struct {
+	Topic         string          `json:"topic"`
+	Compatibility string          `json:"compatibility"`
+	Versions      []SchemaVersion `json:"versions"`
 }
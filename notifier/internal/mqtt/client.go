@@ -0,0 +1,538 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// maxPacketID is the largest packet ID, which limits the QoS 1 messages in flight.
+const maxPacketID = 65535
+
+var (
+	// ErrUnsupportedQoS is returned when a client publishes with QoS 2, which is not supported.
+	ErrUnsupportedQoS = errors.New("QoS 2 is not supported")
+	// ErrInvalidTopic is returned when a client publishes to an empty topic or a topic with wildcards.
+	ErrInvalidTopic = errors.New("topic is invalid")
+)
+
+// subscription is a subscription of a client to a topic filter.
+type subscription struct {
+	filter  string
+	qos     byte
+	channel chan service.Envelope
+	// done is closed when the client unsubscribes.
+	done chan struct{}
+}
+
+// client is the connection of an MQTT client. Packets are written by a single writer goroutine,
+// which takes them from the out channel, and messages are forwarded by a goroutine per subscription.
+type client struct {
+	server    *Server
+	conn      net.Conn
+	reader    *bufio.Reader
+	id        string
+	principal *auth.Principal
+	will      *publishPacket
+	keepAlive time.Duration
+	log       *logger.Logger
+	mutex     sync.Mutex
+	subs      map[string]*subscription
+	inflight  map[uint16]struct{}
+	nextID    uint16
+	// credits holds a token for every QoS 1 message in flight.
+	credits chan struct{}
+	out     chan []byte
+	// done is closed when the reader stops, and written when the writer stops.
+	done    chan struct{}
+	written chan struct{}
+	wg      sync.WaitGroup
+}
+
+// connect reads the CONNECT packet of the connection and authenticates the client. Refused clients
+// are answered with CONNACK before the error is returned.
+func (s *Server) connect(conn net.Conn, log *logger.Logger) (*client, error) {
+	if s.cfg.ConnectTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.cfg.ConnectTimeout))
+	}
+
+	reader := bufio.NewReader(conn)
+	p, err := readPacket(reader, s.cfg.MaxPacketBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.kind != typeConnect {
+		return nil, ErrMalformedPacket
+	}
+
+	connect, err := decodeConnect(p)
+	if err != nil {
+		return nil, err
+	}
+
+	inflight := s.cfg.MaxInflight
+	if inflight <= 0 || inflight > maxPacketID {
+		inflight = maxPacketID
+	}
+
+	c := &client{
+		server:    s,
+		conn:      conn,
+		reader:    reader,
+		id:        connect.clientID,
+		will:      connect.will,
+		keepAlive: time.Duration(connect.keepAlive) * time.Second,
+		subs:      make(map[string]*subscription),
+		inflight:  make(map[uint16]struct{}),
+		credits:   make(chan struct{}, inflight),
+		out:       make(chan []byte, 16),
+		done:      make(chan struct{}),
+		written:   make(chan struct{}),
+	}
+
+	if c.id == "" {
+		c.id = newClientID()
+	}
+
+	c.log = log.With(zap.String("client_id", c.id))
+	code := c.authenticate(connect)
+	if code != connAccepted {
+		c.writeDirect(encodeConnAck(code))
+
+		return nil, fmt.Errorf("CONNECT refused with return code %d", code)
+	}
+
+	return c, nil
+}
+
+// authenticate returns the CONNACK return code of the CONNECT packet. The credential is the password,
+// or the username if there is no password.
+func (c *client) authenticate(connect *connectPacket) byte {
+	if connect.protocol != "MQTT" || connect.level != protocolLevel {
+		return connBadProtocolVersion
+	}
+
+	// Sessions are not kept after a disconnect, so a client that asks for one must identify itself.
+	if connect.clientID == "" && !connect.cleanSession {
+		return connIdentifierRejected
+	}
+
+	credential := connect.password
+	if credential == "" {
+		credential = connect.username
+	}
+
+	principal, err := c.server.authn.AuthenticateCredential(credential)
+	if err != nil {
+		c.log.Info("MQTT authentication failed", zap.Error(err))
+
+		return connBadCredentials
+	}
+
+	c.principal = principal
+	if c.will != nil && (!validTopic(c.will.topic) || !principal.CanPublish(c.will.topic)) {
+		c.log.Info("Last will is forbidden", zap.String("topic", c.will.topic))
+
+		return connNotAuthorized
+	}
+
+	return connAccepted
+}
+
+// run accepts the client and serves its packets until the connection is closed. The last will
+// is published unless the client disconnected with DISCONNECT or the server is shutting down.
+func (c *client) run() {
+	if err := c.writeDirect(encodeConnAck(connAccepted)); err != nil {
+		return
+	}
+
+	c.log.Debug("MQTT client connected", zap.String("subject", c.principal.Subject))
+	go c.write()
+
+	disconnected, err := c.read()
+	close(c.done)
+	c.unsubscribeAll()
+	c.wg.Wait()
+	<-c.written
+
+	if !disconnected && c.will != nil && !c.server.closed() {
+		c.log.Info("MQTT client lost, publishing its last will", zap.String("topic", c.will.topic), zap.Error(err))
+		c.publish(c.will)
+
+		return
+	}
+
+	c.log.Debug("MQTT client disconnected", zap.Error(err))
+}
+
+// read serves the packets of the client. It reports whether the client disconnected with DISCONNECT.
+func (c *client) read() (bool, error) {
+	for {
+		// A client that sends nothing for one and a half keep alive periods is lost.
+		deadline := time.Time{}
+		if c.keepAlive > 0 {
+			deadline = time.Now().Add(c.keepAlive * 3 / 2)
+		}
+
+		c.conn.SetReadDeadline(deadline)
+		p, err := readPacket(c.reader, c.server.cfg.MaxPacketBytes)
+		if err != nil {
+			return false, err
+		}
+
+		switch p.kind {
+		case typePublish:
+			err = c.handlePublish(p)
+		case typePubAck:
+			err = c.handlePubAck(p)
+		case typeSubscribe:
+			err = c.handleSubscribe(p)
+		case typeUnsubscribe:
+			err = c.handleUnsubscribe(p)
+		case typePingReq:
+			c.send(encodePingResp())
+		case typeDisconnect:
+			return true, nil
+		default:
+			err = ErrMalformedPacket
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (c *client) handlePublish(p *packet) error {
+	publish, err := decodePublish(p)
+	if err != nil {
+		return err
+	}
+
+	if !validTopic(publish.topic) {
+		return ErrInvalidTopic
+	}
+
+	if publish.qos > 1 {
+		return ErrUnsupportedQoS
+	}
+
+	// MQTT 3.1.1 cannot refuse a message, so forbidden messages are acknowledged and dropped.
+	if c.principal.CanPublish(publish.topic) {
+		c.publish(publish)
+	} else {
+		c.log.Info("Publishing is forbidden", zap.String("topic", publish.topic))
+		c.server.reject(publish.topic, RejectedForbidden)
+	}
+
+	if publish.qos == 1 {
+		c.send(encodePubAck(publish.packetID))
+	}
+
+	return nil
+}
+
+// publish publishes the message to the notifier, which keeps it for new subscribers if it is retained.
+// Like forbidden messages, messages over the publish limit of the client and messages that do not match
+// the schema of their topic are dropped, because MQTT 3.1.1 cannot refuse them.
+func (c *client) publish(publish *publishPacket) {
+	key := auth.PrincipalKey(c.principal, c.conn.RemoteAddr().String())
+	if !service.IsInbox(publish.topic) && !c.server.limits.Allow(context.Background(), key, publish.topic, c.log) {
+		c.log.Info("Rate limit exceeded", zap.String("topic", publish.topic))
+		c.server.reject(publish.topic, RejectedRateLimit)
+
+		return
+	}
+
+	msg := message(publish.payload)
+	if err := c.server.schemas.Validate(publish.topic, msg); err != nil {
+		c.log.Info("Message does not match the schema", zap.String("topic", publish.topic), zap.Error(err))
+		c.server.reject(publish.topic, RejectedSchema)
+
+		return
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(context.Background(), "mqtt publish",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "mqtt"),
+			attribute.String("messaging.destination", publish.topic),
+		))
+	defer span.End()
+
+	c.server.svc.PublishWith(ctx, publish.topic, msg, &service.PublishOptions{Retain: publish.retain})
+	c.log.Debug("Message published", zap.String("topic", publish.topic), zap.Bool("retain", publish.retain))
+}
+
+func (c *client) handlePubAck(p *packet) error {
+	id, err := decodePacketID(p)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	_, ok := c.inflight[id]
+	delete(c.inflight, id)
+	c.mutex.Unlock()
+
+	if ok {
+		<-c.credits
+	}
+
+	return nil
+}
+
+// handleSubscribe subscribes to the filters the principal may subscribe to and rejects the others.
+// The granted QoS is at most 1. The retained messages of the filters are sent after the SUBACK.
+func (c *client) handleSubscribe(p *packet) error {
+	id, filters, err := decodeSubscribe(p)
+	if err != nil {
+		return err
+	}
+
+	codes := make([]byte, len(filters))
+	subs := make([]*subscription, 0, len(filters))
+	for i, filter := range filters {
+		if filter.qos > 2 {
+			return ErrMalformedPacket
+		}
+
+		if !validFilter(filter.filter) || !c.principal.CanSubscribe(filter.filter) {
+			c.log.Info("Subscription is forbidden", zap.String("filter", filter.filter))
+			codes[i] = subAckFailure
+
+			continue
+		}
+
+		if filter.qos > 1 {
+			filter.qos = 1
+		}
+
+		codes[i] = filter.qos
+		subs = append(subs, c.subscribe(filter))
+	}
+
+	c.send(encodeSubAck(id, codes))
+	for _, sub := range subs {
+		c.wg.Add(1)
+		go c.forward(sub)
+	}
+
+	return nil
+}
+
+// subscribe replaces the subscription to the filter, if any. Its messages wait in its channel until forwarded.
+func (c *client) subscribe(filter topicFilter) *subscription {
+	sub := &subscription{
+		filter: filter.filter,
+		qos:    filter.qos,
+		channel: c.server.svc.SubscribeMatch(filter.filter, func(topic string) bool {
+			return matchFilter(filter.filter, topic)
+		}),
+		done: make(chan struct{}),
+	}
+
+	c.mutex.Lock()
+	if previous, ok := c.subs[filter.filter]; ok {
+		c.unsubscribe(previous)
+	}
+
+	c.subs[filter.filter] = sub
+	c.mutex.Unlock()
+
+	return sub
+}
+
+func (c *client) handleUnsubscribe(p *packet) error {
+	id, filters, err := decodeUnsubscribe(p)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	for _, filter := range filters {
+		if sub, ok := c.subs[filter]; ok {
+			c.unsubscribe(sub)
+			delete(c.subs, filter)
+		}
+	}
+	c.mutex.Unlock()
+
+	c.send(encodeUnsubAck(id))
+
+	return nil
+}
+
+// unsubscribe stops the subscription. The mutex must be held.
+func (c *client) unsubscribe(sub *subscription) {
+	c.server.svc.UnsubscribePattern(sub.channel)
+	close(sub.done)
+}
+
+func (c *client) unsubscribeAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for filter, sub := range c.subs {
+		c.unsubscribe(sub)
+		delete(c.subs, filter)
+	}
+}
+
+// forward sends the messages of the subscription, starting with the retained ones, until it is stopped.
+// Messages of topics the principal may not subscribe to are skipped, because filters with wildcards
+// are authorized as a whole, and so are messages that expired while the client had no credits.
+func (c *client) forward(sub *subscription) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-sub.done:
+			return
+		case envelope := <-sub.channel:
+			if !c.principal.CanSubscribe(envelope.Topic) || c.server.svc.Expired(envelope) {
+				continue
+			}
+
+			if !c.deliverEnvelope(sub, envelope) {
+				return
+			}
+		}
+	}
+}
+
+// deliverEnvelope sends a message of the notifier within a span that continues the publisher's trace.
+func (c *client) deliverEnvelope(sub *subscription, envelope service.Envelope) bool {
+	ctx := tracing.Extract(context.Background(), envelope.TraceContext)
+	_, span := otel.Tracer(tracerName).Start(ctx, "mqtt deliver",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "mqtt"),
+			attribute.String("messaging.destination", envelope.Topic),
+		))
+
+	body, err := payload(envelope.Message)
+	if err != nil {
+		c.log.Error("Message encoding failed", zap.String("topic", envelope.Topic), zap.Error(err))
+		tracing.End(span, err)
+
+		return true
+	}
+
+	delivered := c.deliver(&publishPacket{topic: envelope.Topic, qos: sub.qos, retain: envelope.Retained, payload: body}, sub.done)
+	tracing.End(span, nil)
+
+	return delivered
+}
+
+// deliver sends the message. QoS 1 messages wait for a credit and get a packet ID.
+// It reports false if the subscription or the connection stopped first.
+func (c *client) deliver(publish *publishPacket, stop chan struct{}) bool {
+	if publish.qos > 0 {
+		select {
+		case c.credits <- struct{}{}:
+		case <-stop:
+			return false
+		case <-c.done:
+			return false
+		}
+
+		c.mutex.Lock()
+		publish.packetID = c.newPacketID()
+		c.inflight[publish.packetID] = struct{}{}
+		c.mutex.Unlock()
+	}
+
+	return c.send(encodePublish(publish))
+}
+
+// newPacketID returns the next packet ID that is not in flight. The mutex must be held.
+// The credits limit the messages in flight below the number of packet IDs.
+func (c *client) newPacketID() uint16 {
+	for {
+		c.nextID++
+		if _, ok := c.inflight[c.nextID]; c.nextID != 0 && !ok {
+			return c.nextID
+		}
+	}
+}
+
+// send queues the packet for the writer. It reports false if the connection is closing.
+func (c *client) send(encoded []byte) bool {
+	select {
+	case c.out <- encoded:
+		return true
+	case <-c.done:
+		return false
+	case <-c.written:
+		return false
+	}
+}
+
+// write writes the queued packets until the reader stops. A failed write closes the connection,
+// which stops the reader.
+func (c *client) write() {
+	defer close(c.written)
+
+	w := bufio.NewWriter(c.conn)
+	for {
+		select {
+		case <-c.done:
+			return
+		case encoded := <-c.out:
+			if c.server.cfg.WriteTimeout > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(c.server.cfg.WriteTimeout))
+			}
+
+			_, err := w.Write(encoded)
+			if err == nil && len(c.out) == 0 {
+				err = w.Flush()
+			}
+
+			if err != nil {
+				c.log.Info("MQTT packet writing failed", zap.Error(err))
+				c.conn.Close()
+
+				return
+			}
+		}
+	}
+}
+
+// writeDirect writes the packet before the writer starts.
+func (c *client) writeDirect(encoded []byte) error {
+	if c.server.cfg.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.server.cfg.WriteTimeout))
+	}
+
+	_, err := c.conn.Write(encoded)
+
+	return err
+}
+
+// newClientID returns a random ID for a client that connected without one.
+func newClientID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	return "auto-" + hex.EncodeToString(id)
+}
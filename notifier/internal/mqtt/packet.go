@@ -0,0 +1,340 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Defines the MQTT 3.1.1 control packet types, which are the high four bits of the first byte of a packet.
+const (
+	typeConnect     = 1
+	typeConnAck     = 2
+	typePublish     = 3
+	typePubAck      = 4
+	typeSubscribe   = 8
+	typeSubAck      = 9
+	typeUnsubscribe = 10
+	typeUnsubAck    = 11
+	typePingReq     = 12
+	typePingResp    = 13
+	typeDisconnect  = 14
+)
+
+// Defines the return codes of CONNACK packets.
+const (
+	connAccepted           = 0
+	connBadProtocolVersion = 1
+	connIdentifierRejected = 2
+	connServerUnavailable  = 3
+	connBadCredentials     = 4
+	connNotAuthorized      = 5
+)
+
+// subAckFailure is the SUBACK return code of a rejected subscription.
+const subAckFailure = 0x80
+
+// protocolLevel is the protocol level of MQTT 3.1.1.
+const protocolLevel = 4
+
+var (
+	// ErrMalformedPacket is returned when a packet does not follow MQTT 3.1.1.
+	ErrMalformedPacket = errors.New("malformed packet")
+	// ErrPacketTooLarge is returned when a packet exceeds the maximum packet size.
+	ErrPacketTooLarge = errors.New("packet is too large")
+)
+
+// packet is a control packet whose variable header and payload are not decoded yet.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// connectPacket is a decoded CONNECT packet. Will is nil if the client has no last will.
+type connectPacket struct {
+	protocol     string
+	level        byte
+	cleanSession bool
+	keepAlive    uint16
+	clientID     string
+	will         *publishPacket
+	username     string
+	password     string
+}
+
+// publishPacket is a decoded PUBLISH packet. The packet ID is zero for QoS 0.
+type publishPacket struct {
+	topic    string
+	qos      byte
+	retain   bool
+	dup      bool
+	packetID uint16
+	payload  []byte
+}
+
+// topicFilter is a topic filter of a SUBSCRIBE packet with its requested QoS.
+type topicFilter struct {
+	filter string
+	qos    byte
+}
+
+// readPacket reads the next packet. Packets larger than maxSize, if it is positive, are rejected
+// with ErrPacketTooLarge before their body is read.
+func readPacket(r *bufio.Reader, maxSize int) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+
+		if i == 3 {
+			return nil, ErrMalformedPacket
+		}
+
+		multiplier *= 128
+	}
+
+	if maxSize > 0 && length > maxSize {
+		return nil, ErrPacketTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return &packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// encodePacket returns the packet with the fixed header of the type and flags.
+func encodePacket(kind, flags byte, body []byte) []byte {
+	encoded := make([]byte, 0, len(body)+5)
+	encoded = append(encoded, kind<<4|flags)
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+
+		encoded = append(encoded, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	return append(encoded, body...)
+}
+
+// decoder reads the fields of a packet body. The first error is kept and stops further reads.
+type decoder struct {
+	body []byte
+	err  error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.body) < 1 {
+		d.err = ErrMalformedPacket
+
+		return 0
+	}
+
+	b := d.body[0]
+	d.body = d.body[1:]
+
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.body) < 2 {
+		d.err = ErrMalformedPacket
+
+		return 0
+	}
+
+	n := binary.BigEndian.Uint16(d.body)
+	d.body = d.body[2:]
+
+	return n
+}
+
+func (d *decoder) bytes() []byte {
+	n := int(d.uint16())
+	if d.err != nil || len(d.body) < n {
+		d.err = ErrMalformedPacket
+
+		return nil
+	}
+
+	b := d.body[:n]
+	d.body = d.body[n:]
+
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+// decodeConnect decodes the body of a CONNECT packet. The protocol name and level are returned
+// as they are, so the server can answer an unsupported protocol version.
+func decodeConnect(p *packet) (*connectPacket, error) {
+	d := &decoder{body: p.body}
+	connect := &connectPacket{protocol: d.string(), level: d.byte()}
+	flags := d.byte()
+	connect.keepAlive = d.uint16()
+	connect.clientID = d.string()
+	connect.cleanSession = flags&0x02 != 0
+	if flags&0x04 != 0 {
+		connect.will = &publishPacket{
+			topic:   d.string(),
+			payload: d.bytes(),
+			qos:     flags >> 3 & 0x03,
+			retain:  flags&0x20 != 0,
+		}
+	}
+
+	if flags&0x80 != 0 {
+		connect.username = d.string()
+	}
+
+	if flags&0x40 != 0 {
+		connect.password = d.string()
+	}
+
+	// The reserved flag must be zero, and a will QoS or retain flag requires a will.
+	if d.err != nil || flags&0x01 != 0 || (flags&0x04 == 0 && flags&0x38 != 0) {
+		return nil, ErrMalformedPacket
+	}
+
+	return connect, nil
+}
+
+// decodePublish decodes a PUBLISH packet.
+func decodePublish(p *packet) (*publishPacket, error) {
+	d := &decoder{body: p.body}
+	publish := &publishPacket{
+		topic:  d.string(),
+		qos:    p.flags >> 1 & 0x03,
+		retain: p.flags&0x01 != 0,
+		dup:    p.flags&0x08 != 0,
+	}
+
+	if publish.qos > 0 {
+		publish.packetID = d.uint16()
+	}
+
+	if d.err != nil || publish.qos > 2 {
+		return nil, ErrMalformedPacket
+	}
+
+	publish.payload = d.body
+
+	return publish, nil
+}
+
+// encodePublish returns a PUBLISH packet.
+func encodePublish(publish *publishPacket) []byte {
+	body := make([]byte, 0, 2+len(publish.topic)+2+len(publish.payload))
+	body = appendString(body, publish.topic)
+	if publish.qos > 0 {
+		body = appendUint16(body, publish.packetID)
+	}
+
+	flags := publish.qos << 1
+	if publish.retain {
+		flags |= 0x01
+	}
+
+	if publish.dup {
+		flags |= 0x08
+	}
+
+	return encodePacket(typePublish, flags, append(body, publish.payload...))
+}
+
+// decodePacketID decodes the body of PUBACK packets, which is the packet ID only.
+func decodePacketID(p *packet) (uint16, error) {
+	d := &decoder{body: p.body}
+	id := d.uint16()
+	if d.err != nil || len(d.body) > 0 {
+		return 0, ErrMalformedPacket
+	}
+
+	return id, nil
+}
+
+// decodeSubscribe decodes a SUBSCRIBE packet, which has at least one topic filter.
+func decodeSubscribe(p *packet) (uint16, []topicFilter, error) {
+	d := &decoder{body: p.body}
+	id := d.uint16()
+	var filters []topicFilter
+	for d.err == nil && len(d.body) > 0 {
+		filters = append(filters, topicFilter{filter: d.string(), qos: d.byte()})
+	}
+
+	if d.err != nil || p.flags != 0x02 || len(filters) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	return id, filters, nil
+}
+
+// decodeUnsubscribe decodes an UNSUBSCRIBE packet, which has at least one topic filter.
+func decodeUnsubscribe(p *packet) (uint16, []string, error) {
+	d := &decoder{body: p.body}
+	id := d.uint16()
+	var filters []string
+	for d.err == nil && len(d.body) > 0 {
+		filters = append(filters, d.string())
+	}
+
+	if d.err != nil || p.flags != 0x02 || len(filters) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	return id, filters, nil
+}
+
+func encodeConnAck(code byte) []byte {
+	// The session present flag is always clear: sessions are not kept after a disconnect.
+	return encodePacket(typeConnAck, 0, []byte{0, code})
+}
+
+func encodePubAck(id uint16) []byte {
+	return encodePacket(typePubAck, 0, appendUint16(nil, id))
+}
+
+func encodeSubAck(id uint16, codes []byte) []byte {
+	return encodePacket(typeSubAck, 0, append(appendUint16(nil, id), codes...))
+}
+
+func encodeUnsubAck(id uint16) []byte {
+	return encodePacket(typeUnsubAck, 0, appendUint16(nil, id))
+}
+
+func encodePingResp() []byte {
+	return encodePacket(typePingResp, 0, nil)
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint16(b, uint16(len(s))), s...)
+}
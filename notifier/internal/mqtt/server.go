@@ -0,0 +1,227 @@
+// Package mqtt implements an MQTT 3.1.1 front-end of the notifier. MQTT topics are notifier topics,
+// so messages flow between MQTT clients and the other subscribers and publishers of the notifier.
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// tracerName is the name of the tracer that records MQTT publishes and deliveries.
+const tracerName = "github.com/ivyoverflow/pub-sub/notifier/internal/mqtt"
+
+// ErrServerClosed is returned by Serve after Shutdown is called.
+var ErrServerClosed = errors.New("mqtt server closed")
+
+// Defines the reasons of rejected messages.
+const (
+	RejectedForbidden = "forbidden"
+	RejectedSchema    = "schema"
+	RejectedRateLimit = "rate_limit"
+)
+
+// Observer is notified about the messages the server drops instead of publishing, for example to export them
+// as metrics.
+type Observer interface {
+	// Rejected is called when a message published to the topic is dropped for the reason.
+	Rejected(topic, reason string)
+}
+
+// Config configures the MQTT server.
+type Config struct {
+	// ConnectTimeout is the time a client has to send CONNECT after connecting.
+	ConnectTimeout time.Duration
+	// WriteTimeout is the time a client has to receive a packet. Zero means no timeout.
+	WriteTimeout time.Duration
+	// MaxPacketBytes is the size of the largest packet a client may send. Zero means no limit.
+	MaxPacketBytes int
+	// MaxInflight is the number of QoS 1 messages sent to a client and not acknowledged yet at which
+	// deliveries to the client pause. Zero means the limit of the protocol, 65535.
+	MaxInflight int
+}
+
+// Server accepts MQTT clients. Clients authenticate with an API key or a JWT as the CONNECT password
+// and get the publish and subscribe permissions of their principal.
+type Server struct {
+	svc      *service.Notifier
+	authn    *auth.Authenticator
+	schemas  *schema.Registry
+	limits   *handler.PublishLimits
+	observer Observer
+	cfg      *Config
+	log      *logger.Logger
+	mutex    sync.Mutex
+	// clients are the connected clients by client ID.
+	clients   map[string]*client
+	listeners map[net.Listener]struct{}
+	closing   bool
+	wg        sync.WaitGroup
+}
+
+// New returns a new Server object. Published messages that do not match the latest schema of their topic in schemas
+// or exceed the publish limits of /publish are dropped and reported to the observer. Nil schemas validate
+// no message, nil limits do not limit publishing and a nil observer is not notified.
+func New(svc *service.Notifier, authn *auth.Authenticator, schemas *schema.Registry, limits *handler.PublishLimits,
+	observer Observer, cfg *Config, log *logger.Logger) *Server {
+	return &Server{
+		svc:       svc,
+		authn:     authn,
+		schemas:   schemas,
+		limits:    limits,
+		observer:  observer,
+		cfg:       cfg,
+		log:       log,
+		clients:   make(map[string]*client),
+		listeners: make(map[net.Listener]struct{}),
+	}
+}
+
+// Serve accepts connections on the listener until Shutdown is called, then it returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	if s.closing {
+		s.mutex.Unlock()
+		l.Close()
+
+		return ErrServerClosed
+	}
+
+	s.listeners[l] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, l)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closing := s.closing
+			s.mutex.Unlock()
+			if closing {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections, closes the connections of every client and waits until
+// they are closed or ctx is done. The last wills of the clients are not published, because they did not fail.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+
+	for _, c := range s.clients {
+		c.conn.Close()
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serveConn serves the connection of a client from CONNECT to its close.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	log := s.log.With(zap.String("remote_addr", conn.RemoteAddr().String()))
+	c, err := s.connect(conn, log)
+	if err != nil {
+		log.Info("MQTT connection rejected", zap.Error(err))
+
+		return
+	}
+
+	if code := s.register(c); code != connAccepted {
+		c.writeDirect(encodeConnAck(code))
+		log.Info("MQTT connection rejected", zap.String("client_id", c.id), zap.Uint8("return_code", code))
+
+		return
+	}
+
+	defer s.unregister(c)
+
+	c.run()
+}
+
+// register adds the client and closes the connection of the client with the same ID, if any. Client IDs are
+// shared by all principals, so only the subject of the connected client may take it over. It returns
+// the CONNACK return code of the client.
+func (s *Server) register(c *client) byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closing {
+		return connServerUnavailable
+	}
+
+	if previous, ok := s.clients[c.id]; ok {
+		if previous.principal.Subject != c.principal.Subject {
+			return connIdentifierRejected
+		}
+
+		c.log.Info("MQTT client taken over by a new connection")
+		previous.conn.Close()
+	}
+
+	s.clients[c.id] = c
+
+	return connAccepted
+}
+
+func (s *Server) unregister(c *client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.clients[c.id] == c {
+		delete(s.clients, c.id)
+	}
+}
+
+// reject reports the dropped message to the observer, if any.
+func (s *Server) reject(topic, reason string) {
+	if s.observer != nil {
+		s.observer.Rejected(topic, reason)
+	}
+}
+
+// closed reports whether Shutdown was called.
+func (s *Server) closed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closing
+}
@@ -0,0 +1,410 @@
+package mqtt_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/mqtt"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
+)
+
+const apiKeys = `[{"key":"s3cr3t","subject":"device","publish":["sensors/*"],"subscribe":["sensors/*"]},
+{"key":"0th3r","subject":"other","publish":["sensors/*"],"subscribe":["sensors/*"]}]`
+
+// will is the last will of a test client.
+type will struct {
+	topic   string
+	message string
+	retain  bool
+}
+
+// testClient is a raw MQTT client that writes and reads packets as bytes.
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// serve starts an MQTT server with authentication by API keys and returns its address.
+func serve(t *testing.T, schemas *schema.Registry, limits *handler.PublishLimits) (string, *service.Notifier, *mqtt.Server) {
+	t.Helper()
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, ioutil.WriteFile(keysFile, []byte(apiKeys), 0o600))
+	authn, err := auth.New(&auth.Config{APIKeysFile: keysFile})
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	srv := mqtt.New(svc, authn, schemas, limits, nil, &mqtt.Config{ConnectTimeout: time.Second, WriteTimeout: time.Second}, log)
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	return listener.Addr().String(), svc, srv
+}
+
+// dial connects to the server and sends CONNECT. It returns the CONNACK return code.
+func dial(t *testing.T, addr, clientID, password string, level byte, cleanSession bool, w *will) (*testClient, byte) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	flags := byte(0)
+	if cleanSession {
+		flags |= 0x02
+	}
+
+	payload := str(clientID)
+	if w != nil {
+		flags |= 0x04
+		if w.retain {
+			flags |= 0x20
+		}
+
+		payload = append(append(payload, str(w.topic)...), str(w.message)...)
+	}
+
+	if password != "" {
+		flags |= 0xc0
+		payload = append(append(payload, str("device")...), str(password)...)
+	}
+
+	c := &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	header := append(append(str("MQTT"), level, flags), 0, 60)
+	c.write(0x10, append(header, payload...))
+
+	kind, body := c.read()
+	require.Equal(t, byte(0x20), kind)
+	require.Len(t, body, 2)
+
+	return c, body[1]
+}
+
+func (c *testClient) write(header byte, body []byte) {
+	c.t.Helper()
+
+	packet := []byte{header}
+	for length := len(body); ; {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	_, err := c.conn.Write(append(packet, body...))
+	require.NoError(c.t, err)
+}
+
+// read returns the first byte and the body of the next packet.
+func (c *testClient) read() (byte, []byte) {
+	c.t.Helper()
+
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(time.Second)))
+	header, err := c.reader.ReadByte()
+	require.NoError(c.t, err)
+
+	length, multiplier := 0, 1
+	for {
+		digit, err := c.reader.ReadByte()
+		require.NoError(c.t, err)
+
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader, body)
+	require.NoError(c.t, err)
+
+	return header, body
+}
+
+func (c *testClient) subscribe(id byte, filters ...string) []byte {
+	c.t.Helper()
+
+	body := []byte{0, id}
+	for _, filter := range filters {
+		body = append(append(body, str(filter)...), 1)
+	}
+
+	c.write(0x82, body)
+	kind, ack := c.read()
+	require.Equal(c.t, byte(0x90), kind)
+	require.Equal(c.t, []byte{0, id}, ack[:2])
+
+	return ack[2:]
+}
+
+// receive reads the next PUBLISH packet and acknowledges it if its QoS is 1. It returns the topic and the payload.
+func (c *testClient) receive() (byte, string, string) {
+	c.t.Helper()
+
+	header, body := c.read()
+	require.Equal(c.t, byte(0x30), header&0xf0, "expected a PUBLISH packet")
+
+	length := int(body[0])<<8 | int(body[1])
+	topic, body := string(body[2:2+length]), body[2+length:]
+	if header&0x06 != 0 {
+		c.write(0x40, body[:2])
+		body = body[2:]
+	}
+
+	return header, topic, string(body)
+}
+
+// ping checks that the next packet is the answer to a ping, so no message was sent before it.
+func (c *testClient) ping() {
+	c.t.Helper()
+
+	c.write(0xc0, nil)
+	kind, _ := c.read()
+	assert.Equal(c.t, byte(0xd0), kind, "expected PINGRESP")
+}
+
+func str(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func TestServer_connect(t *testing.T) {
+	testCases := []struct {
+		name         string
+		clientID     string
+		password     string
+		level        byte
+		cleanSession bool
+		will         *will
+		expected     byte
+	}{
+		{name: "OK", clientID: "sensor", password: "s3cr3t", level: 4, cleanSession: true, expected: 0},
+		{name: "Generated client ID", password: "s3cr3t", level: 4, cleanSession: true, expected: 0},
+		{name: "MQTT 3.1", clientID: "sensor", password: "s3cr3t", level: 3, cleanSession: true, expected: 1},
+		{name: "Persistent session without client ID", password: "s3cr3t", level: 4, expected: 2},
+		{name: "Missing credentials", clientID: "sensor", level: 4, cleanSession: true, expected: 4},
+		{name: "Invalid credentials", clientID: "sensor", password: "guess", level: 4, cleanSession: true, expected: 4},
+		{
+			name:         "Forbidden will",
+			clientID:     "sensor",
+			password:     "s3cr3t",
+			level:        4,
+			cleanSession: true,
+			will:         &will{topic: "secrets", message: "offline"},
+			expected:     5,
+		},
+	}
+
+	addr, _, _ := serve(t, nil, nil)
+	for _, testCase := range testCases {
+		_, code := dial(t, addr, testCase.clientID, testCase.password, testCase.level, testCase.cleanSession, testCase.will)
+		assert.Equal(t, testCase.expected, code, testCase.name)
+	}
+}
+
+func TestServer_publishSubscribe(t *testing.T) {
+	addr, svc, _ := serve(t, nil, nil)
+	c, code := dial(t, addr, "sensor", "s3cr3t", 4, true, nil)
+	require.Equal(t, byte(0), code)
+
+	// Wildcards outside the permissions of the principal are rejected.
+	assert.Equal(t, []byte{1, 0x80}, c.subscribe(1, "sensors/+/temperature", "#"))
+
+	svc.Publish(context.Background(), "sensors/kitchen/humidity", 40)
+	svc.Publish(context.Background(), "sensors/kitchen/temperature", map[string]interface{}{"celsius": 21})
+	header, topic, payload := c.receive()
+	assert.Equal(t, byte(0x32), header)
+	assert.Equal(t, "sensors/kitchen/temperature", topic)
+	assert.JSONEq(t, `{"celsius":21}`, payload)
+
+	channel := svc.Subscribe("sensors/kitchen/light")
+	defer svc.Unsubscribe("sensors/kitchen/light", channel)
+
+	testCases := []struct {
+		payload  string
+		expected interface{}
+	}{
+		{payload: `{"on":true}`, expected: json.RawMessage(`{"on":true}`)},
+		{payload: `on`, expected: "on"},
+	}
+
+	for i, testCase := range testCases {
+		body := append(append(str("sensors/kitchen/light"), 0, byte(i+1)), testCase.payload...)
+		c.write(0x32, body)
+		kind, ack := c.read()
+		assert.Equal(t, byte(0x40), kind)
+		assert.Equal(t, []byte{0, byte(i + 1)}, ack)
+		assert.Equal(t, testCase.expected, (<-channel).Message, testCase.payload)
+	}
+
+	// Messages from MQTT clients are received by MQTT subscribers unchanged.
+	c.write(0x30, append(str("sensors/garden/temperature"), "18.5"...))
+	_, topic, payload = c.receive()
+	assert.Equal(t, "sensors/garden/temperature", topic)
+	assert.Equal(t, "18.5", payload)
+
+	c.write(0xa2, append([]byte{0, 2}, str("sensors/+/temperature")...))
+	kind, ack := c.read()
+	assert.Equal(t, byte(0xb0), kind)
+	assert.Equal(t, []byte{0, 2}, ack)
+	assert.Equal(t, 1, svc.Stats().Subscribers, "only the subscriber of the light is left")
+}
+
+func TestServer_schema(t *testing.T) {
+	schemas := schema.NewRegistry()
+	_, _, err := schemas.Register("sensors/kitchen/temperature",
+		json.RawMessage(`{"type":"object","required":["celsius"],"properties":{"celsius":{"type":"number"}}}`), "")
+	require.NoError(t, err)
+
+	addr, svc, _ := serve(t, schemas, nil)
+	c, _ := dial(t, addr, "sensor", "s3cr3t", 4, true, nil)
+	channel := svc.Subscribe("sensors/kitchen/temperature")
+	defer svc.Unsubscribe("sensors/kitchen/temperature", channel)
+
+	// Messages that do not match the schema are acknowledged but dropped.
+	for i, payload := range []string{`{"celsius":"warm"}`, `warm`, `{"celsius":21}`} {
+		c.write(0x32, append(append(str("sensors/kitchen/temperature"), 0, byte(i+1)), payload...))
+		kind, _ := c.read()
+		assert.Equal(t, byte(0x40), kind, payload)
+	}
+
+	assert.Equal(t, json.RawMessage(`{"celsius":21}`), (<-channel).Message)
+	select {
+	case envelope := <-channel:
+		t.Errorf("Unexpected message %v", envelope.Message)
+	default:
+	}
+}
+
+func TestServer_limits(t *testing.T) {
+	limits, err := handler.NewPublishLimits(ratelimit.NewMemoryStore(), "", []string{"sensors/*:2/m"})
+	require.NoError(t, err)
+
+	addr, svc, _ := serve(t, nil, limits)
+	c, _ := dial(t, addr, "sensor", "s3cr3t", 4, true, nil)
+	channel := svc.Subscribe("sensors/door")
+	defer svc.Unsubscribe("sensors/door", channel)
+
+	// Messages over the publish limit of the topic are acknowledged but dropped.
+	for i, payload := range []string{"open", "closed", "open"} {
+		c.write(0x32, append(append(str("sensors/door"), 0, byte(i+1)), payload...))
+		kind, _ := c.read()
+		assert.Equal(t, byte(0x40), kind, payload)
+	}
+
+	assert.Equal(t, "open", (<-channel).Message)
+	assert.Equal(t, "closed", (<-channel).Message)
+	select {
+	case envelope := <-channel:
+		t.Errorf("Unexpected message %v", envelope.Message)
+	default:
+	}
+}
+
+func TestServer_retained(t *testing.T) {
+	addr, _, _ := serve(t, nil, nil)
+	publisher, _ := dial(t, addr, "publisher", "s3cr3t", 4, true, nil)
+	publisher.write(0x33, append(append(str("sensors/door"), 0, 1), "open"...))
+	publisher.read()
+
+	subscriber, _ := dial(t, addr, "subscriber", "s3cr3t", 4, true, nil)
+	assert.Equal(t, []byte{1}, subscriber.subscribe(1, "sensors/#"))
+	header, topic, payload := subscriber.receive()
+	assert.Equal(t, byte(0x33), header, "retained messages are sent with the retain flag")
+	assert.Equal(t, "sensors/door", topic)
+	assert.Equal(t, "open", payload)
+
+	// An empty retained message removes the retained message.
+	publisher.write(0x31, str("sensors/door"))
+	_, _, payload = subscriber.receive()
+	assert.Empty(t, payload)
+
+	late, _ := dial(t, addr, "late", "s3cr3t", 4, true, nil)
+	late.subscribe(1, "sensors/#")
+	late.ping()
+}
+
+func TestServer_will(t *testing.T) {
+	addr, svc, _ := serve(t, nil, nil)
+	channel := svc.Subscribe("sensors/status")
+	defer svc.Unsubscribe("sensors/status", channel)
+
+	graceful, _ := dial(t, addr, "graceful", "s3cr3t", 4, true, &will{topic: "sensors/status", message: "graceful"})
+	graceful.write(0xe0, nil)
+
+	lost, _ := dial(t, addr, "lost", "s3cr3t", 4, true, &will{topic: "sensors/status", message: "lost"})
+	lost.conn.Close()
+
+	assert.Equal(t, "lost", (<-channel).Message)
+
+	// A new connection with the same client ID takes the session over, and the old connection is lost.
+	first, _ := dial(t, addr, "sensor", "s3cr3t", 4, true, &will{topic: "sensors/status", message: "taken over"})
+	dial(t, addr, "sensor", "s3cr3t", 4, true, nil)
+	assert.Equal(t, "taken over", (<-channel).Message)
+
+	_, err := first.reader.ReadByte()
+	assert.Error(t, err)
+}
+
+func TestServer_takeover(t *testing.T) {
+	addr, _, _ := serve(t, nil, nil)
+	first, code := dial(t, addr, "sensor", "s3cr3t", 4, true, nil)
+	require.Equal(t, byte(0), code)
+
+	// Another principal cannot take the client ID over, and the connected client keeps its connection.
+	_, code = dial(t, addr, "sensor", "0th3r", 4, true, nil)
+	assert.Equal(t, byte(2), code)
+
+	first.write(0x32, append(append(str("sensors/door"), 0, 1), "open"...))
+	kind, _ := first.read()
+	assert.Equal(t, byte(0x40), kind)
+}
+
+func TestServer_shutdown(t *testing.T) {
+	addr, svc, srv := serve(t, nil, nil)
+	channel := svc.Subscribe("sensors/status")
+	defer svc.Unsubscribe("sensors/status", channel)
+
+	c, _ := dial(t, addr, "sensor", "s3cr3t", 4, true, &will{topic: "sensors/status", message: "offline"})
+	require.NoError(t, srv.Shutdown(context.Background()))
+
+	_, err := c.reader.ReadByte()
+	assert.Error(t, err)
+
+	select {
+	case envelope := <-channel:
+		t.Errorf("Last wills must not be published on shutdown, got %v", envelope.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// validTopic reports whether the topic name may be published to: it is not empty and has no wildcards.
+func validTopic(topic string) bool {
+	return topic != "" && utf8.ValidString(topic) && !strings.ContainsAny(topic, "+#\x00")
+}
+
+// validFilter reports whether the topic filter may be subscribed to. "+" must be a whole level,
+// and "#" must be the whole last level.
+func validFilter(filter string) bool {
+	if filter == "" || !utf8.ValidString(filter) || strings.Contains(filter, "\x00") {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.ContainsAny(level, "+#") && level != "+" && level != "#" {
+			return false
+		}
+
+		if level == "#" && i != len(levels)-1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchFilter reports whether the topic matches the filter. "+" matches one level and "#" any number
+// of levels, including the parent level. Wildcards at the first level do not match topics starting with "$".
+func matchFilter(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filters, topics := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filters {
+		if level == "#" {
+			return true
+		}
+
+		if i == len(topics) || (level != "+" && level != topics[i]) {
+			return false
+		}
+	}
+
+	return len(filters) == len(topics)
+}
+
+// message returns the notifier message of a payload: JSON payloads are published as JSON values,
+// so websocket subscribers receive them as they are, and other payloads as strings.
+func message(payload []byte) interface{} {
+	if len(payload) > 0 && json.Valid(payload) {
+		return json.RawMessage(payload)
+	}
+
+	return string(payload)
+}
+
+// payload returns the MQTT payload of a notifier message. Strings are sent as they are, so messages
+// published by MQTT clients are received unchanged; other values are encoded as JSON.
+func payload(message interface{}) ([]byte, error) {
+	switch message := message.(type) {
+	case string:
+		return []byte(message), nil
+	case json.RawMessage:
+		return message, nil
+	default:
+		return json.Marshal(message)
+	}
+}
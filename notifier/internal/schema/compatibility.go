@@ -0,0 +1,198 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// compatible checks that the next schema keeps the compatibility with the latest one.
+func compatible(latest, next *openapi.Schema, compatibility Compatibility) error {
+	switch compatibility {
+	case CompatibilityBackward:
+		return covers(next, latest, "message")
+	case CompatibilityForward:
+		return covers(latest, next, "message")
+	case CompatibilityFull:
+		if err := covers(next, latest, "message"); err != nil {
+			return err
+		}
+
+		return covers(latest, next, "message")
+	default:
+		return nil
+	}
+}
+
+// covers returns why a value that narrow accepts may be rejected by wide, or nil if wide accepts every such value.
+// It compares the keywords and is conservative: it may report schemas that accept the same values, like different
+// patterns that match the same strings. Objects that allow additional properties may have any value in the properties
+// they do not describe, so wide may add a property to such an object only if the property accepts any value.
+func covers(wide, narrow *openapi.Schema, path string) error {
+	if len(narrow.AnyOf) > 0 {
+		for _, branch := range narrow.AnyOf {
+			if err := covers(wide, branch, path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(narrow.Enum) > 0 {
+		for _, value := range narrow.Enum {
+			if err := wide.Validate(value, path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(wide.AnyOf) > 0 {
+		for _, branch := range wide.AnyOf {
+			if covers(branch, narrow, path) == nil {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%s must match one of the allowed schemas", path)
+	}
+
+	if len(wide.Enum) > 0 {
+		return fmt.Errorf("%s must be one of %v", path, wide.Enum)
+	}
+
+	if wide.Type != "" && wide.Type != narrow.Type && !(wide.Type == "number" && narrow.Type == "integer") {
+		return fmt.Errorf("%s must be of type %s", path, wide.Type)
+	}
+
+	if may(narrow, "string") {
+		if err := coversString(wide, narrow, path); err != nil {
+			return err
+		}
+	}
+
+	if may(narrow, "number") || may(narrow, "integer") {
+		if err := coversNumber(wide, narrow, path); err != nil {
+			return err
+		}
+	}
+
+	if may(narrow, "object") {
+		if err := coversObject(wide, narrow, path); err != nil {
+			return err
+		}
+	}
+
+	if may(narrow, "array") && wide.Items != nil {
+		items := narrow.Items
+		if items == nil {
+			items = &openapi.Schema{}
+		}
+
+		return covers(wide.Items, items, path+"[]")
+	}
+
+	return nil
+}
+
+// may reports whether values of the schema may be of the type.
+func may(schema *openapi.Schema, typ string) bool {
+	return schema.Type == "" || schema.Type == typ
+}
+
+func coversString(wide, narrow *openapi.Schema, path string) error {
+	if wide.MinLength != nil && (narrow.MinLength == nil || *narrow.MinLength < *wide.MinLength) {
+		return fmt.Errorf("%s must be at least %d characters long", path, *wide.MinLength)
+	}
+
+	if wide.MaxLength != nil && (narrow.MaxLength == nil || *narrow.MaxLength > *wide.MaxLength) {
+		return fmt.Errorf("%s must be at most %d characters long", path, *wide.MaxLength)
+	}
+
+	if wide.Pattern != "" && wide.Pattern != narrow.Pattern {
+		return fmt.Errorf("%s must match another pattern", path)
+	}
+
+	if wide.Format != "" && wide.Format != narrow.Format {
+		return fmt.Errorf("%s must have the %s format", path, wide.Format)
+	}
+
+	return nil
+}
+
+func coversNumber(wide, narrow *openapi.Schema, path string) error {
+	if wide.Minimum != nil && (narrow.Minimum == nil || *narrow.Minimum < *wide.Minimum) {
+		return fmt.Errorf("%s must be at least %v", path, *wide.Minimum)
+	}
+
+	if wide.Maximum != nil && (narrow.Maximum == nil || *narrow.Maximum > *wide.Maximum) {
+		return fmt.Errorf("%s must be at most %v", path, *wide.Maximum)
+	}
+
+	return nil
+}
+
+func coversObject(wide, narrow *openapi.Schema, path string) error {
+	for _, name := range wide.Required {
+		if !required(narrow, name) {
+			return fmt.Errorf("%s.%s is required", path, name)
+		}
+	}
+
+	closed := narrow.AdditionalProperties != nil && !*narrow.AdditionalProperties
+	for _, name := range sortedNames(wide.Properties) {
+		property, ok := narrow.Properties[name]
+		if !ok {
+			if closed {
+				continue
+			}
+
+			property = &openapi.Schema{}
+		}
+
+		if err := covers(wide.Properties[name], property, path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	if wide.AdditionalProperties == nil || *wide.AdditionalProperties {
+		return nil
+	}
+
+	for _, name := range sortedNames(narrow.Properties) {
+		if _, ok := wide.Properties[name]; !ok {
+			return fmt.Errorf("%s.%s is not allowed", path, name)
+		}
+	}
+
+	if !closed {
+		return fmt.Errorf("%s must have no other properties", path)
+	}
+
+	return nil
+}
+
+func required(schema *openapi.Schema, name string) bool {
+	for _, n := range schema.Required {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedNames returns the property names in order, so that the same schemas always report the same error.
+func sortedNames(properties map[string]*openapi.Schema) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
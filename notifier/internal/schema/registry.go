@@ -0,0 +1,316 @@
+// Package schema keeps the JSON Schemas of topics, which the messages published to the topics must match.
+// Every topic has a list of versions, and a new version is added only if it keeps the compatibility of the topic
+// with the latest one. Schemas support the keywords of the platform openapi package: type, format, enum, pattern,
+// minLength, maxLength, minimum, maximum, properties, required, additionalProperties, items and anyOf.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// Compatibility is the relation that every new schema of a topic keeps with the latest one.
+type Compatibility string
+
+// Defines the compatibilities of topics.
+const (
+	// CompatibilityNone allows any new schema.
+	CompatibilityNone Compatibility = "none"
+	// CompatibilityBackward requires the new schema to accept every message the latest one accepts,
+	// so messages published before the change stay valid.
+	CompatibilityBackward Compatibility = "backward"
+	// CompatibilityForward requires the latest schema to accept every message the new one accepts,
+	// so subscribers that know only the latest schema can read the new messages.
+	CompatibilityForward Compatibility = "forward"
+	// CompatibilityFull is both backward and forward.
+	CompatibilityFull Compatibility = "full"
+)
+
+// DefaultCompatibility is the compatibility of topics whose first schema sets none.
+const DefaultCompatibility = CompatibilityBackward
+
+var (
+	// ErrNotFound is returned when the topic or the version has no schema.
+	ErrNotFound = errors.New("schema is not found")
+	// ErrTopicRequired is returned when a schema is registered without a topic.
+	ErrTopicRequired = errors.New("topic is required")
+	// ErrInboxTopic is returned when a schema is registered for an inbox, whose replies are never validated.
+	ErrInboxTopic = errors.New("inbox topics have no schemas")
+	// ErrInvalidCompatibility is returned when the compatibility is not one of the defined ones.
+	ErrInvalidCompatibility = errors.New("compatibility must be none, backward, forward or full")
+	// ErrInvalidSchema is wrapped by the errors of schemas that cannot be used.
+	ErrInvalidSchema = errors.New("invalid schema")
+	// ErrIncompatible is wrapped by the errors of schemas that break the compatibility of the topic.
+	ErrIncompatible = errors.New("incompatible schema")
+	// ErrInvalidMessage is wrapped by the errors of messages that do not match the schema of their topic.
+	ErrInvalidMessage = errors.New("message does not match the schema")
+)
+
+// version is a registered schema and its decoded form.
+type version struct {
+	model.SchemaVersion
+	schema *openapi.Schema
+}
+
+// topic holds the schema versions of a topic from the oldest to the latest.
+type topic struct {
+	compatibility Compatibility
+	versions      []*version
+}
+
+func (t *topic) latest() *version {
+	return t.versions[len(t.versions)-1]
+}
+
+// Registry keeps the schemas of topics in memory. It is safe for concurrent use.
+type Registry struct {
+	mutex  sync.RWMutex
+	topics map[string]*topic
+}
+
+// NewRegistry returns a new empty Registry object. The schemas are kept in the memory of the process only:
+// they are lost on restart and every replica has its own, so they must be registered again with every replica.
+func NewRegistry() *Registry {
+	return &Registry{topics: make(map[string]*topic)}
+}
+
+// Register adds the schema as the latest version of the topic if it keeps the compatibility of the topic with the
+// previous latest version. A non-empty compatibility replaces the one of the topic, and is checked instead of it.
+// A schema equal to the latest version is not added again, and created is false then.
+func (r *Registry) Register(name string, document json.RawMessage, compatibility Compatibility) (
+	registered model.SchemaVersion, created bool, err error) {
+	if name == "" {
+		return model.SchemaVersion{}, false, ErrTopicRequired
+	}
+
+	if service.IsInbox(name) {
+		return model.SchemaVersion{}, false, ErrInboxTopic
+	}
+
+	switch compatibility {
+	case "", CompatibilityNone, CompatibilityBackward, CompatibilityForward, CompatibilityFull:
+	default:
+		return model.SchemaVersion{}, false, ErrInvalidCompatibility
+	}
+
+	schema, canonical, err := parse(document)
+	if err != nil {
+		return model.SchemaVersion{}, false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	t, ok := r.topics[name]
+	if !ok {
+		t = &topic{compatibility: DefaultCompatibility}
+	}
+
+	if compatibility == "" {
+		compatibility = t.compatibility
+	}
+
+	if ok {
+		latest := t.latest()
+		if bytes.Equal(latest.Schema, canonical) {
+			t.compatibility = compatibility
+
+			return latest.SchemaVersion, false, nil
+		}
+
+		if err = compatible(latest.schema, schema, compatibility); err != nil {
+			return model.SchemaVersion{}, false,
+				fmt.Errorf("%w: not %s compatible with version %d: %v", ErrIncompatible, compatibility, latest.Version, err)
+		}
+	}
+
+	t.compatibility = compatibility
+
+	v := &version{
+		SchemaVersion: model.SchemaVersion{
+			Topic:     name,
+			Version:   len(t.versions) + 1,
+			Schema:    canonical,
+			CreatedAt: time.Now().UTC(),
+		},
+		schema: schema,
+	}
+	t.versions = append(t.versions, v)
+	r.topics[name] = t
+
+	return v.SchemaVersion, true, nil
+}
+
+// Get returns the schema versions of the topic.
+func (r *Registry) Get(name string) (model.TopicSchemas, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.topics[name]
+	if !ok {
+		return model.TopicSchemas{}, ErrNotFound
+	}
+
+	schemas := model.TopicSchemas{Topic: name, Compatibility: string(t.compatibility)}
+	for _, v := range t.versions {
+		schemas.Versions = append(schemas.Versions, v.SchemaVersion)
+	}
+
+	return schemas, nil
+}
+
+// Version returns a schema version of the topic, or the latest one if n is 0.
+func (r *Registry) Version(name string, n int) (model.SchemaVersion, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.topics[name]
+	if !ok || n < 0 || n > len(t.versions) {
+		return model.SchemaVersion{}, ErrNotFound
+	}
+
+	if n == 0 {
+		return t.latest().SchemaVersion, nil
+	}
+
+	return t.versions[n-1].SchemaVersion, nil
+}
+
+// Delete removes every schema version of the topic, so its messages are not validated anymore.
+func (r *Registry) Delete(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.topics[name]; !ok {
+		return ErrNotFound
+	}
+
+	delete(r.topics, name)
+
+	return nil
+}
+
+// Validate checks the decoded JSON message against the latest schema of the topic. Raw JSON messages are decoded
+// first. Messages of topics without a schema are valid, and so is every message of a nil Registry.
+// Every publish path validates its messages: the /publish and /request routes reject invalid ones with 400,
+// and the MQTT server drops them.
+func (r *Registry) Validate(name string, message interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	t, ok := r.topics[name]
+	var latest *version
+	if ok {
+		latest = t.latest()
+	}
+	r.mutex.RUnlock()
+
+	if latest == nil {
+		return nil
+	}
+
+	if raw, ok := message.(json.RawMessage); ok {
+		var decoded interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			return fmt.Errorf("%w version %d: %v", ErrInvalidMessage, latest.Version, err)
+		}
+
+		message = decoded
+	}
+
+	if err := latest.schema.Validate(message, "message"); err != nil {
+		return fmt.Errorf("%w version %d: %v", ErrInvalidMessage, latest.Version, err)
+	}
+
+	return nil
+}
+
+// parse decodes the schema, rejecting the keywords that are not supported rather than ignoring them,
+// checks it and returns it with its canonical JSON form, whose object keys are sorted.
+func parse(document json.RawMessage) (*openapi.Schema, json.RawMessage, error) {
+	var generic interface{}
+	if err := json.Unmarshal(document, &generic); err != nil {
+		return nil, nil, fmt.Errorf("%w: the schema is not valid JSON", ErrInvalidSchema)
+	}
+
+	if _, ok := generic.(map[string]interface{}); !ok {
+		return nil, nil, fmt.Errorf("%w: the schema must be an object", ErrInvalidSchema)
+	}
+
+	canonical, err := json.Marshal(generic)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(canonical))
+	decoder.DisallowUnknownFields()
+	schema := &openapi.Schema{}
+	if err = decoder.Decode(schema); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	if err = check(schema, "schema"); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	return schema, canonical, nil
+}
+
+// types are the values of the type keyword.
+var types = map[string]bool{"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true}
+
+// check rejects the keywords that validation would fail on, so that every message fails for its content only.
+func check(schema *openapi.Schema, path string) error {
+	if schema == nil {
+		return fmt.Errorf("%s must be an object", path)
+	}
+
+	if schema.Ref != "" {
+		return fmt.Errorf("%s.$ref is not supported", path)
+	}
+
+	if schema.Type != "" && !types[schema.Type] {
+		return fmt.Errorf("%s.type must be string, number, integer, boolean, object or array", path)
+	}
+
+	if schema.Format != "" && schema.Format != "uuid" {
+		return fmt.Errorf("%s.format must be uuid", path)
+	}
+
+	if schema.Pattern != "" {
+		if _, err := regexp.Compile(schema.Pattern); err != nil {
+			return fmt.Errorf("%s.pattern is not a valid regular expression", path)
+		}
+	}
+
+	for _, name := range sortedNames(schema.Properties) {
+		if err := check(schema.Properties[name], path+".properties."+name); err != nil {
+			return err
+		}
+	}
+
+	if schema.Items != nil {
+		if err := check(schema.Items, path+".items"); err != nil {
+			return err
+		}
+	}
+
+	for i, branch := range schema.AnyOf {
+		if err := check(branch, fmt.Sprintf("%s.anyOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+)
+
+const book = `{
+	"type": "object",
+	"required": ["title", "price"],
+	"properties": {
+		"title": {"type": "string", "minLength": 1},
+		"price": {"type": "number", "minimum": 0},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"additionalProperties": false
+}`
+
+func decode(t *testing.T, message string) interface{} {
+	t.Helper()
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(message), &value); err != nil {
+		t.Fatalf("Message decoding throws an error: %v", err)
+	}
+
+	return value
+}
+
+func TestRegistry_Register(t *testing.T) {
+	testCases := []struct {
+		name          string
+		schema        string
+		compatibility schema.Compatibility
+		version       int
+		expected      string
+	}{
+		{name: "Same schema", schema: `{"additionalProperties": false, "type": "object", "required": ["title", "price"],
+			"properties": {"price": {"minimum": 0, "type": "number"}, "title": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "items": {"type": "string"}}}}`, version: 1},
+		{name: "Optional property added", schema: `{"type": "object", "required": ["title", "price"],
+			"properties": {"title": {"type": "string"}, "price": {"type": "number"}, "tags": {"type": "array"},
+			"isbn": {"type": "string"}}, "additionalProperties": false}`, version: 2},
+		{name: "Required property added", schema: `{"type": "object", "required": ["title", "price", "isbn"],
+			"properties": {"title": {"type": "string"}, "price": {"type": "number"}, "isbn": {"type": "string"}}}`,
+			expected: "incompatible schema: not backward compatible with version 1: message.isbn is required"},
+		{name: "Property narrowed", schema: `{"type": "object", "properties": {"price": {"type": "integer"}}}`,
+			expected: "incompatible schema: not backward compatible with version 1: message.price must be of type integer"},
+		{name: "Range narrowed", schema: `{"type": "object", "properties": {"price": {"type": "number", "minimum": 1}}}`,
+			expected: "incompatible schema: not backward compatible with version 1: message.price must be at least 1"},
+		{name: "Items narrowed", schema: `{"type": "object", "properties": {"tags": {"type": "array", "items": {"enum": ["sf"]}}}}`,
+			expected: "incompatible schema: not backward compatible with version 1: message.tags[] must be one of [sf]"},
+		{name: "Forward", schema: `{"type": "object"}`, compatibility: schema.CompatibilityForward,
+			expected: "incompatible schema: not forward compatible with version 1: message.title is required"},
+		{name: "Full", schema: `{"type": "object", "properties": {"price": {"type": "number"}}}`,
+			compatibility: schema.CompatibilityFull,
+			expected:      "incompatible schema: not full compatible with version 1: message.title is required"},
+		{name: "None", schema: `{"type": "string"}`, compatibility: schema.CompatibilityNone, version: 2},
+		{name: "Invalid compatibility", schema: book, compatibility: "transitive", expected: "compatibility must be none, backward, forward or full"},
+		{name: "Not JSON", schema: `{"type":`, expected: "invalid schema: the schema is not valid JSON"},
+		{name: "Not an object", schema: `true`, expected: "invalid schema: the schema must be an object"},
+		{name: "Unknown keyword", schema: `{"type": "array", "minItems": 1}`, expected: "invalid schema: json: unknown field \"minItems\""},
+		{name: "Unknown type", schema: `{"properties": {"a": {"type": "null"}}}`,
+			expected: "invalid schema: schema.properties.a.type must be string, number, integer, boolean, object or array"},
+		{name: "Reference", schema: `{"items": {"$ref": "#/definitions/item"}}`, expected: "invalid schema: schema.items.$ref is not supported"},
+		{name: "Invalid pattern", schema: `{"pattern": "("}`, expected: "invalid schema: schema.pattern is not a valid regular expression"},
+	}
+
+	for _, testCase := range testCases {
+		registry := schema.NewRegistry()
+		if _, _, err := registry.Register("books", json.RawMessage(book), ""); err != nil {
+			t.Fatalf("%s: Register throws an error: %v", testCase.name, err)
+		}
+
+		version, created, err := registry.Register("books", json.RawMessage(testCase.schema), testCase.compatibility)
+		if testCase.expected != "" {
+			if err == nil || err.Error() != testCase.expected {
+				t.Errorf("%s: Register error is %v, expected %s", testCase.name, err, testCase.expected)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Register throws an error: %v", testCase.name, err)
+
+			continue
+		}
+
+		if version.Version != testCase.version || created != (testCase.version == 2) {
+			t.Errorf("%s: Register returned version %d, created %t", testCase.name, version.Version, created)
+		}
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	registry := schema.NewRegistry()
+	if _, _, err := registry.Register("books", json.RawMessage(book), ""); err != nil {
+		t.Fatalf("Register throws an error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		topic    string
+		message  string
+		expected string
+	}{
+		{name: "Valid", topic: "books", message: `{"title": "Dune", "price": 9.99, "tags": ["sf"]}`},
+		{name: "Topic without schema", topic: "games", message: `"anything"`},
+		{name: "Missing property", topic: "books", message: `{"title": "Dune"}`,
+			expected: "message does not match the schema version 1: message.price is required"},
+		{name: "Invalid property", topic: "books", message: `{"title": "Dune", "price": -1}`,
+			expected: "message does not match the schema version 1: message.price must be at least 0"},
+		{name: "Invalid item", topic: "books", message: `{"title": "Dune", "price": 1, "tags": [1]}`,
+			expected: "message does not match the schema version 1: message.tags[0] must be of type string"},
+		{name: "Unknown property", topic: "books", message: `{"title": "Dune", "price": 1, "a\"b": 1}`,
+			expected: "message does not match the schema version 1: message.a\"b is not allowed"},
+	}
+
+	for _, testCase := range testCases {
+		err := registry.Validate(testCase.topic, decode(t, testCase.message))
+		switch {
+		case testCase.expected == "" && err != nil:
+			t.Errorf("%s: Validate throws an error: %v", testCase.name, err)
+		case testCase.expected != "" && (err == nil || err.Error() != testCase.expected || !errors.Is(err, schema.ErrInvalidMessage)):
+			t.Errorf("%s: Validate error is %v, expected %s", testCase.name, err, testCase.expected)
+		}
+	}
+
+	var nilRegistry *schema.Registry
+	if err := nilRegistry.Validate("books", nil); err != nil {
+		t.Errorf("Validate of a nil registry throws an error: %v", err)
+	}
+}
+
+func TestRegistry_versions(t *testing.T) {
+	registry := schema.NewRegistry()
+	for _, document := range []string{book, `{"type": "object"}`} {
+		if _, _, err := registry.Register("books", json.RawMessage(document), schema.CompatibilityNone); err != nil {
+			t.Fatalf("Register throws an error: %v", err)
+		}
+	}
+
+	schemas, err := registry.Get("books")
+	if err != nil || schemas.Compatibility != "none" || len(schemas.Versions) != 2 {
+		t.Fatalf("Get returned %+v, %v", schemas, err)
+	}
+
+	latest, err := registry.Version("books", 0)
+	if err != nil || latest.Version != 2 || string(latest.Schema) != `{"type":"object"}` {
+		t.Errorf("Version returned the latest version %+v, %v", latest, err)
+	}
+
+	// The latest schema applies.
+	if err = registry.Validate("books", decode(t, `{}`)); err != nil {
+		t.Errorf("Validate throws an error: %v", err)
+	}
+
+	if _, err = registry.Version("books", 3); !errors.Is(err, schema.ErrNotFound) {
+		t.Errorf("Version of a missing version returned %v", err)
+	}
+
+	if err = registry.Delete("books"); err != nil {
+		t.Errorf("Delete throws an error: %v", err)
+	}
+
+	if _, err = registry.Get("books"); !errors.Is(err, schema.ErrNotFound) {
+		t.Errorf("Get of a deleted topic returned %v", err)
+	}
+
+	if _, _, err = registry.Register("_inbox.x", json.RawMessage(book), ""); !errors.Is(err, schema.ErrInboxTopic) {
+		t.Errorf("Register of an inbox returned %v", err)
+	}
+}
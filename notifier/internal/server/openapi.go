@@ -0,0 +1,482 @@
+package server
+
+import (
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// Spec returns the OpenAPI document of the /publish, /subscribe, /webhooks and /schemas routes.
+// It must be updated together with the routes and handlers.
+func Spec() *openapi.Document {
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "pub-sub notifier",
+			Description: "Publishers send messages to topics and subscribers receive them over websockets.",
+			Version:     "1.0.0",
+		},
+		Paths: map[string]openapi.PathItem{
+			"/publish": {
+				"post": {
+					OperationID: "publish",
+					Summary:     "Publish a message to a topic",
+					Description: "Requires permission to publish to the topic. A message whose ID, or Idempotency-Key " +
+						"if it has none, was published to the topic within the dedup window is acknowledged but dropped. " +
+						"Replies may be published to any inbox. Messages must match the latest schema of the topic.",
+					Tags: []string{"messages"},
+					Parameters: []*openapi.Parameter{{
+						Name:        idempotency.Header,
+						In:          "header",
+						Description: "The ID of a message without the id field.",
+						Schema:      &openapi.Schema{Type: "string"},
+					}},
+					RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("PublishRequest"))},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The message is published, or dropped as a duplicate if Idempotent-Replayed is set."},
+						"400":     errorResponse("The request does not match the specification, or the message does not match the schema of the topic."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Publishing to the topic is forbidden."),
+						"413":     errorResponse("The request body is too large."),
+						"429":     errorResponse("The rate limit of the topic is exceeded."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/request": {
+				"post": {
+					OperationID: "request",
+					Summary:     "Publish a message to a topic and wait for a reply",
+					Description: "Publishes the message like /publish with a temporary inbox topic as its replyTo. " +
+						"Subscribers reply by publishing to the inbox with the correlationId of the message.",
+					Tags: []string{"messages"},
+					Parameters: []*openapi.Parameter{{
+						Name:        "timeout",
+						In:          "query",
+						Description: "The time to wait for the reply, like 5s, up to the reply timeout.",
+						Schema:      &openapi.Schema{Type: "string"},
+					}},
+					RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("PublishRequest"))},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The first reply.", Content: openapi.JSON(openapi.Ref("Frame"))},
+						"400":     errorResponse("The request does not match the specification, it is delayed, or the message does not match the schema of the topic."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Publishing to the topic is forbidden."),
+						"413":     errorResponse("The request body is too large."),
+						"429":     errorResponse("The rate limit of the topic is exceeded."),
+						"503":     errorResponse("No subscriber received the request, or the client canceled it."),
+						"504":     errorResponse("No reply was received in time."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/subscribe": {
+				"get": {
+					OperationID: "subscribe",
+					Summary:     "Subscribe to topics over a websocket",
+					Description: "After the upgrade, the client sends Command frames and receives Frame frames. " +
+						"Every connection multiplexes subscriptions with client-chosen IDs, which are echoed by the frames " +
+						"of the subscription. Browsers may pass the credentials in the access_token query parameter.",
+					Tags: []string{"messages"},
+					Responses: map[string]*openapi.Response{
+						"101": {Description: "The connection is upgraded to a websocket."},
+						"401": errorResponse("The credentials are missing or invalid."),
+					},
+				},
+			},
+			"/subscribe/sse": {
+				"get": {
+					OperationID: "subscribeEvents",
+					Summary:     "Subscribe to a topic with server-sent events",
+					Description: "Streams message events whose data is a Frame and whose ID is the sequence number " +
+						"of the message in the topic. Reconnecting with Last-Event-ID replays the kept messages after it.",
+					Tags: []string{"messages"},
+					Parameters: []*openapi.Parameter{
+						{Name: "topic", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+						{
+							Name:        "Last-Event-ID",
+							In:          "header",
+							Description: "The ID of the last event received before reconnecting.",
+							Schema:      &openapi.Schema{Type: "integer", Minimum: openapi.Float(0)},
+						},
+					},
+					Responses: map[string]*openapi.Response{
+						"200": {
+							Description: "The event stream.",
+							Content:     map[string]*openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}},
+						},
+						"400": errorResponse("The topic is missing or Last-Event-ID is invalid."),
+						"401": errorResponse("The credentials are missing or invalid."),
+						"403": errorResponse("Subscribing to the topic is forbidden."),
+						"503": errorResponse("The server is going away."),
+					},
+				},
+			},
+			"/poll": {
+				"get": {
+					OperationID: "poll",
+					Summary:     "Receive the messages of a topic by long polling",
+					Description: "Responds with the kept messages published after the cursor, or waits up to the poll " +
+						"timeout for the next message. The next request passes the cursor of the response.",
+					Tags: []string{"messages"},
+					Parameters: []*openapi.Parameter{
+						{Name: "topic", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", MinLength: openapi.Int(1)}},
+						{
+							Name:        "cursor",
+							In:          "query",
+							Description: "The cursor of the previous response. Without it, the request waits for the next message.",
+							Schema:      &openapi.Schema{Type: "integer", Minimum: openapi.Float(0)},
+						},
+					},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The messages, empty if none was published in time.", Content: openapi.JSON(openapi.Ref("PollResponse"))},
+						"400":     errorResponse("The request does not match the specification."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Subscribing to the topic is forbidden."),
+						"503":     errorResponse("The server is going away."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/webhooks": {
+				"post": {
+					OperationID: "createWebhook",
+					Summary:     "Create a webhook subscription",
+					Description: "Requires permission to subscribe to the topic pattern. The messages of matching topics " +
+						"are posted to the URL as Frame bodies, signed with the secret in the Webhook-Signature header.",
+					Tags:        []string{"webhooks"},
+					RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("WebhookRequest"))},
+					Responses: map[string]*openapi.Response{
+						"201":     {Description: "The webhook is created.", Content: openapi.JSON(openapi.Ref("Webhook"))},
+						"400":     errorResponse("The request does not match the specification."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Subscribing to the topic is forbidden."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+				"get": {
+					OperationID: "listWebhooks",
+					Summary:     "List the webhook subscriptions of the caller",
+					Tags:        []string{"webhooks"},
+					Responses: map[string]*openapi.Response{
+						"200": {
+							Description: "The webhooks, from the oldest to the newest.",
+							Content:     openapi.JSON(&openapi.Schema{Type: "array", Items: openapi.Ref("Webhook")}),
+						},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/webhooks/{id}": {
+				"get": {
+					OperationID: "getWebhook",
+					Summary:     "Get a webhook subscription",
+					Tags:        []string{"webhooks"},
+					Parameters:  []*openapi.Parameter{webhookID()},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The webhook.", Content: openapi.JSON(openapi.Ref("Webhook"))},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"404":     errorResponse("The webhook is not found."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+				"delete": {
+					OperationID: "deleteWebhook",
+					Summary:     "Delete a webhook subscription",
+					Tags:        []string{"webhooks"},
+					Parameters:  []*openapi.Parameter{webhookID()},
+					Responses: map[string]*openapi.Response{
+						"204":     {Description: "The webhook is deleted."},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"404":     errorResponse("The webhook is not found."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/webhooks/{id}/enable": {
+				"post": {
+					OperationID: "enableWebhook",
+					Summary:     "Enable a webhook subscription disabled after repeated failures",
+					Tags:        []string{"webhooks"},
+					Parameters:  []*openapi.Parameter{webhookID()},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The webhook.", Content: openapi.JSON(openapi.Ref("Webhook"))},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"404":     errorResponse("The webhook is not found."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/webhooks/{id}/deliveries": {
+				"get": {
+					OperationID: "listWebhookDeliveries",
+					Summary:     "List the latest delivery attempts of a webhook subscription",
+					Tags:        []string{"webhooks"},
+					Parameters:  []*openapi.Parameter{webhookID()},
+					Responses: map[string]*openapi.Response{
+						"200": {
+							Description: "The delivery attempts, the newest first.",
+							Content:     openapi.JSON(&openapi.Schema{Type: "array", Items: openapi.Ref("Delivery")}),
+						},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"404":     errorResponse("The webhook is not found."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+			"/schemas": {
+				"post": {
+					OperationID: "registerSchema",
+					Summary:     "Register a new schema version of a topic",
+					Description: "Requires the schema-admin role and permission to publish to the topic. The schema must keep " +
+						"the compatibility of the topic with the latest version, backward unless set.",
+					Tags:        []string{"schemas"},
+					Parameters:  []*openapi.Parameter{schemaTopic()},
+					RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("SchemaRequest"))},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "The schema is the latest version already.", Content: openapi.JSON(openapi.Ref("SchemaVersion"))},
+						"201":     {Description: "The schema version is created.", Content: openapi.JSON(openapi.Ref("SchemaVersion"))},
+						"400":     errorResponse("The request does not match the specification, or the schema is invalid."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Managing the schemas of the topic is forbidden."),
+						"409":     errorResponse("The schema breaks the compatibility of the topic."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+				"get": {
+					OperationID: "getSchemas",
+					Summary:     "Get the schema versions of a topic",
+					Description: "Requires permission to publish or subscribe to the topic.",
+					Tags:        []string{"schemas"},
+					Parameters: []*openapi.Parameter{
+						schemaTopic(),
+						{
+							Name:        "version",
+							In:          "query",
+							Description: "Returns only the version, a number or latest.",
+							Schema:      &openapi.Schema{Type: "string", Pattern: "^([1-9][0-9]*|latest)$"},
+						},
+					},
+					Responses: map[string]*openapi.Response{
+						"200": {
+							Description: "The schema versions, or the requested version.",
+							Content:     openapi.JSON(&openapi.Schema{AnyOf: []*openapi.Schema{openapi.Ref("TopicSchemas"), openapi.Ref("SchemaVersion")}}),
+						},
+						"400":     errorResponse("The request does not match the specification."),
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Reading the schemas of the topic is forbidden."),
+						"404":     errorResponse("The topic or the version has no schema."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+				"delete": {
+					OperationID: "deleteSchemas",
+					Summary:     "Delete the schema versions of a topic",
+					Description: "Requires the schema-admin role and permission to publish to the topic. Its messages are not " +
+						"validated anymore.",
+					Tags:       []string{"schemas"},
+					Parameters: []*openapi.Parameter{schemaTopic()},
+					Responses: map[string]*openapi.Response{
+						"204":     {Description: "The schema versions are deleted."},
+						"401":     errorResponse("The credentials are missing or invalid."),
+						"403":     errorResponse("Managing the schemas of the topic is forbidden."),
+						"404":     errorResponse("The topic has no schema."),
+						"default": errorResponse("An unexpected error."),
+					},
+				},
+			},
+		},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{
+				"PublishRequest": {
+					Type:     "object",
+					Required: []string{"topic", "message"},
+					Properties: map[string]*openapi.Schema{
+						"id":      {Type: "string", Description: "Deduplicates retries of the message."},
+						"topic":   {Type: "string", MinLength: openapi.Int(1), Example: "news"},
+						"message": {Description: "Any JSON value."},
+						"retain": {
+							Type: "boolean",
+							Description: "Keeps the message for new subscribers of the topic until another retained message " +
+								"replaces it. A retained null or empty string removes it.",
+						},
+						"ttl": {
+							Type:        "string",
+							Description: "Drops the message if it is not delivered within the duration after its publishing.",
+							Example:     "2h",
+						},
+						"delay":     {Type: "string", Description: "Publishes the message after the duration.", Example: "90s"},
+						"deliverAt": {Type: "string", Format: "date-time", Description: "Publishes the message at the time, instead of delay."},
+						"replyTo":   {Type: "string", Description: "The topic subscribers publish their replies to."},
+						"correlationId": {
+							Type:        "string",
+							Description: "Passed to subscribers, which set it on their replies. Generated by /request if missing.",
+						},
+						"headers": {
+							Type: "object",
+							Description: "String values passed to subscribers, which filter on them as $headers.name. " +
+								"Names are made of letters, digits and underscores.",
+							Example: map[string]interface{}{"region": "eu"},
+						},
+					},
+				},
+				"Command": {
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"type": {
+							Type:        "string",
+							Enum:        []interface{}{"subscribe", "unsubscribe", "ping", "ack"},
+							Description: "A command without a type subscribes to the topic with the topic as the ID.",
+						},
+						"id":    {Type: "string", Description: "The subscription ID, echoed by the answer."},
+						"topic": {Type: "string", MinLength: openapi.Int(1)},
+						"seq":   {Type: "integer", Description: "Acknowledges messages up to and including seq."},
+						"filter": {
+							Type:        "string",
+							MaxLength:   openapi.Int(1024),
+							Description: "Subscribes only to the messages matching the expression, like price < 20 && $topic == 'books'.",
+						},
+					},
+				},
+				"Frame": {
+					Type:     "object",
+					Required: []string{"type"},
+					Properties: map[string]*openapi.Schema{
+						"type": {
+							Type: "string",
+							Enum: []interface{}{"message", "subscribed", "unsubscribed", "pong", "error"},
+						},
+						"id":            {Type: "string", Description: "The subscription or command ID."},
+						"topic":         {Type: "string"},
+						"seq":           {Type: "integer", Description: "The number of the message in its subscription."},
+						"message":       {Description: "The published message."},
+						"retained":      {Type: "boolean", Description: "Set on the retained message sent when subscribing."},
+						"replyTo":       {Type: "string", Description: "The topic to publish a reply to."},
+						"correlationId": {Type: "string", Description: "The ID the reply carries."},
+						"headers":       {Type: "object", Description: "The headers set by the publisher."},
+						"traceContext":  {Type: "object", Description: "The W3C trace context of the delivery."},
+						"error": {
+							Type:     "object",
+							Required: []string{"statusCode", "message"},
+							Properties: map[string]*openapi.Schema{
+								"statusCode": {Type: "integer"},
+								"message":    {Type: "string"},
+							},
+						},
+					},
+				},
+				"PollResponse": {
+					Type:     "object",
+					Required: []string{"messages", "cursor"},
+					Properties: map[string]*openapi.Schema{
+						"messages": {Type: "array", Items: openapi.Ref("Frame")},
+						"cursor":   {Type: "integer", Description: "The cursor of the next request."},
+					},
+				},
+				"WebhookRequest": {
+					Type:     "object",
+					Required: []string{"topic", "url", "secret"},
+					Properties: map[string]*openapi.Schema{
+						"topic":  {Type: "string", MinLength: openapi.Int(1), Description: "A topic pattern, \"*\" matches any characters.", Example: "news.*"},
+						"url":    {Type: "string", MinLength: openapi.Int(1), Example: "https://example.com/hooks/news"},
+						"secret": {Type: "string", MinLength: openapi.Int(16), Description: "Signs the deliveries."},
+					},
+				},
+				"Webhook": {
+					Type:     "object",
+					Required: []string{"id", "topic", "url", "enabled", "failures", "createdAt"},
+					Properties: map[string]*openapi.Schema{
+						"id":        {Type: "string"},
+						"topic":     {Type: "string"},
+						"url":       {Type: "string"},
+						"enabled":   {Type: "boolean", Description: "Webhooks are disabled after repeated failed deliveries."},
+						"failures":  {Type: "integer", Description: "The number of failed deliveries in a row."},
+						"createdAt": {Type: "string", Format: "date-time"},
+					},
+				},
+				"Delivery": {
+					Type:     "object",
+					Required: []string{"id", "topic", "seq", "attempt", "durationSeconds", "time"},
+					Properties: map[string]*openapi.Schema{
+						"id":              {Type: "string", Description: "The Webhook-ID header, the same for every attempt."},
+						"topic":           {Type: "string"},
+						"seq":             {Type: "integer"},
+						"attempt":         {Type: "integer"},
+						"statusCode":      {Type: "integer", Description: "Missing if the request failed without a response."},
+						"error":           {Type: "string"},
+						"durationSeconds": {Type: "number"},
+						"time":            {Type: "string", Format: "date-time"},
+					},
+				},
+				"SchemaRequest": {
+					Type:     "object",
+					Required: []string{"schema"},
+					Properties: map[string]*openapi.Schema{
+						"schema": {
+							Type: "object",
+							Description: "A JSON Schema with the type, format, enum, pattern, minLength, maxLength, minimum, " +
+								"maximum, properties, required, additionalProperties, items and anyOf keywords.",
+							Example: map[string]interface{}{"type": "object", "required": []string{"title"}},
+						},
+						"compatibility": {
+							Type:        "string",
+							Enum:        []interface{}{"none", "backward", "forward", "full"},
+							Description: "Replaces the compatibility of the topic.",
+						},
+					},
+				},
+				"SchemaVersion": {
+					Type:     "object",
+					Required: []string{"topic", "version", "schema", "createdAt"},
+					Properties: map[string]*openapi.Schema{
+						"topic":     {Type: "string"},
+						"version":   {Type: "integer", Description: "Starts at 1 for every topic."},
+						"schema":    {Type: "object"},
+						"createdAt": {Type: "string", Format: "date-time"},
+					},
+				},
+				"TopicSchemas": {
+					Type:     "object",
+					Required: []string{"topic", "compatibility", "versions"},
+					Properties: map[string]*openapi.Schema{
+						"topic":         {Type: "string"},
+						"compatibility": {Type: "string", Enum: []interface{}{"none", "backward", "forward", "full"}},
+						"versions":      {Type: "array", Items: openapi.Ref("SchemaVersion"), Description: "From the oldest to the latest."},
+					},
+				},
+				"Error": {
+					Type:     "object",
+					Required: []string{"error"},
+					Properties: map[string]*openapi.Schema{
+						"error": {
+							Type:     "object",
+							Required: []string{"statusCode", "message"},
+							Properties: map[string]*openapi.Schema{
+								"statusCode": {Type: "integer"},
+								"message":    {Type: "string"},
+							},
+						},
+					},
+				},
+			},
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"apiKey": {Type: "apiKey", Name: auth.APIKeyHeader, In: "header"},
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{"apiKey": {}}, {"bearer": {}}},
+	}
+}
+
+func webhookID() *openapi.Parameter {
+	return &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func schemaTopic() *openapi.Parameter {
+	return &openapi.Parameter{Name: "topic", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", MinLength: openapi.Int(1)}}
+}
+
+func errorResponse(description string) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.JSON(openapi.Ref("Error"))}
+}
@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/server"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// TestSpec checks that the responses of the publish and schemas handlers match the OpenAPI document.
+func TestSpec(t *testing.T) {
+	testCases := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+	}{
+		{name: "OK", body: `{"topic":"news","message":{"title":"..."}}`, expectedStatusCode: http.StatusOK},
+		{name: "Forbidden topic", body: `{"topic":"secrets","message":"..."}`, expectedStatusCode: http.StatusForbidden},
+		{name: "Missing message", body: `{"topic":"news"}`, expectedStatusCode: http.StatusBadRequest},
+		{name: "Wrong topic type", body: `{"topic":1,"message":"..."}`, expectedStatusCode: http.StatusBadRequest},
+		{name: "Empty body", body: ``, expectedStatusCode: http.StatusBadRequest},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	principal := &auth.Principal{Subject: "test", Roles: []string{auth.RoleSchemaAdmin}, Publish: []string{"news"}}
+	publisher := handler.NewPublisher(service.NewNotifier(0, 0, 0, nil), nil, nil, time.Second, log)
+	publish := openapi.Middleware(server.Spec(), true, log)(http.HandlerFunc(publisher.Publish))
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/publish", bytes.NewBufferString(testCase.body))
+		rec := httptest.NewRecorder()
+		publish.ServeHTTP(rec, req.WithContext(auth.NewContext(req.Context(), principal)))
+
+		assert.Equal(t, testCase.expectedStatusCode, rec.Code, testCase.name)
+	}
+
+	request := openapi.Middleware(server.Spec(), true, log)(http.HandlerFunc(publisher.Request))
+	req := httptest.NewRequest(http.MethodPost, "/request", bytes.NewBufferString(`{"topic":"news","message":"..."}`))
+	rec := httptest.NewRecorder()
+	request.ServeHTTP(rec, req.WithContext(auth.NewContext(req.Context(), principal)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "Request without responders")
+
+	schemas := openapi.Middleware(server.Spec(), true, log)(handler.NewSchemas(schema.NewRegistry(), log))
+	for _, call := range []struct {
+		method, url, body  string
+		expectedStatusCode int
+	}{
+		{http.MethodPost, "/schemas?topic=news", `{"schema":{"type":"object"}}`, http.StatusCreated},
+		{http.MethodPost, "/schemas?topic=news", `{"schema":{"type":"object"},"compatibility":"all"}`, http.StatusBadRequest},
+		{http.MethodGet, "/schemas?topic=news", ``, http.StatusOK},
+		{http.MethodGet, "/schemas?topic=news&version=latest", ``, http.StatusOK},
+		{http.MethodGet, "/schemas?topic=news&version=-1", ``, http.StatusBadRequest},
+	} {
+		req = httptest.NewRequest(call.method, call.url, bytes.NewBufferString(call.body))
+		rec = httptest.NewRecorder()
+		schemas.ServeHTTP(rec, req.WithContext(auth.NewContext(req.Context(), principal)))
+		assert.Equal(t, call.expectedStatusCode, rec.Code, call.method+" "+call.url)
+	}
+}
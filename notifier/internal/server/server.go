@@ -2,21 +2,36 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
-	"golang.org/x/net/websocket"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/ivyoverflow/pub-sub/notifier/internal/config"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/handler"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/metrics"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/mqtt"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/schema"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/health"
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
 	"github.com/ivyoverflow/pub-sub/platform/logger"
+	platformmetrics "github.com/ivyoverflow/pub-sub/platform/metrics"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
 )
 
 // Server represents application server.
 type Server struct {
 	httpServer *http.Server
 	log        *logger.Logger
+	cfg        *config.Config
 }
 
 // New returns a new configured Server object.
@@ -26,20 +41,206 @@ func New(cfg *config.Config, log *logger.Logger) *Server {
 			Addr: fmt.Sprintf("%s:%s", cfg.Addr, cfg.Port),
 		},
 		log: log,
+		cfg: cfg,
 	}
 }
 
-// Run configures routes and starts the server.
-func (server *Server) Run() error {
-	svc := service.NewNotifier()
-	publisherHandler := handler.NewPublisher(svc, server.log)
-	subscriberHandler := handler.NewSubscriber(svc, server.log)
+// Run configures routes and starts the server. When ctx is done, the server stops accepting
+// new connections, closes subscriber websockets with a "going away" notice and waits up to
+// the shutdown timeout for in-flight requests and subscribers to finish. The OpenAPI document
+// is served by /openapi.json and rendered by /docs, and /publish, /request, /poll, /webhooks and /schemas requests
+// that do not match it are rejected. Published messages must match the schemas registered by /schemas, which
+// are kept in the memory of this replica only and lost on restart.
+// If the MQTT port is set, MQTT clients are served on it too, and their connections are closed on shutdown.
+func (server *Server) Run(ctx context.Context) error {
+	reg := platformmetrics.NewRegistry()
+	httpMetrics := platformmetrics.NewHTTP(reg)
+	observer := metrics.NewNotifier(reg, server.cfg.MetricsTopics)
+	svc := service.NewNotifier(server.cfg.DeliveryTimeout, server.cfg.DedupWindow, server.cfg.HistorySize, observer)
+	svc.LimitHistory(server.cfg.HistoryTopics)
+	checker := health.NewChecker(server.cfg.HealthTimeout)
+	checker.Add("queue", service.QueueCheck(svc, server.cfg.MaxPending))
+	checker.Add("subscribers", service.SubscribersCheck(svc, server.cfg.MaxSubscribers))
+
+	limiter, err := ratelimit.NewStore(server.cfg.RateLimitRedisURL)
+	if err != nil {
+		return err
+	}
+
+	if shared, ok := limiter.(*ratelimit.RedisStore); ok {
+		defer shared.Close()
+		checker.Add("ratelimit", shared.Ping)
+	}
+
+	publishLimits, err := handler.NewPublishLimits(limiter, server.cfg.PublishRateLimit, server.cfg.PublishRateLimitTopics)
+	if err != nil {
+		return err
+	}
+
+	schemas := schema.NewRegistry()
+	publisherHandler := handler.NewPublisher(svc, publishLimits, schemas, server.cfg.ReplyTimeout, server.log)
+	subscriberHandler := handler.NewSubscriber(svc, &handler.SubscriberConfig{
+		MaxUnacked:      server.cfg.MaxUnacked,
+		PingInterval:    server.cfg.PingInterval,
+		PongTimeout:     server.cfg.PongTimeout,
+		WriteTimeout:    server.cfg.WriteTimeout,
+		MaxMessageBytes: server.cfg.MaxMessageBytes,
+		Compression:     server.cfg.Compression,
+
+		HeartbeatInterval: server.cfg.HeartbeatInterval,
+		PollTimeout:       server.cfg.PollTimeout,
+		AllowedOrigins:    server.cfg.CORSAllowedOrigins,
+	}, server.log)
+
+	if server.cfg.Webhook.AllowedNetworks, err = middleware.ParseCIDRs(server.cfg.WebhookAllowedNetworks); err != nil {
+		return err
+	}
+
+	webhooks := webhook.New(svc, server.cfg.Webhook,
+		&http.Client{Transport: otelhttp.NewTransport(webhook.NewTransport(server.cfg.Webhook))}, server.log)
+
+	trustedProxies, err := middleware.ParseCIDRs(server.cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	authn, err := auth.New(server.cfg.Auth)
+	if err != nil {
+		return err
+	}
+
+	if !authn.Enabled() {
+		server.log.Warn("Authentication is disabled, every request is allowed")
+	}
+
+	authenticate := auth.Middleware(authn, server.log)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/publish", publisherHandler.Publish)
-	mux.Handle("/subscribe", websocket.Handler(subscriberHandler.Subscribe))
+	mux.HandleFunc("/healthz", checker.Healthz)
+	mux.HandleFunc("/readyz", checker.Readyz)
+	mux.Handle("/metrics", platformmetrics.Handler(reg))
+	spec := Spec()
+	mux.Handle("/openapi.json", openapi.Handler(spec))
+	mux.Handle("/docs", openapi.DocsHandler("/openapi.json"))
+	publish := otelhttp.NewHandler(http.HandlerFunc(publisherHandler.Publish), "/publish")
+	publish = middleware.Chain(
+		httpMetrics.Middleware(route("/publish")),
+		authenticate,
+		middleware.Timeout(server.cfg.RequestTimeout),
+		middleware.Gzip(),
+		openapi.Middleware(spec, server.cfg.ValidateResponses, server.log),
+	)(publish)
+	mux.Handle("/publish", publish)
+	// Requests wait for their reply up to the reply timeout, so the request timeout does not apply.
+	request := otelhttp.NewHandler(http.HandlerFunc(publisherHandler.Request), "/request")
+	request = middleware.Chain(
+		httpMetrics.Middleware(route("/request")),
+		authenticate,
+		middleware.Gzip(),
+		openapi.Middleware(spec, server.cfg.ValidateResponses, server.log),
+	)(request)
+	mux.Handle("/request", request)
+	mux.Handle("/subscribe", authenticate(http.HandlerFunc(subscriberHandler.Subscribe)))
+	mux.Handle("/subscribe/sse", authenticate(http.HandlerFunc(subscriberHandler.Events)))
+	mux.Handle("/poll", middleware.Chain(
+		authenticate,
+		openapi.Middleware(spec, server.cfg.ValidateResponses, server.log),
+	)(http.HandlerFunc(subscriberHandler.Poll)))
+	webhookHandler := middleware.Chain(
+		authenticate,
+		openapi.Middleware(spec, server.cfg.ValidateResponses, server.log),
+	)(handler.NewWebhooks(webhooks, server.log))
+	mux.Handle("/webhooks", webhookHandler)
+	mux.Handle("/webhooks/", webhookHandler)
+	mux.Handle("/schemas", middleware.Chain(
+		authenticate,
+		openapi.Middleware(spec, server.cfg.ValidateResponses, server.log),
+	)(handler.NewSchemas(schemas, server.log)))
+
+	server.httpServer.Handler = middleware.Chain(
+		middleware.RealIP(trustedProxies),
+		logger.Middleware(server.log, path),
+		middleware.Recovery(server.log),
+		middleware.AccessLog(server.log),
+		middleware.CORS(&middleware.CORSConfig{
+			AllowedOrigins: server.cfg.CORSAllowedOrigins,
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+			AllowedHeaders: []string{
+				"Content-Type", "Authorization", auth.APIKeyHeader, logger.RequestIDHeader, idempotency.Header,
+			},
+			ExposedHeaders: []string{
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", idempotency.ReplayedHeader,
+			},
+			MaxAge: server.cfg.CORSMaxAge,
+		}),
+		middleware.BodyLimit(server.cfg.MaxBodyBytes),
+	)(mux)
+
+	errs := make(chan error, 2)
+	var mqttServer *mqtt.Server
+	if server.cfg.MQTTPort != "" {
+		var listener net.Listener
+		if listener, err = net.Listen("tcp", fmt.Sprintf("%s:%s", server.cfg.Addr, server.cfg.MQTTPort)); err != nil {
+			return err
+		}
 
-	server.httpServer.Handler = mux
+		mqttServer = mqtt.New(svc, authn, schemas, publishLimits, observer, server.cfg.MQTT, server.log)
+		go func() {
+			errs <- mqttServer.Serve(listener)
+		}()
+	}
+
+	go func() {
+		errs <- server.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.cfg.ShutdownTimeout)
+	defer cancel()
+
+	subscribersErr := make(chan error, 1)
+	go func() {
+		subscribersErr <- subscriberHandler.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := webhooks.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if mqttServer != nil {
+		if err := mqttServer.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+	}
+
+	return <-subscribersErr
+}
+
+// route returns a route function of the metrics middleware that always reports pattern.
+func route(pattern string) func(*http.Request) string {
+	return func(*http.Request) string {
+		return pattern
+	}
+}
+
+// path is the route function of the logger middleware. The IDs of /webhooks routes are replaced
+// with a placeholder, the other notifier routes have no parameters.
+func path(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/webhooks/") {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/webhooks/"), "/", 2)
+		parts[0] = "{id}"
+
+		return "/webhooks/" + strings.Join(parts, "/")
+	}
 
-	return server.httpServer.ListenAndServe()
+	return r.URL.Path
 }
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// dedup remembers the keys of published messages for a window, so that duplicates can be dropped.
+type dedup struct {
+	window time.Duration
+	mutex  sync.Mutex
+	seen   map[string]time.Time
+	// order holds the keys in the order they were seen, so expired keys are removed from its front.
+	order []string
+}
+
+func newDedup(window time.Duration) *dedup {
+	return &dedup{window: window, seen: make(map[string]time.Time)}
+}
+
+// add remembers key and reports whether it was not seen within the window. A zero window remembers nothing.
+func (d *dedup) add(key string, now time.Time) bool {
+	if d.window <= 0 {
+		return true
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for len(d.order) > 0 && now.Sub(d.seen[d.order[0]]) >= d.window {
+		delete(d.seen, d.order[0])
+		d.order = d.order[1:]
+	}
+
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+
+	d.seen[key] = now
+	d.order = append(d.order, key)
+
+	return true
+}
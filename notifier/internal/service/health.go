@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ivyoverflow/pub-sub/platform/health"
+)
+
+// QueueCheck returns a readiness check that fails when at least maxPending messages are waiting for delivery.
+// A zero maxPending disables the check.
+func QueueCheck(n *Notifier, maxPending int) health.Check {
+	return func(context.Context) error {
+		if pending := n.Stats().Pending; maxPending > 0 && pending >= int64(maxPending) {
+			return fmt.Errorf("%d messages are waiting for delivery, the limit is %d", pending, maxPending)
+		}
+
+		return nil
+	}
+}
+
+// SubscribersCheck returns a readiness check that fails when there are at least maxSubscribers subscribers.
+// A zero maxSubscribers disables the check.
+func SubscribersCheck(n *Notifier, maxSubscribers int) health.Check {
+	return func(context.Context) error {
+		if subscribers := n.Stats().Subscribers; maxSubscribers > 0 && subscribers >= maxSubscribers {
+			return fmt.Errorf("%d subscribers are connected, the limit is %d", subscribers, maxSubscribers)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryTopics is the number of topics whose history is kept unless LimitHistory sets another one.
+const DefaultHistoryTopics = 10000
+
+// history numbers the messages of every topic and keeps the latest of them,
+// so that subscribers can resume after the last message they received. It also keeps
+// the last retained message of every topic for new subscribers. Only the maxTopics topics
+// published to most recently are kept, so publishing to ever new topics does not grow it without bound.
+type history struct {
+	size      int
+	maxTopics int
+	mutex     sync.Mutex
+	topics    map[string]*topicHistory
+	// recent holds the names of the topics from the most to the least recently published.
+	recent *list.List
+}
+
+// topicHistory is the sequence number of the last message of a topic and the kept messages in publishing order.
+type topicHistory struct {
+	seq       uint64
+	envelopes []Envelope
+	retained  *Envelope
+	element   *list.Element
+}
+
+func newHistory(size int) *history {
+	return &history{size: size, maxTopics: DefaultHistoryTopics, topics: make(map[string]*topicHistory), recent: list.New()}
+}
+
+// limit sets the number of kept topics and forgets the least recently published topics beyond it.
+func (h *history) limit(maxTopics int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.maxTopics = maxTopics
+	h.evict()
+}
+
+// evict forgets the least recently published topics beyond the limit, with their numbering and retained message.
+func (h *history) evict() {
+	for h.maxTopics > 0 && h.recent.Len() > h.maxTopics {
+		oldest := h.recent.Back()
+		h.recent.Remove(oldest)
+		delete(h.topics, oldest.Value.(string))
+	}
+}
+
+// add numbers the envelope and keeps it, dropping the oldest kept message if there are size of them.
+// A topic that is not kept starts its numbering again.
+// A retained envelope replaces the retained message of the topic, or removes it if its message is empty.
+func (h *history) add(topic string, envelope Envelope, retain bool) Envelope {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	t, ok := h.topics[topic]
+	if !ok {
+		t = &topicHistory{element: h.recent.PushFront(topic)}
+		h.topics[topic] = t
+		h.evict()
+	} else {
+		h.recent.MoveToFront(t.element)
+	}
+
+	t.seq++
+	envelope.Seq = t.seq
+	if h.size > 0 {
+		if len(t.envelopes) == h.size {
+			t.envelopes = append(t.envelopes[:0], t.envelopes[1:]...)
+		}
+
+		t.envelopes = append(t.envelopes, envelope)
+	}
+
+	if retain {
+		t.retained = nil
+		if envelope.Message != nil && envelope.Message != "" {
+			retained := envelope
+			retained.Retained = true
+			t.retained = &retained
+		}
+	}
+
+	return envelope
+}
+
+// retained returns the retained messages of the topics that match. Expired retained messages are removed.
+func (h *history) retained(match func(topic string) bool) []Envelope {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var envelopes []Envelope
+	now := time.Now()
+	for topic, t := range h.topics {
+		if t.retained != nil && t.retained.expired(now) {
+			t.retained = nil
+		}
+
+		if t.retained != nil && match(topic) {
+			envelopes = append(envelopes, *t.retained)
+		}
+	}
+
+	return envelopes
+}
+
+// after returns the kept messages of the topic with a sequence number greater than seq that have not expired,
+// and the sequence number of the last message of the topic. A seq beyond the last message
+// was numbered before a restart, so all kept messages are returned.
+func (h *history) after(topic string, seq uint64) ([]Envelope, uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	t, ok := h.topics[topic]
+	if !ok {
+		return nil, 0
+	}
+
+	if seq > t.seq {
+		seq = 0
+	}
+
+	var envelopes []Envelope
+	now := time.Now()
+	for _, envelope := range t.envelopes {
+		if envelope.Seq > seq && !envelope.expired(now) {
+			envelopes = append(envelopes, envelope)
+		}
+	}
+
+	return envelopes, t.seq
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InboxPrefix starts the names of inbox topics. Messages published to them are received only by their inbox.
+const InboxPrefix = "_inbox."
+
+// Defines the errors of requests.
+var (
+	ErrNoResponders = errors.New("no subscriber received the request")
+	ErrNoReply      = errors.New("no reply was received in time")
+)
+
+// Inbox is a temporary topic that receives the replies to a request. It is removed when it is closed
+// or when its TTL expires, and the replies published to it afterwards are dropped.
+type Inbox struct {
+	// Topic is the name of the inbox, the replyTo of the request.
+	Topic string
+	// C receives the first reply. Replies published while it is full are dropped.
+	C             <-chan Envelope
+	channel       chan Envelope
+	correlationID string
+	n             *Notifier
+	timer         *time.Timer
+	once          sync.Once
+}
+
+// IsInbox reports whether the topic is the name of an inbox. Inbox names are random, so publishing to an inbox
+// is allowed to anyone who knows it, and subscribing to it receives nothing.
+func IsInbox(topic string) bool {
+	return strings.HasPrefix(topic, InboxPrefix)
+}
+
+// NewInbox creates an inbox that accepts replies with the correlation ID, or without any, for ttl.
+func (n *Notifier) NewInbox(correlationID string, ttl time.Duration) *Inbox {
+	channel := make(chan Envelope, 1)
+	inbox := &Inbox{Topic: InboxPrefix + randomID(), C: channel, channel: channel, correlationID: correlationID, n: n}
+
+	n.inboxMutex.Lock()
+	n.inboxes[inbox.Topic] = inbox
+	n.inboxMutex.Unlock()
+
+	inbox.timer = time.AfterFunc(ttl, inbox.remove)
+
+	return inbox
+}
+
+// Close removes the inbox before its TTL expires.
+func (i *Inbox) Close() {
+	i.timer.Stop()
+	i.remove()
+}
+
+func (i *Inbox) remove() {
+	i.once.Do(func() {
+		i.n.inboxMutex.Lock()
+		delete(i.n.inboxes, i.Topic)
+		i.n.inboxMutex.Unlock()
+	})
+}
+
+// reply passes the envelope to its inbox if the inbox exists, the correlation IDs match and no reply is waiting yet.
+func (n *Notifier) reply(envelope Envelope) {
+	n.inboxMutex.Lock()
+	inbox, ok := n.inboxes[envelope.Topic]
+	n.inboxMutex.Unlock()
+	if !ok || (envelope.CorrelationID != "" && envelope.CorrelationID != inbox.correlationID) {
+		return
+	}
+
+	select {
+	case inbox.channel <- envelope:
+	default:
+	}
+}
+
+// Request publishes the message with a new inbox as its replyTo and waits for the first reply until ctx is done.
+// A correlation ID is generated if opts has none. It returns ErrNoResponders if no subscriber received the message,
+// and ErrNoReply if ctx expired first. Requests are neither deduplicated nor scheduled.
+func (n *Notifier) Request(ctx context.Context, topic string, message interface{}, opts *PublishOptions) (Envelope, error) {
+	request := *opts
+	request.ID, request.DeliverAt = "", time.Time{}
+	if request.CorrelationID == "" {
+		request.CorrelationID = randomID()
+	}
+
+	ttl := time.Minute
+	if deadline, ok := ctx.Deadline(); ok {
+		ttl = time.Until(deadline)
+	}
+
+	inbox := n.NewInbox(request.CorrelationID, ttl)
+	defer inbox.Close()
+
+	request.ReplyTo = inbox.Topic
+	if n.publish(ctx, topic, message, &request) == 0 {
+		return Envelope{}, ErrNoResponders
+	}
+
+	select {
+	case envelope := <-inbox.C:
+		return envelope, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Envelope{}, ErrNoReply
+		}
+
+		return Envelope{}, ctx.Err()
+	}
+}
+
+// randomID returns a random hex ID that cannot be guessed.
+func randomID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -1,41 +1,493 @@
 // Package service cotains Publisher-Subscriber pattern implementation.
 package service
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/filter"
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// Stats contains the current load of the notifier. Scheduled is the number of messages waiting for their publishing time.
+type Stats struct {
+	Topics      int
+	Subscribers int
+	Pending     int64
+	Scheduled   int
+}
+
+// Observer is notified about notifier events, for example to export them as metrics.
+type Observer interface {
+	// Published is called when a message is published to a topic with the passed number of subscribers.
+	Published(topic string, subscribers int)
+	// Subscribed is called when a subscriber is added to a topic.
+	Subscribed(topic string)
+	// Unsubscribed is called when a subscriber is removed from a topic.
+	Unsubscribed(topic string)
+	// Delivered is called when a subscriber receives a message, latency is the time since its publishing.
+	Delivered(topic string, latency time.Duration)
+	// Dropped is called when a message is not delivered in time or its subscriber is gone.
+	Dropped(topic string)
+	// Duplicated is called when a message is not published because its ID was published to the topic before.
+	Duplicated(topic string)
+	// Expired is called when the TTL of a message expires before a subscriber receives it.
+	// Messages that expire while waiting for a delivery are reported as dropped too.
+	Expired(topic string)
+}
+
+// Envelope is a published message with the W3C trace context of its publisher,
+// so that subscribers can continue the publisher's trace. Seq numbers the messages of a topic from 1.
+// Retained is set on the retained message of a topic that is delivered to a new subscriber.
+// Expires is the time after which the message is not delivered anymore, zero if it never expires.
+// Subscribers answer a message with a ReplyTo by publishing to that topic with the same CorrelationID.
+type Envelope struct {
+	Topic         string
+	Seq           uint64
+	Message       interface{}
+	TraceContext  map[string]string
+	Retained      bool
+	Expires       time.Time
+	ReplyTo       string
+	CorrelationID string
+	Headers       map[string]string
+}
+
+// expired reports whether the message expired at the time.
+func (e *Envelope) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// PublishOptions are the options of PublishWith. A message with an ID is dropped if a message with
+// the same ID was published to the topic within the dedup window. A retained message is kept as
+// the last value of the topic and delivered to every new subscriber, until another retained message
+// replaces it. A retained nil or empty string message removes the retained message of the topic.
+// A message with a DeliverAt time in the future is published at that time. A message with a TTL
+// is not delivered once the TTL has passed since its publishing. ReplyTo and CorrelationID are passed
+// to the subscribers, so they can answer the message, and so are the Headers set by the publisher.
+type PublishOptions struct {
+	ID            string
+	Retain        bool
+	TTL           time.Duration
+	DeliverAt     time.Time
+	ReplyTo       string
+	CorrelationID string
+	Headers       map[string]string
+}
+
+// subscription is a subscriber channel of a topic or a topic pattern. The done channel is closed on unsubscribing.
+// Messages that do not match the filter, if any, are not delivered to the subscriber. Messages wait in the queue
+// and are passed to the channel one at a time by a single goroutine, so the subscriber receives them in order.
+type subscription struct {
+	pattern string
+	match   func(topic string) bool
+	filter  *filter.Filter
+	channel chan Envelope
+	done    chan struct{}
+
+	queueMutex sync.Mutex
+	queue      []delivery
+	draining   bool
+}
+
+// delivery is a message waiting in the queue of a subscription.
+type delivery struct {
+	envelope  Envelope
+	published time.Time
+}
 
 // Notifier implements Publish-Subscriber pattern methods.
 type Notifier struct {
-	mutex sync.RWMutex
-	subs  map[string][]chan interface{}
+	pending         int64
+	mutex           sync.RWMutex
+	subs            map[string][]*subscription
+	patterns        []*subscription
+	deliveryTimeout time.Duration
+	published       *dedup
+	history         *history
+	scheduler       *scheduler
+	observer        Observer
+	inboxMutex      sync.Mutex
+	inboxes         map[string]*Inbox
 }
 
-// NewNotifier returns a new PublishSubscriber object.
-func NewNotifier() *Notifier {
-	n := &Notifier{}
-	n.subs = make(map[string][]chan interface{})
+// NewNotifier returns a new PublishSubscriber object. A message that is not received by a subscriber
+// within deliveryTimeout is dropped; a zero deliveryTimeout waits until the subscriber is gone.
+// The IDs of messages published by PublishOnce are remembered for dedupWindow; a zero dedupWindow
+// disables deduplication. The last historySize messages of every topic are kept for History.
+// The observer may be nil.
+func NewNotifier(deliveryTimeout, dedupWindow time.Duration, historySize int, observer Observer) *Notifier {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+
+	n := &Notifier{
+		deliveryTimeout: deliveryTimeout,
+		published:       newDedup(dedupWindow),
+		history:         newHistory(historySize),
+		observer:        observer,
+	}
+	n.subs = make(map[string][]*subscription)
+	n.inboxes = make(map[string]*Inbox)
+	n.scheduler = newScheduler(n.publishScheduled)
 
 	return n
 }
 
-// Publish func writes a message to the transmitted topic.
-func (n *Notifier) Publish(topic string, message interface{}) {
+// LimitHistory sets the number of topics, DefaultHistoryTopics by default, whose sequence numbers, kept messages
+// and retained message are remembered. The least recently published topics beyond it are forgotten, and their
+// numbering starts again. Zero keeps every topic.
+func (n *Notifier) LimitHistory(topics int) {
+	n.history.limit(topics)
+}
+
+// Publish func writes a message to the transmitted topic. The trace context of ctx is sent along with the message.
+func (n *Notifier) Publish(ctx context.Context, topic string, message interface{}) {
+	n.publish(ctx, topic, message, &PublishOptions{})
+}
+
+// PublishOnce publishes the message like Publish unless a message with the same ID was published
+// to the topic within the dedup window. It reports whether the message was published.
+// Messages without an ID are always published.
+func (n *Notifier) PublishOnce(ctx context.Context, topic, id string, message interface{}) bool {
+	return n.PublishWith(ctx, topic, message, &PublishOptions{ID: id})
+}
+
+// PublishWith publishes the message like Publish with the options. It reports whether the message
+// was published or scheduled, that is, it is not a duplicate. Scheduled messages are kept in memory,
+// so they are lost on restart.
+func (n *Notifier) PublishWith(ctx context.Context, topic string, message interface{}, opts *PublishOptions) bool {
+	if opts.ID != "" && !n.published.add(topic+"\x00"+opts.ID, time.Now()) {
+		n.observer.Duplicated(topic)
+
+		return false
+	}
+
+	if opts.DeliverAt.After(time.Now()) {
+		n.scheduler.add(&scheduled{
+			at:           opts.DeliverAt,
+			topic:        topic,
+			message:      message,
+			traceContext: tracing.Inject(ctx),
+			opts:         *opts,
+		})
+
+		return true
+	}
+
+	n.publish(ctx, topic, message, opts)
+
+	return true
+}
+
+// publishScheduled publishes the scheduled message within the trace of its publisher.
+func (n *Notifier) publishScheduled(item *scheduled) {
+	n.publish(tracing.Extract(context.Background(), item.traceContext), item.topic, item.message, &item.opts)
+}
+
+// publish delivers the message to the subscribers of the topic and returns their number. Messages to inboxes
+// are passed to the inbox only, so they are neither kept nor counted per topic.
+func (n *Notifier) publish(ctx context.Context, topic string, message interface{}, opts *PublishOptions) int {
+	published := time.Now()
+	envelope := Envelope{
+		Topic:         topic,
+		Message:       message,
+		TraceContext:  tracing.Inject(ctx),
+		ReplyTo:       opts.ReplyTo,
+		CorrelationID: opts.CorrelationID,
+		Headers:       opts.Headers,
+	}
+
+	if opts.TTL > 0 {
+		envelope.Expires = published.Add(opts.TTL)
+	}
+
+	if IsInbox(topic) {
+		n.reply(envelope)
+
+		return 0
+	}
+
 	n.mutex.RLock()
 	defer n.mutex.RUnlock()
 
-	for _, channel := range n.subs[topic] {
-		go func(channel chan interface{}) {
-			channel <- message
-		}(channel)
+	// The message is numbered and retained while subscribing is locked, so a subscriber either receives it
+	// or finds it in the history and as the retained message.
+	envelope = n.history.add(topic, envelope, opts.Retain)
+	// The message is decoded for filters once, when the first filter needs it.
+	var decoded *filter.Message
+	var subs []*subscription
+	for _, sub := range n.subs[topic] {
+		if sub.accepts(envelope, &decoded) {
+			subs = append(subs, sub)
+		}
+	}
+
+	for _, sub := range n.patterns {
+		if sub.match(topic) && sub.accepts(envelope, &decoded) {
+			subs = append(subs, sub)
+		}
+	}
+
+	n.observer.Published(topic, len(subs))
+	for _, sub := range subs {
+		n.enqueue(sub, envelope, published)
 	}
+
+	return len(subs)
 }
 
-// Subscribe func adds a new subscriber to the transmitted topic.
-func (n *Notifier) Subscribe(topic string) chan interface{} {
+// enqueue queues the message for the subscription and starts draining the queue unless it is drained already.
+// It is called with the mutex of the notifier held, so messages are queued in the order they are numbered.
+func (n *Notifier) enqueue(sub *subscription, envelope Envelope, published time.Time) {
+	atomic.AddInt64(&n.pending, 1)
+
+	sub.queueMutex.Lock()
+	sub.queue = append(sub.queue, delivery{envelope: envelope, published: published})
+	start := !sub.draining
+	sub.draining = true
+	sub.queueMutex.Unlock()
+
+	if start {
+		go n.drain(sub)
+	}
+}
+
+// drain delivers the queued messages of the subscription in order and returns when the queue is empty.
+func (n *Notifier) drain(sub *subscription) {
+	for {
+		sub.queueMutex.Lock()
+		if len(sub.queue) == 0 {
+			sub.draining = false
+			sub.queueMutex.Unlock()
+
+			return
+		}
+
+		next := sub.queue[0]
+		sub.queue[0] = delivery{}
+		sub.queue = sub.queue[1:]
+		sub.queueMutex.Unlock()
+
+		n.deliver(sub, next.envelope, next.published)
+	}
+}
+
+// deliver passes the message to the subscriber. The delivery timeout runs from the publishing of the message,
+// so the time it waited in the queue counts.
+func (n *Notifier) deliver(sub *subscription, envelope Envelope, published time.Time) {
+	defer atomic.AddInt64(&n.pending, -1)
+
+	topic := envelope.Topic
+	var timeout <-chan time.Time
+	if n.deliveryTimeout > 0 {
+		timer := time.NewTimer(n.deliveryTimeout - time.Since(published))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var expiry <-chan time.Time
+	if !envelope.Expires.IsZero() {
+		timer := time.NewTimer(time.Until(envelope.Expires))
+		defer timer.Stop()
+		expiry = timer.C
+	}
+
+	select {
+	case sub.channel <- envelope:
+		n.observer.Delivered(topic, time.Since(published))
+	case <-sub.done:
+		n.observer.Dropped(topic)
+	case <-timeout:
+		n.observer.Dropped(topic)
+	case <-expiry:
+		n.observer.Dropped(topic)
+		n.observer.Expired(topic)
+	}
+}
+
+// Expired reports whether the TTL of the received message has expired and counts the expiration.
+// Subscribers that hold messages before passing them on check it to drop expired messages.
+func (n *Notifier) Expired(envelope Envelope) bool {
+	if !envelope.expired(time.Now()) {
+		return false
+	}
+
+	n.observer.Expired(envelope.Topic)
+
+	return true
+}
+
+// Subscribe func adds a new subscriber to the transmitted topic. The retained message of the topic,
+// if any, is waiting in the channel.
+func (n *Notifier) Subscribe(topic string) chan Envelope {
+	return n.SubscribeFilter(topic, nil)
+}
+
+// SubscribeFilter adds a new subscriber to the topic like Subscribe that receives only the messages
+// matching the filter. A nil filter matches every message. The channel is removed by Unsubscribe.
+func (n *Notifier) SubscribeFilter(topic string, f *filter.Filter) chan Envelope {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	sub := &subscription{filter: f, done: make(chan struct{})}
+	sub.channel = n.retainedChannel(func(t string) bool { return t == topic }, sub)
+	n.subs[topic] = append(n.subs[topic], sub)
+	n.observer.Subscribed(topic)
+
+	return sub.channel
+}
+
+// Unsubscribe func removes the subscriber channel from the transmitted topic.
+// Messages that are still waiting for this subscriber are dropped.
+func (n *Notifier) Unsubscribe(topic string, channel chan Envelope) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	subs := n.subs[topic]
+	for i, sub := range subs {
+		if sub.channel != channel {
+			continue
+		}
+
+		close(sub.done)
+		n.subs[topic] = append(subs[:i], subs[i+1:]...)
+		if len(n.subs[topic]) == 0 {
+			delete(n.subs, topic)
+		}
+
+		n.observer.Unsubscribed(topic)
+
+		return
+	}
+}
+
+// SubscribePattern adds a new subscriber to the topics matching the pattern, where "*" matches any characters.
+func (n *Notifier) SubscribePattern(pattern string) chan Envelope {
+	return n.SubscribeMatch(pattern, func(topic string) bool {
+		return auth.Match(pattern, topic)
+	})
+}
+
+// SubscribeMatch adds a new subscriber to the topics the match function reports, for patterns of other syntaxes.
+// The pattern only names the subscription. The subscriber channel is removed by UnsubscribePattern.
+// The retained messages of the matching topics are waiting in the channel.
+func (n *Notifier) SubscribeMatch(pattern string, match func(topic string) bool) chan Envelope {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	sub := &subscription{pattern: pattern, match: match, done: make(chan struct{})}
+	sub.channel = n.retainedChannel(match, sub)
+	n.patterns = append(n.patterns, sub)
+	n.observer.Subscribed(pattern)
+
+	return sub.channel
+}
+
+// UnsubscribePattern removes the subscriber channel of SubscribePattern or SubscribeMatch.
+// Messages that are still waiting for this subscriber are dropped.
+func (n *Notifier) UnsubscribePattern(channel chan Envelope) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
-	channel := make(chan interface{}, 1)
-	n.subs[topic] = append(n.subs[topic], channel)
+	for i, sub := range n.patterns {
+		if sub.channel != channel {
+			continue
+		}
+
+		close(sub.done)
+		n.patterns = append(n.patterns[:i], n.patterns[i+1:]...)
+		n.observer.Unsubscribed(sub.pattern)
+
+		return
+	}
+}
+
+// retainedChannel returns a new subscriber channel with the retained messages of the matching topics.
+// The channel has room for all of them, so they do not wait for a delivery.
+func (n *Notifier) retainedChannel(match func(topic string) bool, sub *subscription) chan Envelope {
+	var retained []Envelope
+	for _, envelope := range n.history.retained(match) {
+		var decoded *filter.Message
+		if sub.accepts(envelope, &decoded) {
+			retained = append(retained, envelope)
+		}
+	}
+
+	capacity := len(retained)
+	if capacity == 0 {
+		capacity = 1
+	}
+
+	channel := make(chan Envelope, capacity)
+	for _, envelope := range retained {
+		channel <- envelope
+	}
 
 	return channel
 }
+
+// accepts reports whether the envelope matches the filter of the subscription. The message the filter
+// is evaluated on is built on the first call and shared by the next ones.
+func (sub *subscription) accepts(envelope Envelope, message **filter.Message) bool {
+	if sub.filter == nil {
+		return true
+	}
+
+	if *message == nil {
+		*message = &filter.Message{
+			Headers: map[string]interface{}{
+				"$topic":         envelope.Topic,
+				"$seq":           float64(envelope.Seq),
+				"$retained":      envelope.Retained,
+				"$replyTo":       envelope.ReplyTo,
+				"$correlationId": envelope.CorrelationID,
+			},
+			Body: filter.Decode(envelope.Message),
+		}
+		for name, value := range envelope.Headers {
+			(*message).Headers[filter.PublisherHeader+name] = value
+		}
+	}
+
+	return sub.filter.Match(*message)
+}
+
+// History returns the kept messages of the topic published after the message numbered seq
+// and the number of the last message of the topic. Subscribers resume without missing messages
+// by subscribing first and then reading the history.
+func (n *Notifier) History(topic string, seq uint64) ([]Envelope, uint64) {
+	return n.history.after(topic, seq)
+}
+
+// Stats returns the number of topics, subscribers and messages waiting to be delivered.
+func (n *Notifier) Stats() Stats {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	stats := Stats{
+		Topics:      len(n.subs),
+		Subscribers: len(n.patterns),
+		Pending:     atomic.LoadInt64(&n.pending),
+		Scheduled:   n.scheduler.len(),
+	}
+	for _, subs := range n.subs {
+		stats.Subscribers += len(subs)
+	}
+
+	return stats
+}
+
+// nopObserver ignores all notifier events.
+type nopObserver struct{}
+
+func (nopObserver) Published(string, int)           {}
+func (nopObserver) Subscribed(string)               {}
+func (nopObserver) Unsubscribed(string)             {}
+func (nopObserver) Delivered(string, time.Duration) {}
+func (nopObserver) Dropped(string)                  {}
+func (nopObserver) Duplicated(string)               {}
+func (nopObserver) Expired(string)                  {}
@@ -2,8 +2,14 @@
 package service_test
 
 import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ivyoverflow/pub-sub/notifier/internal/filter"
 	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
 )
 
@@ -40,12 +46,519 @@ func TestNotifier_service(t *testing.T) {
 		},
 	}
 
-	svc := service.NewNotifier()
+	svc := service.NewNotifier(0, 0, 0, nil)
 	for _, testCase := range testCases {
 		message := svc.Subscribe(testCase.topic)
-		svc.Publish(testCase.topic, testCase.message)
-		if <-message != testCase.result {
+		svc.Publish(context.Background(), testCase.topic, testCase.message)
+		if envelope := <-message; envelope.Message != testCase.result {
 			t.Errorf("The message received does not match what was expected. Expected: %s", testCase.result)
 		}
 	}
 }
+
+func TestNotifier_stats(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	svc.Subscribe("news")
+	svc.Subscribe("news")
+	svc.Subscribe("games")
+
+	stats := svc.Stats()
+	if stats.Topics != 2 || stats.Subscribers != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	queue := service.QueueCheck(svc, 1)
+	if err := queue(context.Background()); err != nil {
+		t.Errorf("Queue check throws an error: %v", err)
+	}
+
+	// The first message fills the subscriber buffer, the second one stays pending.
+	svc.Publish(context.Background(), "games", "...")
+	svc.Publish(context.Background(), "games", "...")
+	for svc.Stats().Pending != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := queue(context.Background()); err == nil {
+		t.Errorf("Queue check must fail when the queue is saturated")
+	}
+
+	if err := service.QueueCheck(svc, 0)(context.Background()); err != nil {
+		t.Errorf("Queue check without limit throws an error: %v", err)
+	}
+
+	if err := service.SubscribersCheck(svc, 3)(context.Background()); err == nil {
+		t.Errorf("Subscribers check must fail when the limit is reached")
+	}
+
+	if err := service.SubscribersCheck(svc, 0)(context.Background()); err != nil {
+		t.Errorf("Subscribers check without limit throws an error: %v", err)
+	}
+}
+
+// observer counts notifier events.
+type observer struct {
+	mutex      sync.Mutex
+	published  int
+	delivered  int
+	dropped    int
+	duplicated int
+	expired    int
+}
+
+func (o *observer) Published(string, int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.published++
+}
+
+func (o *observer) Subscribed(string)   {}
+func (o *observer) Unsubscribed(string) {}
+
+func (o *observer) Delivered(string, time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.delivered++
+}
+
+func (o *observer) Dropped(string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.dropped++
+}
+
+func (o *observer) Duplicated(string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.duplicated++
+}
+
+func (o *observer) Expired(string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.expired++
+}
+
+func (o *observer) counts() (int, int, int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.published, o.delivered, o.dropped
+}
+
+func TestNotifier_observer(t *testing.T) {
+	obs := &observer{}
+	svc := service.NewNotifier(10*time.Millisecond, 0, 0, obs)
+	channel := svc.Subscribe("news")
+	gone := svc.Subscribe("news")
+
+	// The first message fills both subscriber buffers, the second one is received only by the first subscriber.
+	svc.Publish(context.Background(), "news", "first")
+	svc.Publish(context.Background(), "news", "second")
+	<-channel
+	<-channel
+	svc.Unsubscribe("news", gone)
+
+	for svc.Stats().Pending != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	published, delivered, dropped := obs.counts()
+	if published != 2 || delivered != 3 || dropped != 1 {
+		t.Errorf("Unexpected events: published %d, delivered %d, dropped %d", published, delivered, dropped)
+	}
+
+	if stats := svc.Stats(); stats.Topics != 1 || stats.Subscribers != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// The third message fills the subscriber buffer and nobody receives the fourth one,
+	// so it is dropped after the delivery timeout.
+	svc.Publish(context.Background(), "news", "third")
+	svc.Publish(context.Background(), "news", "fourth")
+	for svc.Stats().Pending != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, delivered, dropped := obs.counts(); delivered != 4 || dropped != 2 {
+		t.Errorf("The message must be dropped after the delivery timeout, delivered %d, dropped %d", delivered, dropped)
+	}
+}
+
+func TestNotifier_publishOnce(t *testing.T) {
+	testCases := []struct {
+		name      string
+		topic     string
+		id        string
+		published bool
+	}{
+		{name: "OK", topic: "news", id: "1", published: true},
+		{name: "Duplicate ID", topic: "news", id: "1", published: false},
+		{name: "Same ID in another topic", topic: "games", id: "1", published: true},
+		{name: "Another ID", topic: "news", id: "2", published: true},
+		{name: "Without ID", topic: "news", id: "", published: true},
+		{name: "Without ID again", topic: "news", id: "", published: true},
+	}
+
+	obs := &observer{}
+	svc := service.NewNotifier(0, 50*time.Millisecond, 0, obs)
+	for _, testCase := range testCases {
+		if published := svc.PublishOnce(context.Background(), testCase.topic, testCase.id, "..."); published != testCase.published {
+			t.Errorf("%s: expected published %t, got %t", testCase.name, testCase.published, published)
+		}
+	}
+
+	if obs.duplicated != 1 {
+		t.Errorf("Unexpected number of duplicates: %d", obs.duplicated)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !svc.PublishOnce(context.Background(), "news", "1", "...") {
+		t.Errorf("The message ID must be forgotten after the dedup window")
+	}
+
+	if !service.NewNotifier(0, 0, 0, nil).PublishOnce(context.Background(), "news", "1", "...") ||
+		!service.NewNotifier(0, 0, 0, nil).PublishOnce(context.Background(), "news", "1", "...") {
+		t.Errorf("A zero dedup window must not drop messages")
+	}
+}
+
+func TestNotifier_history(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 2, nil)
+	for _, message := range []string{"first", "second", "third"} {
+		svc.Publish(context.Background(), "news", message)
+	}
+
+	svc.Publish(context.Background(), "games", "first")
+
+	testCases := []struct {
+		name     string
+		topic    string
+		seq      uint64
+		expected []string
+		last     uint64
+	}{
+		{name: "Oldest messages are dropped", topic: "news", seq: 0, expected: []string{"second", "third"}, last: 3},
+		{name: "After a message", topic: "news", seq: 2, expected: []string{"third"}, last: 3},
+		{name: "After the last message", topic: "news", seq: 3, expected: nil, last: 3},
+		{name: "Before a restart", topic: "news", seq: 5, expected: []string{"second", "third"}, last: 3},
+		{name: "Topics are numbered separately", topic: "games", seq: 0, expected: []string{"first"}, last: 1},
+		{name: "Unknown topic", topic: "sports", seq: 0, expected: nil, last: 0},
+	}
+
+	for _, testCase := range testCases {
+		envelopes, last := svc.History(testCase.topic, testCase.seq)
+		var messages []string
+		for _, envelope := range envelopes {
+			messages = append(messages, envelope.Message.(string))
+		}
+
+		if !reflect.DeepEqual(messages, testCase.expected) || last != testCase.last {
+			t.Errorf("%s: expected %v and %d, got %v and %d", testCase.name, testCase.expected, testCase.last, messages, last)
+		}
+	}
+
+	channel := svc.Subscribe("news")
+	svc.Publish(context.Background(), "news", "fourth")
+	if envelope := <-channel; envelope.Seq != 4 {
+		t.Errorf("Delivered messages must be numbered like kept messages, got %d", envelope.Seq)
+	}
+}
+
+func TestNotifier_limitHistory(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 2, nil)
+	svc.LimitHistory(2)
+	for _, topic := range []string{"news", "games", "news", "sports"} {
+		svc.Publish(context.Background(), topic, topic)
+	}
+
+	// games is the least recently published topic, so it is forgotten first.
+	for topic, expected := range map[string]uint64{"news": 2, "games": 0, "sports": 1} {
+		if _, last := svc.History(topic, 0); last != expected {
+			t.Errorf("Expected the last message of %s to be %d, got %d", topic, expected, last)
+		}
+	}
+
+	svc.LimitHistory(1)
+	if _, last := svc.History("news", 0); last != 0 {
+		t.Errorf("Lowering the limit must forget topics, got %d", last)
+	}
+
+	if _, last := svc.History("sports", 0); last != 1 {
+		t.Errorf("Lowering the limit must keep the latest topics, got %d", last)
+	}
+}
+
+func TestNotifier_order(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	channel := svc.Subscribe("news")
+	defer svc.Unsubscribe("news", channel)
+
+	const messages = 1000
+	for i := 0; i < messages; i++ {
+		svc.Publish(context.Background(), "news", i)
+	}
+
+	for i := 1; i <= messages; i++ {
+		if envelope := <-channel; envelope.Seq != uint64(i) {
+			t.Fatalf("Expected the message %d, got %d", i, envelope.Seq)
+		}
+	}
+}
+
+func TestNotifier_subscribePattern(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	channel := svc.SubscribePattern("news.*")
+	if subscribers := svc.Stats().Subscribers; subscribers != 1 {
+		t.Errorf("Pattern subscribers must be counted, got %d", subscribers)
+	}
+
+	svc.Publish(context.Background(), "games", "skipped")
+	svc.Publish(context.Background(), "news.sports", "first")
+	if envelope := <-channel; envelope.Topic != "news.sports" || envelope.Message != "first" {
+		t.Errorf("Expected the first message of news.sports, got %+v", envelope)
+	}
+
+	svc.UnsubscribePattern(channel)
+	if subscribers := svc.Stats().Subscribers; subscribers != 0 {
+		t.Errorf("Unsubscribed pattern subscribers must not be counted, got %d", subscribers)
+	}
+}
+
+func TestNotifier_retained(t *testing.T) {
+	testCases := []struct {
+		name     string
+		messages []interface{}
+		retain   bool
+		expected interface{}
+	}{
+		{name: "Retained", messages: []interface{}{"open"}, retain: true, expected: "open"},
+		{name: "Replaced", messages: []interface{}{"open", "closed"}, retain: true, expected: "closed"},
+		{name: "Not retained", messages: []interface{}{"open"}, retain: false, expected: nil},
+		{name: "Removed by null", messages: []interface{}{"open", nil}, retain: true, expected: nil},
+		{name: "Removed by empty string", messages: []interface{}{"open", ""}, retain: true, expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		svc := service.NewNotifier(0, 0, 0, nil)
+		for _, message := range testCase.messages {
+			svc.PublishWith(context.Background(), "sensors.door", message, &service.PublishOptions{Retain: testCase.retain})
+		}
+
+		channel := svc.Subscribe("sensors.door")
+		select {
+		case envelope := <-channel:
+			if testCase.expected == nil || envelope.Message != testCase.expected || !envelope.Retained {
+				t.Errorf("%s: expected %v, got %+v", testCase.name, testCase.expected, envelope)
+			}
+		default:
+			if testCase.expected != nil {
+				t.Errorf("%s: expected %v, got nothing", testCase.name, testCase.expected)
+			}
+		}
+
+		svc.Unsubscribe("sensors.door", channel)
+	}
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	svc.PublishWith(context.Background(), "sensors.door", "open", &service.PublishOptions{Retain: true})
+	svc.PublishWith(context.Background(), "alerts", "fire", &service.PublishOptions{Retain: true})
+	channel := svc.SubscribePattern("sensors.*")
+	defer svc.UnsubscribePattern(channel)
+
+	if envelope := <-channel; envelope.Topic != "sensors.door" || !envelope.Retained {
+		t.Errorf("Pattern subscribers must receive the retained messages of matching topics, got %+v", envelope)
+	}
+
+	svc.Publish(context.Background(), "sensors.window", "open")
+	if envelope := <-channel; envelope.Topic != "sensors.window" || envelope.Retained {
+		t.Errorf("Published messages must follow the retained messages, got %+v", envelope)
+	}
+}
+
+func TestNotifier_ttl(t *testing.T) {
+	obs := &observer{}
+	svc := service.NewNotifier(0, 0, 10, obs)
+	ttl := &service.PublishOptions{TTL: 20 * time.Millisecond, Retain: true}
+	channel := svc.Subscribe("news")
+
+	// The first message fills the subscriber buffer, so the second one expires while waiting for a delivery.
+	for _, message := range []string{"first", "second"} {
+		svc.PublishWith(context.Background(), "news", message, ttl)
+		for svc.Stats().Pending != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	envelope := <-channel
+	if envelope.Message != "first" {
+		t.Errorf("Expected the first message, got %v", envelope.Message)
+	}
+
+	if !svc.Expired(envelope) {
+		t.Errorf("The first message must have expired in the subscriber buffer")
+	}
+
+	if _, _, dropped := obs.counts(); dropped != 1 || obs.expired != 2 {
+		t.Errorf("Unexpected events: dropped %d, expired %d", dropped, obs.expired)
+	}
+
+	if envelopes, last := svc.History("news", 0); len(envelopes) != 0 || last != 2 {
+		t.Errorf("Expired messages must not be replayed, got %d messages and %d", len(envelopes), last)
+	}
+
+	late := svc.Subscribe("news")
+	select {
+	case envelope = <-late:
+		t.Errorf("Expired retained messages must not be delivered, got %v", envelope.Message)
+	default:
+	}
+
+	svc.Publish(context.Background(), "news", "third")
+	if envelope = <-channel; svc.Expired(envelope) || !envelope.Expires.IsZero() {
+		t.Errorf("Messages without a TTL must not expire, got %+v", envelope)
+	}
+}
+
+func TestNotifier_deliverAt(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	channel := svc.Subscribe("news")
+	now := time.Now()
+
+	scheduled := []struct {
+		message string
+		delay   time.Duration
+	}{
+		{message: "third", delay: 60 * time.Millisecond},
+		{message: "first", delay: 20 * time.Millisecond},
+		{message: "second", delay: 40 * time.Millisecond},
+	}
+
+	for _, s := range scheduled {
+		svc.PublishWith(context.Background(), "news", s.message, &service.PublishOptions{DeliverAt: now.Add(s.delay)})
+	}
+
+	svc.PublishWith(context.Background(), "news", "now", &service.PublishOptions{DeliverAt: now.Add(-time.Second)})
+	if stats := svc.Stats(); stats.Scheduled != 3 {
+		t.Errorf("Expected 3 scheduled messages, got %d", stats.Scheduled)
+	}
+
+	for _, expected := range []string{"now", "first", "second", "third"} {
+		if envelope := <-channel; envelope.Message != expected {
+			t.Errorf("Expected %s, got %v", expected, envelope.Message)
+		}
+	}
+
+	if elapsed := time.Since(now); elapsed < 60*time.Millisecond {
+		t.Errorf("Scheduled messages must wait for their time, got them after %v", elapsed)
+	}
+
+	if stats := svc.Stats(); stats.Scheduled != 0 {
+		t.Errorf("Published messages must not be scheduled, got %d", stats.Scheduled)
+	}
+}
+
+func TestNotifier_request(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	channel := svc.Subscribe("books.price")
+	defer svc.Unsubscribe("books.price", channel)
+
+	watcher := svc.SubscribePattern("books.pr*")
+	defer svc.UnsubscribePattern(watcher)
+
+	go func() {
+		request := <-channel
+		svc.PublishWith(context.Background(), request.ReplyTo, "wrong", &service.PublishOptions{CorrelationID: "other"})
+		svc.PublishWith(context.Background(), request.ReplyTo, 42, &service.PublishOptions{CorrelationID: request.CorrelationID})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reply, err := svc.Request(ctx, "books.price", "1984", &service.PublishOptions{})
+	if err != nil || reply.Message != 42 || reply.CorrelationID == "" {
+		t.Errorf("Expected the reply with the correlation ID, got %+v and %v", reply, err)
+	}
+
+	if envelope := <-watcher; envelope.Topic != "books.price" || !service.IsInbox(envelope.ReplyTo) {
+		t.Errorf("Expected the request, got %+v", envelope)
+	}
+
+	select {
+	case envelope := <-watcher:
+		t.Errorf("Replies must be received only by their inbox, got %+v", envelope)
+	default:
+	}
+
+	if _, err = svc.Request(ctx, "books.stock", "1984", &service.PublishOptions{}); err != service.ErrNoResponders {
+		t.Errorf("Expected ErrNoResponders, got %v", err)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+
+	if _, err = svc.Request(short, "books.price", "1984", &service.PublishOptions{}); err != service.ErrNoReply {
+		t.Errorf("Expected ErrNoReply, got %v", err)
+	}
+}
+
+func TestNotifier_inbox(t *testing.T) {
+	svc := service.NewNotifier(0, 0, 0, nil)
+	inbox := svc.NewInbox("", 20*time.Millisecond)
+	svc.Publish(context.Background(), inbox.Topic, "first")
+	svc.Publish(context.Background(), inbox.Topic, "second")
+	if envelope := <-inbox.C; envelope.Message != "first" {
+		t.Errorf("Expected the first reply, got %v", envelope.Message)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	svc.Publish(context.Background(), inbox.Topic, "late")
+	select {
+	case envelope := <-inbox.C:
+		t.Errorf("Expired inboxes must not receive replies, got %v", envelope.Message)
+	default:
+	}
+}
+
+func TestNotifier_subscribeFilter(t *testing.T) {
+	cheap, err := filter.Compile(`price < 20 && inStock`)
+	if err != nil {
+		t.Fatalf("Filter compilation throws an error: %v", err)
+	}
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	svc.PublishWith(context.Background(), "books", map[string]interface{}{"price": 30, "inStock": true},
+		&service.PublishOptions{Retain: true})
+	channel := svc.SubscribeFilter("books", cheap)
+	defer svc.Unsubscribe("books", channel)
+
+	select {
+	case envelope := <-channel:
+		t.Errorf("Retained messages that do not match must not be delivered, got %v", envelope.Message)
+	default:
+	}
+
+	messages := []interface{}{
+		map[string]interface{}{"price": 10, "inStock": false},
+		"not JSON",
+		json.RawMessage(`{"price":12.5,"inStock":true}`),
+	}
+
+	for _, message := range messages {
+		svc.Publish(context.Background(), "books", message)
+	}
+
+	if envelope := <-channel; !reflect.DeepEqual(envelope.Message, messages[2]) {
+		t.Errorf("Expected the matching message, got %v", envelope.Message)
+	}
+
+	for svc.Stats().Pending != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case envelope := <-channel:
+		t.Errorf("Messages that do not match must not be delivered, got %v", envelope.Message)
+	default:
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// scheduled is a message that is published at a later time.
+type scheduled struct {
+	at           time.Time
+	topic        string
+	message      interface{}
+	traceContext map[string]string
+	opts         PublishOptions
+}
+
+// schedule is a min-heap of scheduled messages ordered by their publishing time.
+type schedule []*scheduled
+
+func (s schedule) Len() int            { return len(s) }
+func (s schedule) Less(i, j int) bool  { return s[i].at.Before(s[j].at) }
+func (s schedule) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *schedule) Push(x interface{}) { *s = append(*s, x.(*scheduled)) }
+
+func (s *schedule) Pop() interface{} {
+	old := *s
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*s = old[:len(old)-1]
+
+	return item
+}
+
+// scheduler holds delayed messages and passes every one of them to publish at its time.
+// A single timer is set to the earliest message, so waiting messages cost no goroutines.
+type scheduler struct {
+	publish func(*scheduled)
+	mutex   sync.Mutex
+	queue   schedule
+	timer   *time.Timer
+}
+
+func newScheduler(publish func(*scheduled)) *scheduler {
+	return &scheduler{publish: publish}
+}
+
+// add schedules the message and moves the timer if the message is the earliest one.
+func (s *scheduler) add(item *scheduled) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	heap.Push(&s.queue, item)
+	if s.queue[0] != item {
+		return
+	}
+
+	delay := time.Until(item.at)
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, s.fire)
+
+		return
+	}
+
+	s.timer.Stop()
+	s.timer.Reset(delay)
+}
+
+// fire publishes the messages whose time has come and sets the timer to the next one.
+func (s *scheduler) fire() {
+	s.mutex.Lock()
+	var due []*scheduled
+	now := time.Now()
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		due = append(due, heap.Pop(&s.queue).(*scheduled))
+	}
+
+	if len(s.queue) > 0 {
+		s.timer.Reset(time.Until(s.queue[0].at))
+	}
+	s.mutex.Unlock()
+
+	for _, item := range due {
+		s.publish(item)
+	}
+}
+
+// len returns the number of waiting messages.
+func (s *scheduler) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.queue)
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+// Defines the headers of posted messages. The signature is "sha256=" followed by the hex HMAC-SHA256
+// of the timestamp, a dot and the body, keyed with the secret of the webhook. The delivery ID
+// is the same for every attempt to post a message.
+const (
+	SignatureHeader = "Webhook-Signature"
+	TimestampHeader = "Webhook-Timestamp"
+	DeliveryHeader  = "Webhook-ID"
+)
+
+// tracerName is the name of the tracer that records webhook deliveries.
+const tracerName = "github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+
+// maxResponseBytes is the size of the part of a response body that is read before the connection is reused.
+const maxResponseBytes = 64 << 10
+
+// job is an attempt to post a message to a webhook subscribed by the subscription stopped by stop.
+type job struct {
+	hook     *hook
+	stop     chan struct{}
+	envelope service.Envelope
+	id       string
+	attempt  int
+}
+
+// deliver posts the queued messages of the webhook in order until it is stopped. A message is retried
+// until it is posted or its attempts run out before the next one is posted, so receivers get them in order.
+func (m *Manager) deliver(h *hook, queue chan service.Envelope, stop chan struct{}) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case envelope := <-queue:
+			j := &job{hook: h, stop: stop, envelope: envelope, id: newID(), attempt: 1}
+			for m.attempt(j) {
+				if !m.wait(m.backoff(j.attempt), stop) {
+					return
+				}
+
+				j.attempt++
+			}
+		}
+	}
+}
+
+// wait waits for the delay unless stop or the Manager is closed first. It reports whether the delay passed.
+func (m *Manager) wait(delay time.Duration, stop chan struct{}) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	case <-m.closed:
+		return false
+	}
+}
+
+// attempt posts the message of the job when a worker is free and reports whether the attempt failed
+// and may be retried. Messages whose TTL expired while waiting for an attempt are dropped.
+func (m *Manager) attempt(j *job) bool {
+	if !m.active(j.hook, j.stop) {
+		return false
+	}
+
+	if m.svc.Expired(j.envelope) {
+		m.log.Info("Webhook delivery expired", zap.String("webhook", j.hook.ID),
+			zap.String("delivery", j.id), zap.Int("attempt", j.attempt))
+
+		return false
+	}
+
+	select {
+	case m.workers <- struct{}{}:
+	case <-j.stop:
+		return false
+	case <-m.closed:
+		return false
+	}
+
+	delivery := &model.Delivery{
+		ID:      j.id,
+		Topic:   j.envelope.Topic,
+		Seq:     j.envelope.Seq,
+		Attempt: j.attempt,
+		Time:    time.Now().UTC(),
+	}
+
+	statusCode, err := m.post(j)
+	<-m.workers
+	delivery.Duration = time.Since(delivery.Time).Seconds()
+	delivery.StatusCode = statusCode
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	succeeded := err == nil && statusCode >= 200 && statusCode < 300
+	retry := !succeeded && j.attempt < m.cfg.MaxAttempts && retryable(statusCode)
+	m.record(j.hook, delivery, succeeded, !retry)
+	if !succeeded && !retry {
+		m.log.Info("Webhook delivery failed", zap.String("webhook", j.hook.ID),
+			zap.String("delivery", j.id), zap.Int("status_code", statusCode), zap.String("error", delivery.Error))
+	}
+
+	return retry
+}
+
+// post sends the message of the job to the webhook within a span that continues the publisher's trace.
+// It returns the status code of the response, or zero if there is none.
+func (m *Manager) post(j *job) (int, error) {
+	ctx := tracing.Extract(context.Background(), j.envelope.TraceContext)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "webhook deliver",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "webhook"),
+			attribute.String("messaging.destination", j.envelope.Topic),
+			attribute.Int("webhook.attempt", j.attempt),
+		))
+
+	statusCode, err := m.send(ctx, j)
+	tracing.End(span, err)
+
+	return statusCode, err
+}
+
+func (m *Manager) send(ctx context.Context, j *job) (int, error) {
+	body, err := json.Marshal(&model.Frame{
+		Type:          model.FrameMessage,
+		ID:            j.hook.ID,
+		Topic:         j.envelope.Topic,
+		Seq:           j.envelope.Seq,
+		Message:       j.envelope.Message,
+		Retained:      j.envelope.Retained,
+		ReplyTo:       j.envelope.ReplyTo,
+		CorrelationID: j.envelope.CorrelationID,
+		Headers:       j.envelope.Headers,
+		TraceContext:  tracing.Inject(ctx),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set(DeliveryHeader, j.id)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(j.hook.secret, timestamp, body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the retry of the attempt.
+func (m *Manager) backoff(attempt int) time.Duration {
+	delay := m.cfg.Backoff
+	for i := 1; i < attempt && delay < m.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > m.cfg.MaxBackoff {
+		return m.cfg.MaxBackoff
+	}
+
+	return delay
+}
+
+// retryable reports whether an attempt that failed with the status code may succeed later.
+// Requests that failed without a response are retried.
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode >= http.StatusInternalServerError ||
+		statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// Sign returns the signature of a message posted at the Unix timestamp with the body.
+// Receivers compute it with their copy of the secret and compare it with the signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
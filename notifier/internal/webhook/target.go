@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// privateNetworks are the networks of addresses that are not reachable from the internet.
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// allowed reports whether webhooks may be posted to the address: public addresses and the addresses
+// of the allowed networks are, while loopback, private, link-local, unspecified and multicast ones are not.
+func (c *Config) allowed(ip net.IP) bool {
+	for _, network := range c.AllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkHost rejects the hosts of webhook URLs that are forbidden addresses or localhost names. Other names are
+// resolved when the messages are posted, and the transport of NewTransport checks the addresses they resolve to.
+func (c *Config) checkHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !c.allowed(ip) {
+			return ErrForbiddenURL
+		}
+
+		return nil
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if !c.allowed(net.IPv4(127, 0, 0, 1)) {
+			return ErrForbiddenURL
+		}
+	}
+
+	return nil
+}
+
+// NewTransport returns a transport that connects only to the addresses webhooks may be posted to, checked after
+// the host name is resolved, so that names resolving to internal addresses cannot reach them either.
+// It does not use proxies, which would connect on its behalf.
+func NewTransport(cfg *Config) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !cfg.allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenURL, host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return transport
+}
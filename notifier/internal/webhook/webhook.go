@@ -0,0 +1,343 @@
+// Package webhook posts the messages published to the notifier to webhook subscriptions.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// minSecretLength is the length of the shortest secret that signs deliveries.
+const minSecretLength = 16
+
+var (
+	// ErrNotFound is returned when the webhook does not exist or belongs to another owner.
+	ErrNotFound = errors.New("webhook is not found")
+	// ErrTopicRequired is returned when a webhook is created without a topic.
+	ErrTopicRequired = errors.New("topic is required")
+	// ErrInvalidURL is returned when the URL of a webhook is not an absolute HTTP or HTTPS URL.
+	ErrInvalidURL = errors.New("url must be an absolute http or https URL")
+	// ErrSecretTooShort is returned when the secret of a webhook is shorter than 16 characters.
+	ErrSecretTooShort = errors.New("secret must be at least 16 characters long")
+	// ErrForbiddenURL is returned when the URL of a webhook targets a loopback, private or link-local address
+	// outside the allowed networks.
+	ErrForbiddenURL = errors.New("url must not target a loopback, private or link-local address")
+)
+
+// Config configures the delivery of messages to webhooks.
+type Config struct {
+	// Workers is the number of deliveries posted at the same time to all webhooks.
+	Workers int
+	// QueueSize is the number of messages of every webhook waiting for the delivery of the previous ones.
+	// Messages wait for the notifier delivery timeout while the queue is full.
+	QueueSize int
+	// Timeout limits every attempt to post a message.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts to post a message. Failed attempts are retried after Backoff,
+	// which doubles with every retry up to MaxBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// DisableAfter is the number of messages in a row that could not be posted at which a webhook is disabled.
+	DisableAfter int
+	// LogSize is the number of the latest delivery attempts kept for every webhook.
+	LogSize int
+	// AllowedNetworks are internal networks that webhooks may target, like the networks of internal receivers.
+	// Loopback, private and link-local addresses outside them are refused.
+	AllowedNetworks []*net.IPNet
+}
+
+// hook is a webhook and the subscription that feeds it. The fields are guarded by the mutex of the Manager.
+type hook struct {
+	model.Webhook
+	owner      string
+	secret     string
+	channel    chan service.Envelope
+	stop       chan struct{}
+	deliveries []model.Delivery
+}
+
+// Manager keeps the webhooks of every owner in memory and posts the messages of their topics. Every webhook
+// has a queue whose messages are posted one at a time in the order they were published, retries included,
+// and up to Workers messages of different webhooks are posted at the same time.
+type Manager struct {
+	svc    *service.Notifier
+	cfg    *Config
+	client *http.Client
+	log    *logger.Logger
+	mutex  sync.Mutex
+	hooks  map[string]*hook
+	// workers holds a token for every message being posted.
+	workers chan struct{}
+	closed  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New returns a new Manager object. The client posts the messages, its timeout is replaced by the timeout of cfg.
+// Its transport should be the one of NewTransport, which refuses the addresses outside the allowed networks
+// that the names of webhook URLs resolve to.
+func New(svc *service.Notifier, cfg *Config, client *http.Client, log *logger.Logger) *Manager {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Manager{
+		svc:     svc,
+		cfg:     cfg,
+		client:  client,
+		log:     log,
+		hooks:   make(map[string]*hook),
+		workers: make(chan struct{}, workers),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Create adds a webhook of the owner for the topic pattern and starts posting its messages.
+func (m *Manager) Create(owner string, request *model.WebhookRequest) (model.Webhook, error) {
+	if request.Topic == "" {
+		return model.Webhook{}, ErrTopicRequired
+	}
+
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return model.Webhook{}, ErrInvalidURL
+	}
+
+	if err = m.cfg.checkHost(target.Hostname()); err != nil {
+		return model.Webhook{}, err
+	}
+
+	if len(request.Secret) < minSecretLength {
+		return model.Webhook{}, ErrSecretTooShort
+	}
+
+	h := &hook{
+		Webhook: model.Webhook{
+			ID:        newID(),
+			Topic:     request.Topic,
+			URL:       target.String(),
+			CreatedAt: time.Now().UTC(),
+		},
+		owner:  owner,
+		secret: request.Secret,
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hooks[h.ID] = h
+	m.enable(h)
+
+	return h.Webhook, nil
+}
+
+// List returns the webhooks of the owner from the oldest to the newest.
+func (m *Manager) List(owner string) []model.Webhook {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	webhooks := []model.Webhook{}
+	for _, h := range m.hooks {
+		if h.owner == owner {
+			webhooks = append(webhooks, h.Webhook)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+
+	return webhooks
+}
+
+// Get returns the webhook of the owner with the ID.
+func (m *Manager) Get(owner, id string) (model.Webhook, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.hooks[id]
+	if !ok || h.owner != owner {
+		return model.Webhook{}, ErrNotFound
+	}
+
+	return h.Webhook, nil
+}
+
+// Delete removes the webhook of the owner with the ID. Deliveries in progress are dropped.
+func (m *Manager) Delete(owner, id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.hooks[id]
+	if !ok || h.owner != owner {
+		return ErrNotFound
+	}
+
+	m.disable(h)
+	delete(m.hooks, id)
+
+	return nil
+}
+
+// Enable resumes posting the messages of a disabled webhook of the owner and resets its failures.
+func (m *Manager) Enable(owner, id string) (model.Webhook, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.hooks[id]
+	if !ok || h.owner != owner {
+		return model.Webhook{}, ErrNotFound
+	}
+
+	h.Failures = 0
+	m.enable(h)
+
+	return h.Webhook, nil
+}
+
+// Deliveries returns the latest delivery attempts of the webhook of the owner, the newest first.
+func (m *Manager) Deliveries(owner, id string) ([]model.Delivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.hooks[id]
+	if !ok || h.owner != owner {
+		return nil, ErrNotFound
+	}
+
+	deliveries := make([]model.Delivery, 0, len(h.deliveries))
+	for i := len(h.deliveries) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, h.deliveries[i])
+	}
+
+	return deliveries, nil
+}
+
+// Shutdown stops posting messages and waits until the attempts in progress finish or ctx is done.
+// Queued deliveries are dropped.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mutex.Lock()
+	select {
+	case <-m.closed:
+	default:
+		close(m.closed)
+	}
+
+	for _, h := range m.hooks {
+		m.disable(h)
+	}
+	m.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enable subscribes the webhook to its topic unless it is subscribed. The mutex must be held.
+func (m *Manager) enable(h *hook) {
+	if h.Enabled {
+		return
+	}
+
+	h.Enabled = true
+	h.channel = m.svc.SubscribePattern(h.Topic)
+	h.stop = make(chan struct{})
+	queue := make(chan service.Envelope, m.cfg.QueueSize)
+	m.wg.Add(2)
+	go m.forward(h.channel, queue, h.stop)
+	go m.deliver(h, queue, h.stop)
+}
+
+// disable unsubscribes the webhook from its topic. The mutex must be held.
+func (m *Manager) disable(h *hook) {
+	if !h.Enabled {
+		return
+	}
+
+	h.Enabled = false
+	m.svc.UnsubscribePattern(h.channel)
+	close(h.stop)
+}
+
+// forward queues the messages of the subscription of a webhook until it is stopped. It waits for room
+// in the queue, so the notifier drops the messages that wait longer than its delivery timeout.
+func (m *Manager) forward(channel chan service.Envelope, queue chan service.Envelope, stop chan struct{}) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case envelope := <-channel:
+			select {
+			case queue <- envelope:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// record keeps the attempt in the log of the webhook and updates its failures after the last attempt
+// of a message. The webhook is disabled after DisableAfter messages in a row could not be posted.
+func (m *Manager) record(h *hook, delivery *model.Delivery, succeeded, last bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cfg.LogSize > 0 {
+		if len(h.deliveries) == m.cfg.LogSize {
+			h.deliveries = append(h.deliveries[:0], h.deliveries[1:]...)
+		}
+
+		h.deliveries = append(h.deliveries, *delivery)
+	}
+
+	switch {
+	case succeeded:
+		h.Failures = 0
+	case last:
+		h.Failures++
+		if m.cfg.DisableAfter > 0 && h.Failures >= m.cfg.DisableAfter && h.Enabled {
+			m.log.Warn("Webhook disabled after repeated failures",
+				zap.String("webhook", h.ID), zap.String("url", h.URL), zap.Int("failures", h.Failures))
+			m.disable(h)
+		}
+	}
+}
+
+// active reports whether the webhook still takes deliveries of the subscription stopped by stop.
+func (m *Manager) active(h *hook, stop chan struct{}) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return h.Enabled && h.stop == stop
+}
+
+// newID returns a random 128-bit hex ID.
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
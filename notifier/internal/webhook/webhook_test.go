@@ -0,0 +1,250 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ivyoverflow/pub-sub/notifier/internal/model"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/service"
+	"github.com/ivyoverflow/pub-sub/notifier/internal/webhook"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+const secret = "0123456789abcdef"
+
+// target is a webhook endpoint that answers with the queued status codes, then with 200.
+type target struct {
+	mutex    sync.Mutex
+	statuses []int
+	received chan *http.Request
+	bodies   chan []byte
+}
+
+func (tg *target) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	tg.mutex.Lock()
+	status := http.StatusOK
+	if len(tg.statuses) > 0 {
+		status, tg.statuses = tg.statuses[0], tg.statuses[1:]
+	}
+	tg.mutex.Unlock()
+
+	rw.WriteHeader(status)
+	tg.received <- r
+	tg.bodies <- body
+}
+
+func newManager(t *testing.T, cfg *webhook.Config, statuses ...int) (*webhook.Manager, *service.Notifier, *target, string) {
+	t.Helper()
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	tg := &target{statuses: statuses, received: make(chan *http.Request, 10), bodies: make(chan []byte, 10)}
+	srv := httptest.NewServer(tg)
+	t.Cleanup(srv.Close)
+
+	svc := service.NewNotifier(0, 0, 0, nil)
+	manager := webhook.New(svc, cfg, srv.Client(), log)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	return manager, svc, tg, srv.URL
+}
+
+func testConfig() *webhook.Config {
+	return &webhook.Config{
+		Workers:      2,
+		QueueSize:    10,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		Backoff:      time.Millisecond,
+		MaxBackoff:   10 * time.Millisecond,
+		DisableAfter: 2,
+		LogSize:      10,
+		// The test targets listen on the loopback network.
+		AllowedNetworks: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}},
+	}
+}
+
+func TestManager_create(t *testing.T) {
+	testCases := []struct {
+		name     string
+		request  model.WebhookRequest
+		expected error
+	}{
+		{name: "OK", request: model.WebhookRequest{Topic: "news", URL: "https://example.com/hooks", Secret: secret}},
+		{name: "Topic is missing", request: model.WebhookRequest{URL: "https://example.com", Secret: secret}, expected: webhook.ErrTopicRequired},
+		{name: "Relative URL", request: model.WebhookRequest{Topic: "news", URL: "/hooks", Secret: secret}, expected: webhook.ErrInvalidURL},
+		{name: "Unsupported scheme", request: model.WebhookRequest{Topic: "news", URL: "ftp://example.com", Secret: secret}, expected: webhook.ErrInvalidURL},
+		{name: "Link-local address", request: model.WebhookRequest{Topic: "news", URL: "http://169.254.169.254/latest", Secret: secret},
+			expected: webhook.ErrForbiddenURL},
+		{name: "Private address", request: model.WebhookRequest{Topic: "news", URL: "http://10.0.0.1:8080", Secret: secret},
+			expected: webhook.ErrForbiddenURL},
+		{name: "Mapped private address", request: model.WebhookRequest{Topic: "news", URL: "http://[::ffff:192.168.0.1]", Secret: secret},
+			expected: webhook.ErrForbiddenURL},
+		{name: "Allowed loopback address", request: model.WebhookRequest{Topic: "news", URL: "http://127.0.0.1:8080", Secret: secret}},
+		{name: "Short secret", request: model.WebhookRequest{Topic: "news", URL: "https://example.com", Secret: "secret"}, expected: webhook.ErrSecretTooShort},
+	}
+
+	manager, _, _, _ := newManager(t, testConfig())
+	for _, testCase := range testCases {
+		testCase := testCase
+		hook, err := manager.Create("alice", &testCase.request)
+		assert.Equal(t, testCase.expected, err, testCase.name)
+		if err == nil {
+			assert.True(t, hook.Enabled, testCase.name)
+			assert.NotEmpty(t, hook.ID, testCase.name)
+		}
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	srv := httptest.NewServer(&target{received: make(chan *http.Request, 1), bodies: make(chan []byte, 1)})
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.AllowedNetworks = nil
+	client := &http.Client{Transport: webhook.NewTransport(cfg)}
+	_, err := client.Post(srv.URL, "application/json", nil)
+	assert.True(t, errors.Is(err, webhook.ErrForbiddenURL), "the loopback address is refused: %v", err)
+
+	manager, _, _, _ := newManager(t, cfg)
+	_, err = manager.Create("alice", &model.WebhookRequest{Topic: "news", URL: "http://localhost:8080", Secret: secret})
+	assert.Equal(t, webhook.ErrForbiddenURL, err)
+
+	client = &http.Client{Transport: webhook.NewTransport(testConfig())}
+	response, err := client.Post(srv.URL, "application/json", nil)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestManager_owner(t *testing.T) {
+	manager, _, _, _ := newManager(t, testConfig())
+	hook, err := manager.Create("alice", &model.WebhookRequest{Topic: "news", URL: "https://example.com", Secret: secret})
+	require.NoError(t, err)
+
+	assert.Len(t, manager.List("alice"), 1)
+	assert.Empty(t, manager.List("bob"))
+
+	_, err = manager.Get("bob", hook.ID)
+	assert.Equal(t, webhook.ErrNotFound, err)
+	assert.Equal(t, webhook.ErrNotFound, manager.Delete("bob", hook.ID))
+
+	require.NoError(t, manager.Delete("alice", hook.ID))
+	_, err = manager.Get("alice", hook.ID)
+	assert.Equal(t, webhook.ErrNotFound, err)
+}
+
+func TestManager_deliver(t *testing.T) {
+	manager, svc, tg, url := newManager(t, testConfig(), http.StatusServiceUnavailable)
+	hook, err := manager.Create("alice", &model.WebhookRequest{Topic: "news.*", URL: url, Secret: secret})
+	require.NoError(t, err)
+
+	svc.Publish(context.Background(), "news.sports", "first")
+
+	// The first attempt fails and is retried with the same delivery ID.
+	first, retry := <-tg.received, <-tg.received
+	<-tg.bodies
+	body := <-tg.bodies
+	assert.Equal(t, first.Header.Get(webhook.DeliveryHeader), retry.Header.Get(webhook.DeliveryHeader))
+	assert.Equal(t, webhook.Sign(secret, retry.Header.Get(webhook.TimestampHeader), body),
+		retry.Header.Get(webhook.SignatureHeader))
+
+	frame := model.Frame{}
+	require.NoError(t, json.Unmarshal(body, &frame))
+	assert.Equal(t, model.Frame{Type: model.FrameMessage, ID: hook.ID, Topic: "news.sports", Seq: 1, Message: "first"}, frame)
+
+	deliveries := waitDeliveries(t, manager, hook.ID, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+
+	hook, err = manager.Get("alice", hook.ID)
+	require.NoError(t, err)
+	assert.Zero(t, hook.Failures)
+}
+
+func TestManager_order(t *testing.T) {
+	manager, svc, tg, url := newManager(t, testConfig(), http.StatusServiceUnavailable)
+	_, err := manager.Create("alice", &model.WebhookRequest{Topic: "news", URL: url, Secret: secret})
+	require.NoError(t, err)
+
+	for _, message := range []string{"first", "second", "third"} {
+		svc.Publish(context.Background(), "news", message)
+	}
+
+	// The failed first message is retried before the next messages are posted.
+	var seqs []uint64
+	for i := 0; i < 4; i++ {
+		<-tg.received
+		frame := model.Frame{}
+		require.NoError(t, json.Unmarshal(<-tg.bodies, &frame))
+		seqs = append(seqs, frame.Seq)
+	}
+
+	assert.Equal(t, []uint64{1, 1, 2, 3}, seqs)
+}
+
+func TestManager_disable(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxAttempts = 1
+	manager, svc, tg, url := newManager(t, cfg, http.StatusInternalServerError, http.StatusBadRequest)
+	hook, err := manager.Create("alice", &model.WebhookRequest{Topic: "news", URL: url, Secret: secret})
+	require.NoError(t, err)
+
+	for _, message := range []string{"first", "second"} {
+		svc.Publish(context.Background(), "news", message)
+		<-tg.received
+		<-tg.bodies
+	}
+
+	waitDeliveries(t, manager, hook.ID, 2)
+	hook, err = manager.Get("alice", hook.ID)
+	require.NoError(t, err)
+	assert.False(t, hook.Enabled)
+	assert.Equal(t, 2, hook.Failures)
+	assert.Zero(t, svc.Stats().Subscribers)
+
+	hook, err = manager.Enable("alice", hook.ID)
+	require.NoError(t, err)
+	assert.True(t, hook.Enabled)
+	assert.Zero(t, hook.Failures)
+
+	svc.Publish(context.Background(), "news", "third")
+	<-tg.received
+	<-tg.bodies
+	assert.Equal(t, http.StatusOK, waitDeliveries(t, manager, hook.ID, 3)[0].StatusCode)
+}
+
+// waitDeliveries waits until the webhook has logged n delivery attempts and returns them.
+func waitDeliveries(t *testing.T, manager *webhook.Manager, id string, n int) []model.Delivery {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		deliveries, err := manager.Deliveries("alice", id)
+		require.NoError(t, err)
+		if len(deliveries) >= n {
+			return deliveries
+		}
+	}
+
+	t.Fatalf("Expected %d deliveries of webhook %s", n, id)
+
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package auth contains the logic to authenticate requests by API keys or JWT bearer tokens
+// and to authorize them by roles and topic permissions.
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Defines roles of the books API. Editors may do everything readers may.
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+)
+
+// RoleSchemaAdmin is the role of the notifier that manages the schemas of the topics the principal may publish to.
+const RoleSchemaAdmin = "schema-admin"
+
+// Anonymous is the subject of requests when authentication is disabled.
+const Anonymous = "anonymous"
+
+// Principal represents an authenticated client.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	// Publish and Subscribe are topic patterns the principal may publish and subscribe to.
+	// A "*" in a pattern matches any sequence of characters, so "news.*" matches "news.sport".
+	Publish   []string `json:"publish"`
+	Subscribe []string `json:"subscribe"`
+	// anonymous is set on the principal of requests when authentication is disabled, whatever the subjects
+	// of the other principals are.
+	anonymous bool
+}
+
+// anonymous returns the principal of requests when authentication is disabled. It is allowed to do everything.
+func anonymous() *Principal {
+	return &Principal{
+		Subject:   Anonymous,
+		Roles:     []string{RoleEditor, RoleSchemaAdmin},
+		Publish:   []string{"*"},
+		Subscribe: []string{"*"},
+		anonymous: true,
+	}
+}
+
+// HasRole reports whether the principal has the role. Editors have the reader role too.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || (r == RoleEditor && role == RoleReader) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanPublish reports whether the principal may publish to the topic.
+func (p *Principal) CanPublish(topic string) bool {
+	return matchAny(p.Publish, topic)
+}
+
+// CanSubscribe reports whether the principal may subscribe to the topic.
+func (p *Principal) CanSubscribe(topic string) bool {
+	return matchAny(p.Subscribe, topic)
+}
+
+// principalKey is the key of the principal in a context.
+type principalKey struct{}
+
+// NewContext returns a copy of ctx that carries the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by ctx. Requests without a principal must be denied.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+
+	return p, ok
+}
+
+func matchAny(patterns []string, topic string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match reports whether the topic matches the pattern, where "*" matches any sequence of characters.
+func Match(pattern, topic string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == topic
+	}
+
+	if !strings.HasPrefix(topic, parts[0]) {
+		return false
+	}
+
+	topic = topic[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(topic, part)
+		if i < 0 {
+			return false
+		}
+
+		topic = topic[i+len(part):]
+	}
+
+	return strings.HasSuffix(topic, last)
+}
@@ -0,0 +1,289 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+const secret = "hs256-secret"
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		topic    string
+		expected bool
+	}{
+		{pattern: "news", topic: "news", expected: true},
+		{pattern: "news", topic: "news.sport", expected: false},
+		{pattern: "news.*", topic: "news.sport", expected: true},
+		{pattern: "news.*", topic: "games", expected: false},
+		{pattern: "*.eu", topic: "news.eu", expected: true},
+		{pattern: "*.*.eu", topic: "news.eu", expected: false},
+		{pattern: "*", topic: "anything", expected: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.pattern+" "+testCase.topic, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, auth.Match(testCase.pattern, testCase.topic))
+		})
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	editor := &auth.Principal{Roles: []string{auth.RoleEditor}, Publish: []string{"news.*"}}
+	reader := &auth.Principal{Roles: []string{auth.RoleReader}, Subscribe: []string{"*"}}
+
+	assert.True(t, editor.HasRole(auth.RoleReader))
+	assert.False(t, reader.HasRole(auth.RoleEditor))
+	assert.True(t, editor.CanPublish("news.sport"))
+	assert.False(t, editor.CanSubscribe("news.sport"))
+	assert.False(t, reader.CanPublish("news.sport"))
+	assert.True(t, reader.CanSubscribe("news.sport"))
+}
+
+func TestAuthenticate(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("RSA key generation throws an error: %v", err)
+	}
+
+	keysFile := writeFile(t, dir, "keys.json", `[{"key":"s3cr3t","subject":"ci","roles":["editor"]}]`)
+	jwksFile := writeFile(t, dir, "jwks.json", fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","use":"sig","n":"%s","e":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())))
+
+	authenticator, err := auth.New(&auth.Config{
+		APIKeysFile: keysFile,
+		JWTSecret:   secret,
+		JWKSFile:    jwksFile,
+		Issuer:      "https://auth.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Authenticator initialization throws an error: %v", err)
+	}
+
+	exp := time.Now().Add(time.Minute).Unix()
+	valid := jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "exp": exp, "roles": []string{"reader"}}
+	expired := jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "exp": time.Now().Add(-time.Minute).Unix()}
+	neverExpires := jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com"}
+	wrongIssuer := jwt.MapClaims{"sub": "alice", "iss": "https://evil.example.com", "exp": exp}
+
+	testCases := []struct {
+		name            string
+		header          string
+		value           string
+		expectedSubject string
+		expectedErr     error
+	}{
+		{
+			name:            "API key header",
+			header:          auth.APIKeyHeader,
+			value:           "s3cr3t",
+			expectedSubject: "ci",
+		},
+		{
+			name:            "API key bearer",
+			header:          "Authorization",
+			value:           "Bearer s3cr3t",
+			expectedSubject: "ci",
+		},
+		{
+			name:        "Unknown API key",
+			header:      auth.APIKeyHeader,
+			value:       "guess",
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:            "HS256 token",
+			header:          "Authorization",
+			value:           "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), "", valid),
+			expectedSubject: "alice",
+		},
+		{
+			name:            "RS256 token",
+			header:          "Authorization",
+			value:           "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, "k1", valid),
+			expectedSubject: "alice",
+		},
+		{
+			name:        "Unknown key ID",
+			header:      "Authorization",
+			value:       "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, "k2", valid),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:        "Unsupported algorithm",
+			header:      "Authorization",
+			value:       "Bearer " + sign(t, jwt.SigningMethodHS512, []byte(secret), "", valid),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:        "Expired token",
+			header:      "Authorization",
+			value:       "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), "", expired),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:        "Token without expiration",
+			header:      "Authorization",
+			value:       "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), "", neverExpires),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:        "Wrong issuer",
+			header:      "Authorization",
+			value:       "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), "", wrongIssuer),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		{
+			name:        "Missing credentials",
+			expectedErr: auth.ErrMissingCredentials,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if testCase.header != "" {
+				request.Header.Set(testCase.header, testCase.value)
+			}
+
+			principal, err := authenticator.Authenticate(request)
+			assert.Equal(t, testCase.expectedErr, err)
+			if testCase.expectedErr == nil {
+				assert.Equal(t, testCase.expectedSubject, principal.Subject)
+			}
+
+			// Protocols other than HTTP pass the same credentials without the header.
+			principal, err = authenticator.AuthenticateCredential(strings.TrimPrefix(testCase.value, "Bearer "))
+			assert.Equal(t, testCase.expectedErr, err)
+			if testCase.expectedErr == nil {
+				assert.Equal(t, testCase.expectedSubject, principal.Subject)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	authenticator, err := auth.New(&auth.Config{JWTSecret: secret})
+	if err != nil {
+		t.Fatalf("Authenticator initialization throws an error: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		roles              []string
+		token              bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "Editor",
+			roles:              []string{auth.RoleEditor},
+			token:              true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Reader",
+			roles:              []string{auth.RoleReader},
+			token:              true,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Anonymous",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	handler := auth.Middleware(authenticator, log)(auth.RequireRole(auth.RoleEditor)(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {})))
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/v1/book/1", nil)
+			if testCase.token {
+				token := sign(t, jwt.SigningMethodHS256, []byte(secret), "", jwt.MapClaims{
+					"sub": "bob", "exp": time.Now().Add(time.Minute).Unix(), "roles": testCase.roles,
+				})
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedStatusCode, recorder.Code)
+		})
+	}
+}
+
+func TestAuthenticate_disabled(t *testing.T) {
+	authenticator, err := auth.New(&auth.Config{})
+	if err != nil {
+		t.Fatalf("Authenticator initialization throws an error: %v", err)
+	}
+
+	principal, err := authenticator.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, err)
+	assert.True(t, principal.HasRole(auth.RoleEditor))
+	assert.True(t, principal.CanPublish("news"))
+}
+
+func TestClientKey(t *testing.T) {
+	disabled, err := auth.New(&auth.Config{})
+	if err != nil {
+		t.Fatalf("Authenticator initialization throws an error: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	anonymous, err := disabled.Authenticate(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "ip:192.0.2.1", auth.ClientKey(request.WithContext(auth.NewContext(request.Context(), anonymous))))
+
+	// A principal whose subject is "anonymous" is not the principal of disabled authentication.
+	principal := &auth.Principal{Subject: auth.Anonymous}
+	assert.Equal(t, "sub:anonymous", auth.ClientKey(request.WithContext(auth.NewContext(request.Context(), principal))))
+	assert.Equal(t, "ip:192.0.2.1", auth.ClientKey(request))
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Token signing throws an error: %v", err)
+	}
+
+	return signed
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("File writing throws an error: %v", err)
+	}
+
+	return path
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Defines headers and query parameters that carry credentials.
+const (
+	APIKeyHeader = "X-API-Key"
+	// accessTokenParam carries the bearer token of websocket upgrades, because browsers cannot set their headers.
+	accessTokenParam = "access_token"
+)
+
+// Defines authentication errors.
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Config contains fields that will be used to configure authentication.
+// Authentication is disabled if neither API keys nor JWT keys are configured.
+type Config struct {
+	// APIKeysFile is a JSON file with a list of principals, each with a "key" field.
+	APIKeysFile string
+	// JWTSecret is the key of HS256 tokens.
+	JWTSecret string
+	// JWKSFile is a JSON Web Key Set file with the RSA keys of RS256 tokens.
+	JWKSFile string
+	// Issuer and Audience are compared with the "iss" and "aud" claims of tokens if they are set.
+	Issuer   string
+	Audience string
+}
+
+// NewConfig returrns a new Config object configured by AUTH_API_KEYS_FILE, AUTH_JWT_SECRET,
+// AUTH_JWKS_FILE, AUTH_JWT_ISSUER and AUTH_JWT_AUDIENCE environment variables.
+func NewConfig() *Config {
+	return &Config{
+		APIKeysFile: os.Getenv("AUTH_API_KEYS_FILE"),
+		JWTSecret:   os.Getenv("AUTH_JWT_SECRET"),
+		JWKSFile:    os.Getenv("AUTH_JWKS_FILE"),
+		Issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+		Audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+	}
+}
+
+// Enabled reports whether any credentials are configured.
+func (cfg *Config) Enabled() bool {
+	return cfg.APIKeysFile != "" || cfg.JWTSecret != "" || cfg.JWKSFile != ""
+}
+
+// Authenticator authenticates requests by API keys and JWT bearer tokens.
+type Authenticator struct {
+	cfg *Config
+	// apiKeys maps SHA-256 hashes of API keys to their principals.
+	apiKeys map[[sha256.Size]byte]*Principal
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+// New returns a new Authenticator object with the API keys and JWKS files of cfg loaded.
+func New(cfg *Config) (*Authenticator, error) {
+	a := &Authenticator{
+		cfg:     cfg,
+		apiKeys: make(map[[sha256.Size]byte]*Principal),
+		rsaKeys: make(map[string]*rsa.PublicKey),
+	}
+
+	methods := make([]string, 0, 2)
+	if cfg.JWTSecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.JWKSFile != "" {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	a.parser = jwt.NewParser(jwt.WithValidMethods(methods))
+
+	if cfg.APIKeysFile != "" {
+		if err := a.loadAPIKeys(cfg.APIKeysFile); err != nil {
+			return nil, fmt.Errorf("loading API keys: %w", err)
+		}
+	}
+
+	if cfg.JWKSFile != "" {
+		if err := a.loadJWKS(cfg.JWKSFile); err != nil {
+			return nil, fmt.Errorf("loading JWKS: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether requests are authenticated. Otherwise every request is anonymous and allowed everything.
+func (a *Authenticator) Enabled() bool {
+	return a.cfg.Enabled()
+}
+
+// Authenticate returns the principal of the request credentials. API keys are read from the X-API-Key header,
+// tokens from the Authorization header and, for websocket upgrades, the access_token query parameter.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if !a.Enabled() {
+		return anonymous(), nil
+	}
+
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	token := ""
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		const prefix = "bearer "
+		if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+			return nil, ErrInvalidCredentials
+		}
+
+		token = strings.TrimSpace(authorization[len(prefix):])
+	} else if r.Header.Get("Upgrade") != "" {
+		token = r.URL.Query().Get(accessTokenParam)
+	}
+
+	return a.AuthenticateCredential(token)
+}
+
+// AuthenticateCredential returns the principal of an API key or a JWT, for protocols that do not carry
+// credentials in HTTP headers. Credentials without the JWT structure are API keys.
+func (a *Authenticator) AuthenticateCredential(credential string) (*Principal, error) {
+	if !a.Enabled() {
+		return anonymous(), nil
+	}
+
+	if credential == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	if strings.Count(credential, ".") != 2 {
+		return a.authenticateAPIKey(credential)
+	}
+
+	return a.authenticateToken(credential)
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	principal, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return principal, nil
+}
+
+// claims are the claims of tokens: registered claims and the permissions of the principal.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles     []string `json:"roles"`
+	Publish   []string `json:"publish"`
+	Subscribe []string `json:"subscribe"`
+}
+
+func (a *Authenticator) authenticateToken(token string) (*Principal, error) {
+	var c claims
+	if _, err := a.parser.ParseWithClaims(token, &c, a.key); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// The parser checks the expiration of tokens that have one, and tokens that never expire are refused.
+	if c.Subject == "" || !c.VerifyExpiresAt(time.Now(), true) ||
+		(a.cfg.Issuer != "" && !c.VerifyIssuer(a.cfg.Issuer, true)) ||
+		(a.cfg.Audience != "" && !c.VerifyAudience(a.cfg.Audience, true)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{Subject: c.Subject, Roles: c.Roles, Publish: c.Publish, Subscribe: c.Subscribe}, nil
+}
+
+// key returns the key that verifies the token signature. The parser has already checked the algorithm.
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return []byte(a.cfg.JWTSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.rsaKeys[kid]; ok {
+		return key, nil
+	}
+
+	// Tokens without a key ID are accepted if the set has a single key.
+	if kid == "" && len(a.rsaKeys) == 1 {
+		for _, key := range a.rsaKeys {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+// apiKey is an entry of the API keys file.
+type apiKey struct {
+	Principal
+	Key string `json:"key"`
+}
+
+func (a *Authenticator) loadAPIKeys(name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var keys []apiKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if keys[i].Key == "" || keys[i].Subject == "" {
+			return fmt.Errorf("API key %d has no key or subject", i)
+		}
+
+		principal := keys[i].Principal
+		a.apiKeys[sha256.Sum256([]byte(keys[i].Key))] = &principal
+	}
+
+	return nil
+}
+
+// jwk is an RSA JSON Web Key. Keys of other types are skipped.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (a *Authenticator) loadJWKS(name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key.Kid, err)
+		}
+
+		a.rsaKeys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if len(a.rsaKeys) == 0 {
+		return errors.New("no RSA signing keys found")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// Middleware returns a middleware that authenticates requests and binds the principal to the request context,
+// along with a child of the request logger that carries the principal subject as the "user" field.
+// Requests with missing or invalid credentials are rejected with the 401 status code.
+func Middleware(a *Authenticator, log *logger.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			principal, err := a.Authenticate(r)
+			if err != nil {
+				logger.FromContext(r.Context(), log).Info("Authentication failed", zap.Error(err))
+				rw.Header().Set("WWW-Authenticate", `Bearer realm="pub-sub"`)
+				middleware.WriteError(rw, http.StatusUnauthorized, err.Error())
+
+				return
+			}
+
+			ctx := NewContext(r.Context(), principal)
+			ctx = logger.WithFields(ctx, log, zap.String("user", principal.Subject))
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole returns a middleware that rejects requests of principals without the role with the 403 status code.
+func RequireRole(role string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if principal, ok := FromContext(r.Context()); !ok || !principal.HasRole(role) {
+				middleware.WriteError(rw, http.StatusForbidden, "the "+role+" role is required")
+
+				return
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ClientKey returns the key that identifies the client of the request: the subject of the authenticated principal
+// or, if authentication is disabled, the client IP address.
+func ClientKey(r *http.Request) string {
+	principal, _ := FromContext(r.Context())
+
+	return PrincipalKey(principal, r.RemoteAddr)
+}
+
+// PrincipalKey returns the key that identifies a client connected from remoteAddr: the subject of the principal
+// or, if there is none or authentication is disabled, the client IP address. It is ClientKey of other protocols.
+// Keys are prefixed by their kind, so a subject never shares the key of an address.
+func PrincipalKey(principal *Principal, remoteAddr string) string {
+	if principal != nil && !principal.anonymous {
+		return "sub:" + principal.Subject
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	return "ip:" + host
+}
@@ -0,0 +1,110 @@
+// Package health contains liveness and readiness handlers that report the state of service dependencies.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defines dependency statuses.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check reports whether a dependency is ready to serve requests.
+type Check func(ctx context.Context) error
+
+// CheckStatus represents the result of a single dependency check.
+type CheckStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Response represents the response body of health handlers.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks,omitempty"`
+}
+
+// Checker runs named dependency checks.
+type Checker struct {
+	mutex   sync.RWMutex
+	checks  map[string]Check
+	timeout time.Duration
+}
+
+// NewChecker returns a new Checker object that bounds every readiness probe by timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{checks: make(map[string]Check), timeout: timeout}
+}
+
+// Add registers a named dependency check.
+func (c *Checker) Add(name string, check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.checks[name] = check
+}
+
+// Check runs all dependency checks concurrently and returns their results.
+func (c *Checker) Check(ctx context.Context) *Response {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	response := &Response{Status: StatusOK, Checks: make(map[string]CheckStatus, len(c.checks))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			status := CheckStatus{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				status = CheckStatus{Status: StatusUnavailable, Error: err.Error()}
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			response.Checks[name] = status
+			if status.Status != StatusOK {
+				response.Status = StatusUnavailable
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	return response
+}
+
+// Healthz processes /healthz route: it reports that the process is alive.
+func (c *Checker) Healthz(rw http.ResponseWriter, r *http.Request) {
+	write(rw, http.StatusOK, &Response{Status: StatusOK})
+}
+
+// Readyz processes /readyz route: it reports the state of every dependency
+// and responds with 503 if any of them is unavailable.
+func (c *Checker) Readyz(rw http.ResponseWriter, r *http.Request) {
+	response := c.Check(r.Context())
+	statusCode := http.StatusOK
+	if response.Status != StatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	write(rw, statusCode, response)
+}
+
+func write(rw http.ResponseWriter, statusCode int, response *Response) {
+	rw.Header().Add("content-type", "application/json")
+	rw.WriteHeader(statusCode)
+	json.NewEncoder(rw).Encode(response)
+}
@@ -0,0 +1,69 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/health"
+)
+
+func TestChecker_Readyz(t *testing.T) {
+	testCases := []struct {
+		name               string
+		checks             map[string]health.Check
+		expectedString     string
+		expectedStatusCode int
+	}{
+		{
+			name: "OK",
+			checks: map[string]health.Check{
+				"mongo": func(context.Context) error { return nil },
+			},
+			expectedString:     `{"status":"ok","checks":{"mongo":{"status":"ok"}}}` + "\n",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Dependency is unavailable",
+			checks: map[string]health.Check{
+				"mongo":    func(context.Context) error { return nil },
+				"postgres": func(context.Context) error { return errors.New("connection refused") },
+			},
+			expectedString:     `{"status":"unavailable","checks":{"mongo":{"status":"ok"},"postgres":{"status":"unavailable","error":"connection refused"}}}` + "\n",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Dependency check times out",
+			checks: map[string]health.Check{
+				"mongo": func(ctx context.Context) error {
+					<-ctx.Done()
+
+					return ctx.Err()
+				},
+			},
+			expectedString:     `{"status":"unavailable","checks":{"mongo":{"status":"unavailable","error":"context deadline exceeded"}}}` + "\n",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			checker := health.NewChecker(10 * time.Millisecond)
+			for name, check := range testCase.checks {
+				checker.Add(name, check)
+			}
+
+			rec := httptest.NewRecorder()
+			checker.Readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			assert.Equal(t, testCase.expectedString, rec.Body.String())
+		})
+	}
+}
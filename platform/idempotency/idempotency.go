@@ -0,0 +1,49 @@
+// Package idempotency contains the logic to replay the responses of retried requests with the same Idempotency-Key.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Defines headers of idempotent requests.
+const (
+	// Header carries the client-chosen key of a request that may be retried.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on responses replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// Defines errors of requests with reused keys.
+var (
+	ErrInProgress = errors.New("a request with the idempotency key is in progress")
+	ErrMismatch   = errors.New("the idempotency key was used with a different request")
+	ErrInvalidKey = errors.New("invalid idempotency key")
+)
+
+// Response is a stored response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Record is the state of a key: the fingerprint of the request that reserved it and, once the request
+// has been served, its response.
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Store keeps the records of keys until their TTL expires.
+type Store interface {
+	// Reserve stores an in-progress record with the fingerprint for key and returns nil.
+	// If key already has a record, the existing record is returned instead.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	// Complete replaces the in-progress record of key with the served one.
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Release deletes the record of key, so the request may be retried.
+	Release(ctx context.Context, key string) error
+}
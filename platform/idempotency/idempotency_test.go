@@ -0,0 +1,111 @@
+package idempotency_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/idempotency"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	calls := 0
+	statusCode := http.StatusCreated
+	handler := idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log)(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			calls++
+			rw.Header().Add("content-type", "application/json")
+			rw.WriteHeader(statusCode)
+			fmt.Fprintf(rw, `{"call":%d}`, calls)
+		}))
+
+	serve := func(key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/book/", strings.NewReader(body))
+		request.Header.Set(idempotency.Header, key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	first := serve("key-1", `{"name":"1984"}`)
+	retry := serve("key-1", `{"name":"1984"}`)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(idempotency.ReplayedHeader))
+	assert.Empty(t, first.Header().Get(idempotency.ReplayedHeader))
+
+	mismatch := serve("key-1", `{"name":"Dune"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+	assert.Equal(t, 1, calls)
+
+	serve("", `{"name":"1984"}`)
+	serve("", `{"name":"1984"}`)
+	assert.Equal(t, 3, calls, "requests without a key are not deduplicated")
+
+	statusCode = http.StatusServiceUnavailable
+	serve("key-2", `{"name":"1984"}`)
+	serve("key-2", `{"name":"1984"}`)
+	assert.Equal(t, 5, calls, "failed requests may be retried")
+}
+
+func TestMiddleware_inProgress(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	store := idempotency.NewMemoryStore()
+	started, finish := make(chan struct{}), make(chan struct{})
+	handler := idempotency.Middleware(store, time.Hour, log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-finish
+	}))
+
+	request := func() *http.Request {
+		request := httptest.NewRequest(http.MethodPost, "/publish", strings.NewReader(`{}`))
+		request.Header.Set(idempotency.Header, "key")
+
+		return request
+	}
+
+	go handler.ServeHTTP(httptest.NewRecorder(), request())
+	<-started
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request())
+	close(finish)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestMemoryStore_expiration(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	ctx := context.Background()
+
+	record, err := store.Reserve(ctx, "key", "fingerprint", 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	record, err = store.Reserve(ctx, "key", "fingerprint", 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, &idempotency.Record{Fingerprint: "fingerprint"}, record)
+
+	time.Sleep(20 * time.Millisecond)
+	record, err = store.Reserve(ctx, "key", "fingerprint", 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
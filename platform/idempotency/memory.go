@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the interval between removals of expired records from MemoryStore.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps records in the process memory, so retries must reach the same replica.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+type memoryRecord struct {
+	record  *Record
+	expires time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore object.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord), lastSweep: time.Now()}
+}
+
+// Reserve stores an in-progress record for key unless it has an unexpired one.
+func (s *MemoryStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(now)
+	if existing, ok := s.records[key]; ok && now.Before(existing.expires) {
+		return existing.record, nil
+	}
+
+	s.records[key] = memoryRecord{record: &Record{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+
+	return nil, nil
+}
+
+// Complete stores the served record of key.
+func (s *MemoryStore) Complete(_ context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[key] = memoryRecord{record: record, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+// Release deletes the record of key.
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+// sweep removes expired records once per sweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	s.lastSweep = now
+	for key, r := range s.records {
+		if !now.Before(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
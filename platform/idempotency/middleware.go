@@ -0,0 +1,187 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+const (
+	// keyPrefix prefixes the keys of records in a shared store.
+	keyPrefix = "idempotency:"
+	// maxKeyLength is the length limit of keys accepted from clients.
+	maxKeyLength = 255
+)
+
+// NewStore returns a RedisStore connected to redisURL, like "redis://localhost:6379/0",
+// or a MemoryStore if redisURL is empty.
+func NewStore(redisURL string) (Store, error) {
+	if redisURL == "" {
+		return NewMemoryStore(), nil
+	}
+
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedisStore(redis.NewClient(options), keyPrefix), nil
+}
+
+// Middleware returns a middleware that stores the responses of requests with the Idempotency-Key header for ttl
+// and replays them to retries with the same key, method, path and body. Keys are scoped to the client.
+// A retry of a request in progress is rejected with 409, and a reused key with a different request with 422.
+// Responses with 5xx status codes are not stored, so the request may be retried.
+func Middleware(store Store, ttl time.Duration, log *logger.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			log := logger.FromContext(r.Context(), log)
+			if len(key) > maxKeyLength {
+				middleware.WriteError(rw, http.StatusBadRequest, ErrInvalidKey.Error())
+
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				middleware.WriteError(rw, http.StatusBadRequest, "request body reading failed")
+
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			storeKey := auth.ClientKey(r) + ":" + r.Method + " " + r.URL.Path + ":" + key
+			fingerprint := fingerprint(r, body)
+			record, err := store.Reserve(r.Context(), storeKey, fingerprint, ttl)
+			if err != nil {
+				// The request is served without the guarantee rather than failed.
+				log.Error("Idempotency key reservation failed", zap.Error(err))
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			if record != nil {
+				replay(rw, record, fingerprint, log)
+
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: rw}
+			served := false
+			defer func() {
+				if !served {
+					// The handler panicked, so the key is released for a retry.
+					store.Release(context.Background(), storeKey)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+			served = true
+			if recorder.status >= http.StatusInternalServerError {
+				if err := store.Release(context.Background(), storeKey); err != nil {
+					log.Error("Idempotency key release failed", zap.Error(err))
+				}
+
+				return
+			}
+
+			if err := store.Complete(context.Background(), storeKey, &Record{
+				Fingerprint: fingerprint,
+				Response:    recorder.response(),
+			}, ttl); err != nil {
+				log.Error("Idempotent response storing failed", zap.Error(err))
+			}
+		})
+	}
+}
+
+// replay sends the stored response of the record or an error if it cannot be replayed.
+func replay(rw http.ResponseWriter, record *Record, fingerprint string, log *logger.Logger) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		log.Info("Idempotency key reused with a different request")
+		middleware.WriteError(rw, http.StatusUnprocessableEntity, ErrMismatch.Error())
+	case record.Response == nil:
+		middleware.WriteError(rw, http.StatusConflict, ErrInProgress.Error())
+	default:
+		log.Debug("Response replayed", zap.Int("status", record.Response.StatusCode))
+		for name, values := range record.Response.Header {
+			rw.Header()[name] = values
+		}
+
+		rw.Header().Set(ReplayedHeader, "true")
+		rw.WriteHeader(record.Response.StatusCode)
+		rw.Write(record.Response.Body)
+	}
+}
+
+// fingerprint returns the hash of the request method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder copies the response it writes, so it can be stored.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) response() *Response {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// Only the headers that describe the body are stored. Headers of other middlewares,
+	// like the request ID, belong to the retry rather than the original request.
+	header := make(http.Header)
+	for _, name := range []string{"Content-Type", "Location"} {
+		if value := r.Header().Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+
+	return &Response{StatusCode: status, Header: header, Body: r.body.Bytes()}
+}
@@ -0,0 +1,75 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStore keeps records in Redis, so retries may reach any replica.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore returns a new RedisStore object that prefixes record keys with prefix.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Reserve stores an in-progress record for key unless it has one.
+func (s *RedisStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	data, err := json.Marshal(&Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := s.client.SetNX(ctx, s.prefix+key, data, ttl).Result()
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	existing, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if err == redis.Nil {
+		// The record expired in between, so the key is free again.
+		return s.Reserve(ctx, key, fingerprint, ttl)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{}
+	if err := json.Unmarshal(existing, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Complete stores the served record of key.
+func (s *RedisStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+// Release deletes the record of key.
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// Ping checks the Redis connection. It is the readiness check of the store.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -2,22 +2,118 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"os"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defines supported logger modes.
+const (
+	ModeDevelopment = "development"
+	ModeProduction  = "production"
 )
 
+// ErrUnknownMode is returned if the logger mode is not supported.
+var ErrUnknownMode = errors.New("unknown logger mode")
+
 // Logger represents application logger.
 type Logger struct {
 	*zap.Logger
 }
 
-// New returns a new configured Logger object.
+// Config contains fields that will be used to configure the logger.
+// Empty Level and Encoding fall back to the defaults of the mode:
+// debug level and console encoding for development, info level and json encoding for production.
+type Config struct {
+	Mode     string
+	Level    string
+	Encoding string
+}
+
+// NewConfig returrns a new Config object configured by LOG_MODE, LOG_LEVEL and LOG_ENCODING environment variables.
+func NewConfig() *Config {
+	return &Config{
+		Mode:     os.Getenv("LOG_MODE"),
+		Level:    os.Getenv("LOG_LEVEL"),
+		Encoding: os.Getenv("LOG_ENCODING"),
+	}
+}
+
+// New returns a new Logger object configured by the environment variables.
 func New() (*Logger, error) {
-	logger, err := zap.NewDevelopment()
+	return NewWithConfig(NewConfig())
+}
+
+// NewWithConfig returns a new Logger object configured by cfg.
+func NewWithConfig(cfg *Config) (*Logger, error) {
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeDevelopment
+	}
+
+	var zapConfig zap.Config
+	switch mode {
+	case ModeDevelopment:
+		zapConfig = zap.NewDevelopmentConfig()
+	case ModeProduction:
+		zapConfig = zap.NewProductionConfig()
+	default:
+		return nil, ErrUnknownMode
+	}
+
+	if cfg.Level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, err
+		}
+
+		zapConfig.Level = zap.NewAtomicLevelAt(level)
+	}
+
+	if cfg.Encoding != "" {
+		zapConfig.Encoding = cfg.Encoding
+	}
+
+	logger, err := zapConfig.Build()
 	if err != nil {
 		return nil, err
 	}
 
-	logger.Info("The logger is successfully configured")
+	logger.Info("The logger is successfully configured",
+		zap.String("mode", mode),
+		zap.Stringer("level", zapConfig.Level),
+		zap.String("encoding", zapConfig.Encoding))
 
 	return &Logger{logger}, nil
 }
+
+// With returns a child logger that adds fields to every entry.
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return &Logger{l.Logger.With(fields...)}
+}
+
+// contextKey is the key of the logger in a context.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the logger.
+func NewContext(ctx context.Context, log *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext returns the logger carried by ctx, or fallback if ctx carries none.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if log, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return log
+	}
+
+	return fallback
+}
+
+// WithFields returns a copy of ctx that carries a child of its logger with fields added,
+// for example the ID of the authenticated user.
+func WithFields(ctx context.Context, fallback *Logger, fields ...zap.Field) context.Context {
+	return NewContext(ctx, FromContext(ctx, fallback).With(fields...))
+}
@@ -0,0 +1,131 @@
+package logger_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         logger.Config
+		expectedLevel zapcore.Level
+		expectedErr   bool
+	}{
+		{
+			name:          "Development by default",
+			input:         logger.Config{},
+			expectedLevel: zapcore.DebugLevel,
+		},
+		{
+			name:          "Production",
+			input:         logger.Config{Mode: logger.ModeProduction},
+			expectedLevel: zapcore.InfoLevel,
+		},
+		{
+			name:          "Production with level and encoding",
+			input:         logger.Config{Mode: logger.ModeProduction, Level: "warn", Encoding: "console"},
+			expectedLevel: zapcore.WarnLevel,
+		},
+		{
+			name:        "Unknown mode",
+			input:       logger.Config{Mode: "staging"},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown level",
+			input:       logger.Config{Level: "verbose"},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown encoding",
+			input:       logger.Config{Encoding: "xml"},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			log, err := logger.NewWithConfig(&testCase.input)
+			if testCase.expectedErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, log.Core().Enabled(testCase.expectedLevel))
+			assert.False(t, log.Core().Enabled(testCase.expectedLevel-1))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "Request ID from the client",
+			requestID: "7a2f922c-073a-11eb",
+		},
+		{
+			name:      "Generated request ID",
+			generated: true,
+		},
+		{
+			name:      "Invalid request ID is replaced",
+			requestID: "bad\nid",
+			generated: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			log := &logger.Logger{Logger: zap.New(core)}
+
+			var requestID string
+			handler := logger.Middleware(log, func(*http.Request) string { return "/v1/book/{id}" })(
+				http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+					requestID = logger.RequestIDFromContext(r.Context())
+					ctx := logger.WithFields(r.Context(), log, zap.String("user", "admin"))
+					logger.FromContext(ctx, log).Info("Book sent")
+				}))
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/book/1", nil)
+			request.Header.Set(logger.RequestIDHeader, testCase.requestID)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if testCase.generated {
+				assert.Len(t, requestID, 32)
+			} else {
+				assert.Equal(t, testCase.requestID, requestID)
+			}
+
+			assert.Equal(t, requestID, recorder.Header().Get(logger.RequestIDHeader))
+			assert.Equal(t, 1, logs.Len())
+			assert.Equal(t, map[string]interface{}{
+				"request_id": requestID,
+				"route":      "/v1/book/{id}",
+				"user":       "admin",
+			}, logs.All()[0].ContextMap())
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := &logger.Logger{Logger: zap.NewNop()}
+	assert.Equal(t, fallback, logger.FromContext(context.Background(), fallback))
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header that carries the request ID between services and back to the client.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the length limit of request IDs accepted from clients.
+const maxRequestIDLength = 64
+
+// requestIDKey is the key of the request ID in a context.
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID carried by ctx or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// Middleware returns a middleware that assigns every request an ID and binds to the request context
+// a child of log carrying the request ID and the route returned by route.
+// The ID is taken from the X-Request-ID header if it is valid or generated otherwise,
+// and it is sent back in the X-Request-ID response header.
+func Middleware(log *Logger, route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+
+			rw.Header().Set(RequestIDHeader, requestID)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = NewContext(ctx, log.With(zap.String("request_id", requestID), zap.String("route", route(r))))
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID reports whether the client request ID is safe to log: it must be short
+// and contain only letters, digits, dashes, underscores and dots.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random 128-bit hex request ID.
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
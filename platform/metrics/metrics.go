@@ -0,0 +1,118 @@
+// Package metrics contains Prometheus collectors and handlers shared by the services.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRegistry returns a new registry with the Go runtime and process collectors registered.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector())
+	reg.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	return reg
+}
+
+// Handler processes /metrics route: it exports metrics of reg in the Prometheus text format.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// HTTP collects request counts and latencies of an HTTP server.
+type HTTP struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewHTTP returns a new HTTP object registered in reg.
+func NewHTTP(reg prometheus.Registerer) *HTTP {
+	labels := []string{"route", "method", "status"}
+	m := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of processed HTTP requests.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of processed HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+
+	reg.MustRegister(m.requests, m.duration)
+
+	return m
+}
+
+// Middleware returns a middleware that records every request under the route returned by route.
+// The route should be a path template rather than the request path to keep the number of series bounded.
+func (m *HTTP) Middleware(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: rw}
+			next.ServeHTTP(recorder, r)
+
+			labels := prometheus.Labels{
+				"route":  route(r),
+				"method": r.Method,
+				"status": strconv.Itoa(recorder.Status()),
+			}
+
+			m.requests.With(labels).Inc()
+			m.duration.With(labels).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Hijack lets websocket handlers take over the connection. Hijacked requests are recorded with 101.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	r.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// Status returns the written status code. Handlers that write nothing respond with 200.
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+
+	return r.status
+}
@@ -0,0 +1,68 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/metrics"
+)
+
+func TestHTTP_Middleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expected string
+	}{
+		{
+			name:     "OK",
+			handler:  func(rw http.ResponseWriter, r *http.Request) { rw.Write([]byte("{}")) },
+			expected: `http_requests_total{method="GET",route="/v1/book/{id}",status="200"} 1`,
+		},
+		{
+			name:     "Not found",
+			handler:  func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusNotFound) },
+			expected: `http_requests_total{method="GET",route="/v1/book/{id}",status="404"} 1`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reg := metrics.NewRegistry()
+			route := func(*http.Request) string { return "/v1/book/{id}" }
+			handler := metrics.NewHTTP(reg).Middleware(route)(testCase.handler)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/book/1", nil))
+
+			recorder := httptest.NewRecorder()
+			metrics.Handler(reg).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			assert.Contains(t, recorder.Body.String(), testCase.expected)
+			assert.Contains(t, recorder.Body.String(), "http_request_duration_seconds_bucket")
+		})
+	}
+}
+
+func TestHTTP_Middleware_hijack(t *testing.T) {
+	reg := metrics.NewRegistry()
+	route := func(*http.Request) string { return "/subscribe" }
+	server := httptest.NewServer(metrics.NewHTTP(reg).Middleware(route)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijacking throws an error: %v", err)
+
+			return
+		}
+
+		conn.Close()
+	})))
+	defer server.Close()
+
+	if response, err := http.Get(server.URL); err == nil {
+		response.Body.Close()
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `http_requests_total{method="GET",route="/subscribe",status="101"} 1`)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig contains fields that will be used to answer cross-origin requests.
+type CORSConfig struct {
+	// AllowedOrigins are origins allowed to call the service, "*" allows any origin.
+	// No origins disable CORS headers.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers readable by clients in addition to X-Request-ID.
+	ExposedHeaders []string
+	MaxAge         time.Duration
+}
+
+// CORS returns a middleware that adds CORS headers for allowed origins
+// and answers preflight requests with the 204 status code.
+func CORS(cfg *CORSConfig) Middleware {
+	allowAny := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+
+		allowed[origin] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(append([]string{"X-Request-ID"}, cfg.ExposedHeaders...), ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			rw.Header().Add("Vary", "Origin")
+			if !allowAny && !allowed[origin] {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			rw.Header().Set("Access-Control-Allow-Origin", origin)
+			rw.Header().Set("Access-Control-Expose-Headers", exposed)
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			rw.Header().Set("Access-Control-Allow-Methods", methods)
+			rw.Header().Set("Access-Control-Allow-Headers", headers)
+			rw.Header().Set("Access-Control-Max-Age", maxAge)
+			rw.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Gzip returns a middleware that compresses responses of clients accepting gzip.
+// Websocket upgrades and responses without a body are passed through unchanged.
+func Gzip() Middleware {
+	pool := sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			rw.Header().Add("Vary", "Accept-Encoding")
+			writer := &gzipResponseWriter{ResponseWriter: rw, pool: &pool}
+			defer writer.close()
+
+			next.ServeHTTP(writer, r)
+		})
+	}
+}
+
+// gzipResponseWriter compresses the body once the handler writes a status code that allows one.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	header := w.Header()
+	if status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.gz.Write(b)
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+
+	w.gz.Close()
+	w.pool.Put(w.gz)
+	w.gz = nil
+}
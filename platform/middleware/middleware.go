@@ -0,0 +1,166 @@
+// Package middleware contains composable HTTP middlewares shared by the services.
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Middleware wraps an HTTP handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares, so that the first one is the outermost: Chain(a, b)(h) is a(b(h)).
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+
+		return next
+	}
+}
+
+// AccessLog returns a middleware that logs every served request with its status, size and duration.
+// Requests are logged by the logger bound to the request context, or log if there is none.
+func AccessLog(log *logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &responseRecorder{ResponseWriter: rw}
+			next.ServeHTTP(recorder, r)
+
+			logger.FromContext(r.Context(), log).Info("Request served",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", recorder.Status()),
+				zap.Int("bytes", recorder.bytes),
+				zap.Duration("duration", time.Since(start)),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("user_agent", r.UserAgent()))
+		})
+	}
+}
+
+// BodyLimit returns a middleware that rejects request bodies larger than maxBytes
+// with the 413 status code. A body without Content-Length fails on reading past the limit.
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				WriteError(rw, http.StatusRequestEntityTooLarge, "request body is too large")
+
+				return
+			}
+
+			r.Body = http.MaxBytesReader(rw, r.Body, maxBytes)
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// Timeout returns a middleware that cancels the request context after timeout and
+// responds with the 503 status code if the handler has not finished by then.
+// The response is buffered, so it must not wrap websocket or streaming handlers.
+func Timeout(timeout time.Duration) Middleware {
+	message := string(ErrorBody(http.StatusServiceUnavailable, "request timeout"))
+
+	return func(next http.Handler) http.Handler {
+		timeoutHandler := http.TimeoutHandler(next, timeout, message)
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("content-type", "application/json")
+			timeoutHandler.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// errorResponse is the format of the error responses of all handlers.
+type errorResponse struct {
+	Error struct {
+		StatusCode int    `json:"statusCode"`
+		Message    string `json:"message"`
+	} `json:"error"`
+}
+
+// ErrorBody returns the JSON body of an error response, like {"error":{"statusCode":404,"message":"..."}}.
+// The message may contain any characters.
+func ErrorBody(statusCode int, message string) []byte {
+	response := errorResponse{}
+	response.Error.StatusCode, response.Error.Message = statusCode, message
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(response)
+
+	return bytes.TrimSuffix(body.Bytes(), []byte("\n"))
+}
+
+// WriteError sends an error response in the format used by all handlers.
+func WriteError(rw http.ResponseWriter, statusCode int, message string) {
+	rw.Header().Set("content-type", "application/json")
+	rw.WriteHeader(statusCode)
+	rw.Write(ErrorBody(statusCode, message))
+}
+
+// responseRecorder remembers the status code and the size of a response.
+// It passes hijacking and flushing through, so it can wrap websocket handlers.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+}
+
+// Status returns the written status code. Handlers that write nothing respond with 200.
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+
+	return r.status
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	r.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
@@ -0,0 +1,224 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+func ok(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Add("content-type", "application/json")
+	rw.Write([]byte(`{"name":"1984"}`))
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(rw, r)
+			})
+		}
+	}
+
+	middleware.Chain(mark("first"), mark("second"))(http.HandlerFunc(ok)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"first", "second"}, order)
+}
+
+func TestRecovery(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := &logger.Logger{Logger: zap.New(core)}
+	handler := middleware.Recovery(log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("nil map")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, `{"error":{"statusCode":500,"message":"internal server error"}}`, recorder.Body.String())
+	assert.Equal(t, 1, logs.FilterMessage("Handler panicked").Len())
+}
+
+func TestWriteError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	middleware.WriteError(recorder, http.StatusBadRequest, `parsing time "tomorrow" as "2006-01-02": cannot parse \ <here>`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("content-type"))
+	assert.JSONEq(t,
+		`{"error":{"statusCode":400,"message":"parsing time \"tomorrow\" as \"2006-01-02\": cannot parse \\ <here>"}}`,
+		recorder.Body.String())
+}
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := &logger.Logger{Logger: zap.New(core)}
+	middleware.AccessLog(log)(http.HandlerFunc(ok)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/book/1", nil))
+
+	entries := logs.FilterMessage("Request served").All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int64(http.StatusOK), entries[0].ContextMap()["status"])
+	assert.Equal(t, int64(15), entries[0].ContextMap()["bytes"])
+}
+
+func TestBodyLimit(t *testing.T) {
+	testCases := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+	}{
+		{
+			name:               "OK",
+			body:               `{"name":"1984"}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Body is too large",
+			body:               `{"name":"The Hitchhiker's Guide to the Galaxy"}`,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := middleware.BodyLimit(16)(http.HandlerFunc(ok))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testCase.body)))
+			assert.Equal(t, testCase.expectedStatusCode, recorder.Code)
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	testCases := []struct {
+		name               string
+		method             string
+		origin             string
+		expectedOrigin     string
+		expectedStatusCode int
+	}{
+		{
+			name:               "Allowed origin",
+			method:             http.MethodGet,
+			origin:             "https://books.example.com",
+			expectedOrigin:     "https://books.example.com",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Preflight request",
+			method:             http.MethodOptions,
+			origin:             "https://books.example.com",
+			expectedOrigin:     "https://books.example.com",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "Unknown origin",
+			method:             http.MethodGet,
+			origin:             "https://evil.example.com",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	cfg := &middleware.CORSConfig{
+		AllowedOrigins: []string{"https://books.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         time.Hour,
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(testCase.method, "/", nil)
+			request.Header.Set("Origin", testCase.origin)
+			request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			recorder := httptest.NewRecorder()
+			middleware.CORS(cfg)(http.HandlerFunc(ok)).ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedStatusCode, recorder.Code)
+			assert.Equal(t, testCase.expectedOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+	recorder := httptest.NewRecorder()
+	middleware.Gzip()(http.HandlerFunc(ok)).ServeHTTP(recorder, request)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatalf("Response body is not compressed: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"1984"}`, string(body))
+}
+
+func TestTimeout(t *testing.T) {
+	handler := middleware.Timeout(10 * time.Millisecond)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, `{"error":{"statusCode":503,"message":"request timeout"}}`, recorder.Body.String())
+}
+
+func TestRealIP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{
+			name:       "Trusted proxy",
+			remoteAddr: "10.0.0.2:41234",
+			forwarded:  "203.0.113.7, 10.0.0.3",
+			expected:   "203.0.113.7:0",
+		},
+		{
+			name:       "Spoofed header from an untrusted client",
+			remoteAddr: "198.51.100.1:41234",
+			forwarded:  "203.0.113.7",
+			expected:   "198.51.100.1:41234",
+		},
+	}
+
+	trusted, err := middleware.ParseCIDRs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("CIDR parsing throws an error: %v", err)
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var remoteAddr string
+			handler := middleware.RealIP(trusted)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				remoteAddr = r.RemoteAddr
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = testCase.remoteAddr
+			request.Header.Set("X-Forwarded-For", testCase.forwarded)
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+			assert.Equal(t, testCase.expected, remoteAddr)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDRs parses a list of networks like "10.0.0.0/8". Single addresses are treated as /32 or /128 networks.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// RealIP returns a middleware that replaces the request remote address with the client address
+// reported by trusted proxies in the X-Forwarded-For or X-Real-IP headers. The headers are ignored
+// unless the request comes from one of the trusted networks, so clients cannot spoof their address.
+func RealIP(trusted []*net.IPNet) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if ip := realIP(r, trusted); ip != "" {
+				r.RemoteAddr = net.JoinHostPort(ip, "0")
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// realIP returns the client address or an empty string if the request does not come from a trusted proxy.
+// X-Forwarded-For is read from right to left, and the first untrusted address is the client.
+func realIP(r *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || !isTrusted(net.ParseIP(host), trusted) {
+		return ""
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addrs := strings.Split(forwarded, ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(addrs[i]))
+			if ip == nil {
+				return ""
+			}
+
+			if !isTrusted(ip, trusted) || i == 0 {
+				return ip.String()
+			}
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+)
+
+// Recovery returns a middleware that recovers from handler panics, logs them with the stack trace
+// and responds with the 500 status code. Panics with http.ErrAbortHandler are passed through
+// because they abort the response on purpose.
+func Recovery(log *logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				logger.FromContext(r.Context(), log).Error("Handler panicked",
+					zap.String("panic", fmt.Sprint(recovered)),
+					zap.Stack("stack"))
+				WriteError(rw, http.StatusInternalServerError, "internal server error")
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// Handler returns a handler that serves doc as JSON. It is meant for the /openapi.json route.
+func Handler(doc *Document) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			middleware.WriteError(rw, http.StatusInternalServerError, "the specification cannot be encoded")
+
+			return
+		}
+
+		rw.Header().Set("content-type", ContentJSON)
+		rw.Write(data)
+	})
+}
+
+// DocsHandler returns a handler that serves a page that renders the document served at specURL.
+// The page is self-contained, so it works without access to the internet.
+func DocsHandler(specURL string) http.Handler {
+	page := template.Must(template.New("docs").Parse(docsPage))
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "text/html; charset=utf-8")
+		page.Execute(rw, specURL)
+	})
+}
+
+// docsPage renders the operations of the document at the URL it is executed with.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+details { border: 1px solid #ddd; border-radius: 4px; margin: .5em 0; padding: .5em; }
+summary { cursor: pointer; }
+.method { display: inline-block; width: 5em; font-weight: bold; text-transform: uppercase; }
+.get { color: #2a7ae2; } .post { color: #2e9d4b; } .put { color: #c27c0e; } .delete { color: #c0392b; }
+pre { background: #f6f8fa; padding: .5em; overflow: auto; }
+td, th { text-align: left; padding: .2em 1em .2em 0; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1 id="title">API documentation</h1>
+<p id="description"></p>
+<p>The specification is available at <a href="{{.}}">{{.}}</a>.</p>
+<div id="operations"></div>
+<script>
+(function () {
+  var specURL = {{.}};
+
+  function element(tag, text, className) {
+    var e = document.createElement(tag);
+    if (text) { e.textContent = text; }
+    if (className) { e.className = className; }
+    return e;
+  }
+
+  function resolve(spec, schema) {
+    while (schema && schema.$ref) {
+      schema = spec.components.schemas[schema.$ref.split("/").pop()];
+    }
+    return schema;
+  }
+
+  function expand(spec, schema, depth) {
+    schema = resolve(spec, schema);
+    if (!schema || depth > 5) { return schema; }
+    var copy = JSON.parse(JSON.stringify(schema));
+    Object.keys(copy.properties || {}).forEach(function (name) {
+      copy.properties[name] = expand(spec, copy.properties[name], depth + 1);
+    });
+    if (copy.items) { copy.items = expand(spec, copy.items, depth + 1); }
+    if (copy.anyOf) { copy.anyOf = copy.anyOf.map(function (s) { return expand(spec, s, depth + 1); }); }
+    return copy;
+  }
+
+  function schemaBlock(spec, content) {
+    var media = content && content["application/json"];
+    if (!media) { return null; }
+    return element("pre", JSON.stringify(expand(spec, media.schema, 0), null, 2));
+  }
+
+  fetch(specURL).then(function (response) { return response.json(); }).then(function (spec) {
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    document.getElementById("description").textContent = spec.info.description || "";
+    var operations = document.getElementById("operations");
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      Object.keys(spec.paths[path]).forEach(function (method) {
+        var operation = spec.paths[path][method];
+        var details = element("details");
+        var summary = element("summary");
+        summary.appendChild(element("span", method, "method " + method));
+        summary.appendChild(element("code", path));
+        summary.appendChild(document.createTextNode(" " + (operation.summary || "")));
+        details.appendChild(summary);
+        if (operation.description) { details.appendChild(element("p", operation.description)); }
+        if (operation.parameters) {
+          details.appendChild(element("h4", "Parameters"));
+          var table = element("table");
+          operation.parameters.forEach(function (parameter) {
+            var row = element("tr");
+            row.appendChild(element("td", parameter.name + (parameter.required ? " *" : "")));
+            row.appendChild(element("td", parameter.in));
+            row.appendChild(element("td", parameter.description || ""));
+            table.appendChild(row);
+          });
+          details.appendChild(table);
+        }
+        if (operation.requestBody) {
+          details.appendChild(element("h4", "Request body"));
+          var body = schemaBlock(spec, operation.requestBody.content);
+          if (body) { details.appendChild(body); }
+        }
+        details.appendChild(element("h4", "Responses"));
+        Object.keys(operation.responses).forEach(function (status) {
+          var response = operation.responses[status];
+          details.appendChild(element("p", status + ": " + response.description));
+          var block = schemaBlock(spec, response.content);
+          if (block) { details.appendChild(block); }
+        });
+        operations.appendChild(details);
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`
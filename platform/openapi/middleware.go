@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// Middleware returns a middleware that rejects requests that do not match the operation of doc with 400.
+// Requests without an operation in doc are passed through. If validateResponses is set, which is meant
+// for tests, responses are buffered and replaced with 500 when they do not match the operation either.
+func Middleware(doc *Document, validateResponses bool, log *logger.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			operation, vars := doc.find(r.Method, r.URL.Path)
+			if operation == nil {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			log := logger.FromContext(r.Context(), log)
+			if err := doc.validateRequest(operation, vars, r); err != nil {
+				log.Info("Request does not match the specification", zap.Error(err))
+				middleware.WriteError(rw, http.StatusBadRequest, err.Error())
+
+				return
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			buffer := &bufferedResponse{ResponseWriter: rw, status: http.StatusOK}
+			next.ServeHTTP(buffer, r)
+			if err := doc.validateResponse(operation, buffer.status, buffer.body.Bytes()); err != nil {
+				log.Error("Response does not match the specification", zap.Int("status", buffer.status), zap.Error(err))
+				middleware.WriteError(rw, http.StatusInternalServerError, "the response does not match the specification")
+
+				return
+			}
+
+			rw.WriteHeader(buffer.status)
+			rw.Write(buffer.body.Bytes())
+		})
+	}
+}
+
+// validateRequest checks the parameters and the body of the request. The body is restored for the next handler.
+func (d *Document) validateRequest(operation *Operation, vars map[string]string, r *http.Request) error {
+	for _, parameter := range operation.Parameters {
+		var value string
+		switch parameter.In {
+		case "path":
+			value = vars[parameter.Name]
+		case "query":
+			value = r.URL.Query().Get(parameter.Name)
+		case "header":
+			value = r.Header.Get(parameter.Name)
+		}
+
+		if value == "" {
+			if parameter.Required {
+				return fmt.Errorf("%s parameter %s is required", parameter.In, parameter.Name)
+			}
+
+			continue
+		}
+
+		if parameter.Schema == nil {
+			continue
+		}
+
+		schema, err := d.resolve(parameter.Schema)
+		if err != nil {
+			return err
+		}
+
+		if err := d.validate(schema, parseParameter(schema, value), parameter.In+"."+parameter.Name); err != nil {
+			return err
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.New("request body reading failed")
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		if operation.RequestBody.Required {
+			return errors.New("request body is required")
+		}
+
+		return nil
+	}
+
+	media, ok := operation.RequestBody.Content[ContentJSON]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return errors.New("request body is not valid JSON")
+	}
+
+	return d.validate(media.Schema, value, "body")
+}
+
+// validateResponse checks the status code and the body of a response.
+func (d *Document) validateResponse(operation *Operation, status int, body []byte) error {
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = operation.Responses["default"]; !ok {
+			return fmt.Errorf("status code %d is not specified", status)
+		}
+	}
+
+	media, ok := response.Content[ContentJSON]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("response body is not valid JSON: %w", err)
+	}
+
+	return d.validate(media.Schema, value, "response")
+}
+
+// parseParameter converts the string value of a parameter to the JSON type of its schema if it can.
+// Values that cannot be converted are returned as strings, so validation reports their type.
+func parseParameter(schema *Schema, value string) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return value
+}
+
+// bufferedResponse holds the status code and the body of a response until it is validated.
+// Headers are written to the underlying response writer directly.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+
+	return b.body.Write(p)
+}
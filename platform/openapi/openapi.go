@@ -0,0 +1,151 @@
+// Package openapi contains the OpenAPI 3 document model, the handlers that serve a document and its docs page,
+// and the middleware that validates requests and responses against a document.
+package openapi
+
+import (
+	"strings"
+)
+
+// Defines the versions and media types of documents.
+const (
+	// Version is the OpenAPI version of documents.
+	Version = "3.0.3"
+	// ContentJSON is the media type of JSON bodies.
+	ContentJSON = "application/json"
+)
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps lowercase HTTP methods, like "get", to the operations of a path.
+type PathItem map[string]*Operation
+
+// Operation describes a route.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path, query or header parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes the body of an operation request.
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response describes an operation response. A response without content has no body.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType contains the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components contains the schemas and security schemes referenced by the document.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes a way to authenticate.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// JSON returns the content of a JSON body with the schema.
+func JSON(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{ContentJSON: {Schema: schema}}
+}
+
+// Ref returns a schema that refers to the component schema with the name.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// find returns the operation of the method whose path template matches path, and the values of its path parameters.
+// Literal segments take precedence over parameters, so "/book/new" wins over "/book/{id}".
+func (d *Document) find(method, path string) (*Operation, map[string]string) {
+	var (
+		found      *Operation
+		foundVars  map[string]string
+		foundCount = -1
+	)
+
+	for template, item := range d.Paths {
+		operation, ok := item[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+
+		vars, ok := matchPath(template, path)
+		if !ok || (foundCount >= 0 && len(vars) >= foundCount) {
+			continue
+		}
+
+		found, foundVars, foundCount = operation, vars, len(vars)
+	}
+
+	return found, foundVars
+}
+
+// matchPath reports whether path matches the template, like "/v1/book/{id}", and returns its parameters.
+func matchPath(template, path string) (map[string]string, bool) {
+	templateSegments, pathSegments := strings.Split(template, "/"), strings.Split(path, "/")
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	vars := make(map[string]string)
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+
+			vars[segment[1:len(segment)-1]] = pathSegments[i]
+
+			continue
+		}
+
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	return vars, true
+}
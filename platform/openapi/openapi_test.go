@@ -0,0 +1,309 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/openapi"
+)
+
+// testDocument returns a document with a route to create and a route to get items.
+func testDocument() *openapi.Document {
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    openapi.Info{Title: "test", Version: "1"},
+		Paths: map[string]openapi.PathItem{
+			"/items/": {
+				"post": {
+					OperationID: "createItem",
+					Parameters: []*openapi.Parameter{
+						{Name: "X-Limit", In: "header", Schema: &openapi.Schema{Type: "integer", Maximum: openapi.Float(10)}},
+					},
+					RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("Item"))},
+					Responses: map[string]*openapi.Response{
+						"201": {Description: "Created", Content: openapi.JSON(openapi.Ref("Item"))},
+					},
+				},
+			},
+			"/items/{id}": {
+				"get": {
+					OperationID: "getItem",
+					Parameters: []*openapi.Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+					},
+					Responses: map[string]*openapi.Response{
+						"200":     {Description: "OK", Content: openapi.JSON(openapi.Ref("Item"))},
+						"default": {Description: "Error"},
+					},
+				},
+			},
+		},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{
+				"Item": {
+					Type:     "object",
+					Required: []string{"name", "price"},
+					Properties: map[string]*openapi.Schema{
+						"name":  {Type: "string", MinLength: openapi.Int(1)},
+						"kind":  {Type: "string", Enum: []interface{}{"book", "game"}},
+						"tags":  {Type: "array", Items: &openapi.Schema{Type: "string"}},
+						"price": {AnyOf: []*openapi.Schema{{Type: "number"}, {Type: "string", Pattern: `^[0-9]+(\.[0-9]+)?$`}}},
+					},
+					AdditionalProperties: openapi.Bool(false),
+				},
+			},
+		},
+	}
+}
+
+func TestMiddleware_request(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		body       string
+		statusCode int
+		expected   string
+	}{
+		{
+			name:       "OK",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune","price":"9.99","kind":"book","tags":["sf"]}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "Number in anyOf",
+			method:     http.MethodPost,
+			path:       "/items/",
+			header:     "5",
+			body:       `{"name":"Dune","price":9.99}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "Missing body",
+			method:     http.MethodPost,
+			path:       "/items/",
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"request body is required"}}`,
+		},
+		{
+			name:       "Invalid JSON",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"request body is not valid JSON"}}`,
+		},
+		{
+			name:       "Missing property",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune"}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.price is required"}}`,
+		},
+		{
+			name:       "Wrong property type",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":1,"price":1}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.name must be of type string"}}`,
+		},
+		{
+			name:       "Empty string",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"","price":1}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.name must be at least 1 characters long"}}`,
+		},
+		{
+			name:       "Value out of enum",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune","price":1,"kind":"film"}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.kind must be one of [book game]"}}`,
+		},
+		{
+			name:       "Wrong item type",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune","price":1,"tags":["sf",1]}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.tags[1] must be of type string"}}`,
+		},
+		{
+			name:       "Unknown property",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune","price":1,"color":"red"}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"body.color is not allowed"}}`,
+		},
+		{
+			name:       "No schema matches",
+			method:     http.MethodPost,
+			path:       "/items/",
+			body:       `{"name":"Dune","price":"cheap"}`,
+			statusCode: http.StatusBadRequest,
+			expected: `{"error":{"statusCode":400,"message":"body.price matches none of the allowed schemas: ` +
+				`body.price must be of type number; body.price must match the pattern ^[0-9]+(\\.[0-9]+)?$"}}`,
+		},
+		{
+			name:       "Header out of range",
+			method:     http.MethodPost,
+			path:       "/items/",
+			header:     "11",
+			body:       `{"name":"Dune","price":1}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"header.X-Limit must be at most 10"}}`,
+		},
+		{
+			name:       "Header of wrong type",
+			method:     http.MethodPost,
+			path:       "/items/",
+			header:     "many",
+			body:       `{"name":"Dune","price":1}`,
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"header.X-Limit must be of type integer"}}`,
+		},
+		{
+			name:       "OK path parameter",
+			method:     http.MethodGet,
+			path:       "/items/7a2f922c-073a-11eb-adc1-0242ac120003",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Invalid path parameter",
+			method:     http.MethodGet,
+			path:       "/items/1",
+			statusCode: http.StatusBadRequest,
+			expected:   `{"error":{"statusCode":400,"message":"path.id must be a UUID"}}`,
+		},
+		{
+			name:       "Route without operation",
+			method:     http.MethodDelete,
+			path:       "/items/1",
+			statusCode: http.StatusOK,
+		},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	handler := openapi.Middleware(testDocument(), false, log)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rw.WriteHeader(http.StatusCreated)
+		}
+	}))
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+		if testCase.header != "" {
+			req.Header.Set("X-Limit", testCase.header)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, testCase.statusCode, rec.Code, testCase.name)
+		assert.Equal(t, testCase.expected, rec.Body.String(), testCase.name)
+	}
+}
+
+func TestMiddleware_response(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   int
+	}{
+		{name: "OK", statusCode: http.StatusOK, body: `{"name":"Dune","price":1}`, expected: http.StatusOK},
+		{name: "Default response", statusCode: http.StatusNotFound, body: `not found`, expected: http.StatusNotFound},
+		{name: "Invalid body", statusCode: http.StatusOK, body: `{"name":"Dune"}`, expected: http.StatusInternalServerError},
+		{name: "Invalid JSON", statusCode: http.StatusOK, body: `Dune`, expected: http.StatusInternalServerError},
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		handler := openapi.Middleware(testDocument(), true, log)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(testCase.statusCode)
+			rw.Write([]byte(testCase.body))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/7a2f922c-073a-11eb-adc1-0242ac120003", nil))
+
+		assert.Equal(t, testCase.expected, rec.Code, testCase.name)
+		if testCase.expected == testCase.statusCode {
+			assert.Equal(t, testCase.body, rec.Body.String(), testCase.name)
+		}
+	}
+
+	// Responses are not validated unless requested.
+	handler := openapi.Middleware(testDocument(), false, log)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`Dune`))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/7a2f922c-073a-11eb-adc1-0242ac120003", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := testDocument().Components.Schemas["Item"]
+	testCases := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Valid", value: `{"name":"Dune","price":"9.99","tags":["sf"]}`},
+		{name: "Missing property", value: `{"name":"Dune"}`, expected: "item.price is required"},
+		{name: "Invalid item", value: `{"name":"Dune","price":1,"tags":[1]}`, expected: "item.tags[0] must be of type string"},
+		{name: "Invalid enum", value: `{"name":"Dune","price":1,"kind":"film"}`, expected: "item.kind must be one of [book game]"},
+	}
+
+	for _, testCase := range testCases {
+		var value interface{}
+		assert.NoError(t, json.Unmarshal([]byte(testCase.value), &value), testCase.name)
+		err := schema.Validate(value, "item")
+		if testCase.expected == "" {
+			assert.NoError(t, err, testCase.name)
+		} else if assert.Error(t, err, testCase.name) {
+			assert.Equal(t, testCase.expected, err.Error(), testCase.name)
+		}
+	}
+
+	// References cannot be resolved without a document.
+	assert.Error(t, openapi.Ref("Item").Validate(map[string]interface{}{}, "item"))
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	openapi.Handler(testDocument()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	spec := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, openapi.Version, spec["openapi"])
+	assert.Equal(t, openapi.ContentJSON, rec.Header().Get("Content-Type"))
+
+	rec = httptest.NewRecorder()
+	openapi.DocsHandler("/openapi.json").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Contains(t, rec.Body.String(), `var specURL = "/openapi.json";`)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+}
@@ -0,0 +1,242 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema describes a JSON value. Validation supports the type, format ("uuid"), enum, pattern, length,
+// range, properties, required, additionalProperties, items and anyOf keywords. An empty schema allows any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+}
+
+// Int returns a pointer to n, for the length keywords of schemas.
+func Int(n int) *int {
+	return &n
+}
+
+// Float returns a pointer to n, for the range keywords of schemas.
+func Float(n float64) *float64 {
+	return &n
+}
+
+// Bool returns a pointer to b, for the additionalProperties keyword of schemas.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Validate checks the decoded JSON value against a schema that has no references, like a standalone JSON Schema.
+// The path names the value in errors, like "message.price".
+func (s *Schema) Validate(value interface{}, path string) error {
+	return (&Document{}).validate(s, value, path)
+}
+
+// resolve follows the reference of the schema to a component schema.
+func (d *Document) resolve(schema *Schema) (*Schema, error) {
+	for schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("schema %s is not defined", schema.Ref)
+		}
+
+		schema = resolved
+	}
+
+	return schema, nil
+}
+
+// validate checks the decoded JSON value against the schema. The path names the value in errors, like "body.name".
+func (d *Document) validate(schema *Schema, value interface{}, path string) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if len(schema.AnyOf) > 0 {
+		return d.validateAnyOf(schema.AnyOf, value, path)
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		return fmt.Errorf("%s must be of type %s", path, schema.Type)
+	}
+
+	if len(schema.Enum) > 0 && !contains(schema.Enum, value) {
+		return fmt.Errorf("%s must be one of %v", path, schema.Enum)
+	}
+
+	switch value := value.(type) {
+	case string:
+		return validateString(schema, value, path)
+	case float64:
+		return validateNumber(schema, value, path)
+	case map[string]interface{}:
+		return d.validateObject(schema, value, path)
+	case []interface{}:
+		if schema.Items == nil {
+			return nil
+		}
+
+		for i, item := range value {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) validateAnyOf(schemas []*Schema, value interface{}, path string) error {
+	var errs []string
+	for _, schema := range schemas {
+		err := d.validate(schema, value, path)
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	return fmt.Errorf("%s matches none of the allowed schemas: %s", path, strings.Join(errs, "; "))
+}
+
+func (d *Document) validateObject(schema *Schema, value map[string]interface{}, path string) error {
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			return fmt.Errorf("%s.%s is required", path, name)
+		}
+	}
+
+	// Properties are checked in order, so the same value always reports the same error.
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return fmt.Errorf("%s.%s is not allowed", path, name)
+			}
+
+			continue
+		}
+
+		if err := d.validate(property, value[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateString(schema *Schema, value, path string) error {
+	length := len([]rune(value))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return fmt.Errorf("%s must be at least %d characters long", path, *schema.MinLength)
+	}
+
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fmt.Errorf("%s must be at most %d characters long", path, *schema.MaxLength)
+	}
+
+	if schema.Pattern != "" {
+		matched, err := regexp.MatchString(schema.Pattern, value)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid pattern: %w", path, err)
+		}
+
+		if !matched {
+			return fmt.Errorf("%s must match the pattern %s", path, schema.Pattern)
+		}
+	}
+
+	if schema.Format == "uuid" && !isUUID(value) {
+		return fmt.Errorf("%s must be a UUID", path)
+	}
+
+	return nil
+}
+
+func validateNumber(schema *Schema, value float64, path string) error {
+	if schema.Minimum != nil && value < *schema.Minimum {
+		return fmt.Errorf("%s must be at least %v", path, *schema.Minimum)
+	}
+
+	if schema.Maximum != nil && value > *schema.Maximum {
+		return fmt.Errorf("%s must be at most %v", path, *schema.Maximum)
+	}
+
+	return nil
+}
+
+// hasType reports whether the decoded JSON value is of the schema type.
+func hasType(value interface{}, typ string) bool {
+	switch value := value.(type) {
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || (typ == "integer" && value == math.Trunc(value))
+	case bool:
+		return typ == "boolean"
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	default:
+		return false
+	}
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isUUID reports whether s is a UUID in the canonical form, like "7a2f922c-073a-11eb-adc1-0242ac120003".
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, c := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case !strings.ContainsRune("0123456789abcdefABCDEF", c):
+			return false
+		}
+	}
+
+	return true
+}
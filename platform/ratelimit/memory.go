@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the interval between removals of full buckets from MemoryStore.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps token buckets in the process memory, so limits are not shared between replicas.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is a token bucket that had tokens at the time it was last updated.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is the time the bucket refills completely, after which it is the same as a new one.
+	full time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore object.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// Take takes a token from the bucket of key.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := result(limit, b.tokens, allowed)
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep removes full buckets once per sweepInterval, so the store does not grow with every client seen.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
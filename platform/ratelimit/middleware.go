@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/middleware"
+)
+
+// keyPrefix prefixes the keys of buckets in a shared store.
+const keyPrefix = "ratelimit:"
+
+// NewStore returns a RedisStore connected to redisURL, like "redis://localhost:6379/0",
+// or a MemoryStore if redisURL is empty.
+func NewStore(redisURL string) (Store, error) {
+	if redisURL == "" {
+		return NewMemoryStore(), nil
+	}
+
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedisStore(redis.NewClient(options), keyPrefix), nil
+}
+
+// Allow takes a token for the client of the request from the bucket named name, sets the RateLimit-* headers
+// and responds with the 429 status code if the bucket is empty. It reports whether the request may proceed.
+// Requests are allowed if the store fails, so an unavailable shared store does not take the service down.
+func Allow(rw http.ResponseWriter, r *http.Request, store Store, name string, limit Limit, log *logger.Logger) bool {
+	if limit.IsZero() {
+		return true
+	}
+
+	res, err := store.Take(r.Context(), auth.ClientKey(r)+":"+name, limit)
+	if err != nil {
+		logger.FromContext(r.Context(), log).Error("Rate limit check failed", zap.Error(err))
+
+		return true
+	}
+
+	header := rw.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	header.Set("RateLimit-Reset", ceilSeconds(res.Reset.Seconds()))
+	if res.Allowed {
+		return true
+	}
+
+	logger.FromContext(r.Context(), log).Info("Rate limit exceeded", zap.String("limit", name))
+	header.Set("Retry-After", ceilSeconds(res.RetryAfter.Seconds()))
+	middleware.WriteError(rw, http.StatusTooManyRequests, "rate limit exceeded")
+
+	return false
+}
+
+// Middleware returns a middleware that limits requests of every client to the limit returned by rule.
+// The rule also names the bucket, so clients have a separate bucket per name, for example per route.
+func Middleware(store Store, rule func(r *http.Request) (string, Limit), log *logger.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			name, limit := rule(r)
+			if Allow(rw, r, store, name, limit, log) {
+				next.ServeHTTP(rw, r)
+			}
+		})
+	}
+}
+
+func ceilSeconds(s float64) string {
+	return strconv.Itoa(int(math.Ceil(s)))
+}
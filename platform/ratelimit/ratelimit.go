@@ -0,0 +1,111 @@
+// Package ratelimit contains the logic to limit request rates of clients with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit is returned if a limit is not in the "<requests>/<s|m|h>" format.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit describes a token bucket: it holds up to Burst tokens and refills Rate tokens per second.
+// Every request takes a token. The zero Limit does not limit requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses limits like "100/m": 100 requests per minute, all of which may be made at once.
+// The units are "s", "m" and "h". An empty string is the zero Limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(parts[1]) {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Rate: float64(requests) / period.Seconds(), Burst: requests}, nil
+}
+
+// ParseLimits parses a map of names to limits, for example routes or topic patterns.
+func ParseLimits(limits map[string]string) (map[string]Limit, error) {
+	parsed := make(map[string]Limit, len(limits))
+	for name, limit := range limits {
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+
+		parsed[strings.TrimSpace(name)] = l
+	}
+
+	return parsed, nil
+}
+
+// IsZero reports whether the limit does not limit requests.
+func (l Limit) IsZero() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Result is the state of a bucket after a request took a token from it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token if the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take takes a token from the bucket of key, creating a full bucket if there is none.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result converts the tokens left in a bucket to a Result.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
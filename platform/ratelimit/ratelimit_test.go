@@ -0,0 +1,143 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ivyoverflow/pub-sub/platform/auth"
+	"github.com/ivyoverflow/pub-sub/platform/logger"
+	"github.com/ivyoverflow/pub-sub/platform/ratelimit"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		limit       string
+		expected    ratelimit.Limit
+		expectedErr error
+	}{
+		{
+			name:     "Per second",
+			limit:    "10/s",
+			expected: ratelimit.Limit{Rate: 10, Burst: 10},
+		},
+		{
+			name:     "Per minute",
+			limit:    "120/m",
+			expected: ratelimit.Limit{Rate: 2, Burst: 120},
+		},
+		{
+			name:     "Empty",
+			limit:    "",
+			expected: ratelimit.Limit{},
+		},
+		{
+			name:        "Unknown unit",
+			limit:       "10/d",
+			expectedErr: ratelimit.ErrInvalidLimit,
+		},
+		{
+			name:        "Negative requests",
+			limit:       "-1/s",
+			expectedErr: ratelimit.ErrInvalidLimit,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			limit, err := ratelimit.ParseLimit(testCase.limit)
+			assert.Equal(t, testCase.expectedErr, err)
+			assert.Equal(t, testCase.expected, limit)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, ratelimit.NewMemoryStore())
+}
+
+func TestRedisStore(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+
+	store, err := ratelimit.NewStore(redisURL)
+	if err != nil {
+		t.Fatalf("Store initialization throws an error: %v", err)
+	}
+
+	testStore(t, store)
+}
+
+func testStore(t *testing.T, store ratelimit.Store) {
+	ctx := context.Background()
+	key := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	limit := ratelimit.Limit{Rate: 20, Burst: 2}
+
+	for i := 1; i >= 0; i-- {
+		res, err := store.Take(ctx, key, limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Take(ctx, key, limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.RetryAfter > 0 && res.RetryAfter <= 50*time.Millisecond)
+
+	time.Sleep(60 * time.Millisecond)
+	res, err = store.Take(ctx, key, limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestMiddleware(t *testing.T) {
+	log, err := logger.New()
+	if err != nil {
+		t.Fatalf("Logger initialization throws an error: %v", err)
+	}
+
+	rule := func(r *http.Request) (string, ratelimit.Limit) {
+		return r.Method + " " + r.URL.Path, ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}
+	}
+
+	handler := ratelimit.Middleware(ratelimit.NewMemoryStore(), rule, log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(remoteAddr string, principal *auth.Principal) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/book/", nil)
+		request.RemoteAddr = remoteAddr
+		if principal != nil {
+			request = request.WithContext(auth.NewContext(request.Context(), principal))
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	recorder := serve("203.0.113.7:41234", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", recorder.Header().Get("RateLimit-Reset"))
+
+	recorder = serve("203.0.113.7:41235", nil)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, `{"error":{"statusCode":429,"message":"rate limit exceeded"}}`, recorder.Body.String())
+
+	// Authenticated clients are limited by their subject rather than their address.
+	recorder = serve("203.0.113.7:41236", &auth.Principal{Subject: "ci"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = serve("198.51.100.1:41234", &auth.Principal{Subject: "ci"})
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
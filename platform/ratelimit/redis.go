@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript takes a token from the bucket hash at KEYS[1] with the rate ARGV[1] and the burst ARGV[2].
+// The Redis clock is used, so replicas with skewed clocks share the same buckets.
+// It returns 1 if the token was taken and the tokens left as a string to keep the fraction.
+const takeScript = `
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// RedisStore keeps token buckets in Redis, so limits hold across replicas.
+type RedisStore struct {
+	client *redis.Client
+	script *redis.Script
+	prefix string
+}
+
+// NewRedisStore returns a new RedisStore object that prefixes bucket keys with prefix.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, script: redis.NewScript(takeScript), prefix: prefix}
+}
+
+// Take takes a token from the bucket of key.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := s.script.Run(ctx, s.client, []string{s.prefix + key},
+		strconv.FormatFloat(limit.Rate, 'f', -1, 64), limit.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokens, err := strconv.ParseFloat(reply[1].(string), 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, tokens, allowed == 1), nil
+}
+
+// Ping checks the Redis connection. It is the readiness check of the store.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
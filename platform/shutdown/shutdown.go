@@ -0,0 +1,28 @@
+// Package shutdown contains the logic to stop the application on termination signals.
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Notify returns a copy of the parent context that is canceled when the process
+// receives SIGINT or SIGTERM, or when the returned cancel function is called.
+func Notify(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+		}
+
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx, cancel
+}
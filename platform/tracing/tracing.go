@@ -0,0 +1,104 @@
+// Package tracing contains the logic to initialize OpenTelemetry tracing and to carry trace context inside messages.
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defines supported span exporters. An empty exporter disables exporting,
+// but the trace context is still propagated.
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// ErrUnknownExporter is returned by Init if the exporter is not supported.
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Init installs the W3C trace context propagator and a global tracer provider of the service
+// that sends spans to the named exporter. The OTLP exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables. The returned function flushes and stops the provider.
+func Init(ctx context.Context, service, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, ErrUnknownExporter
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Inject returns the trace context of ctx as a map that can be sent inside a message.
+func Inject(ctx context.Context) map[string]string {
+	carrier := mapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	return carrier
+}
+
+// Extract returns a copy of ctx that carries the trace context received inside a message.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, mapCarrier(carrier))
+}
+
+// End records err, if any, on the span and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// mapCarrier adapts a map to the propagation.TextMapCarrier interface.
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c mapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c mapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,63 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ivyoverflow/pub-sub/platform/tracing"
+)
+
+func TestInit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		exporter    string
+		expectedErr error
+	}{
+		{
+			name:     "Without exporter",
+			exporter: tracing.ExporterNone,
+		},
+		{
+			name:     "Stdout exporter",
+			exporter: tracing.ExporterStdout,
+		},
+		{
+			name:        "Unknown exporter",
+			exporter:    "zipkin",
+			expectedErr: tracing.ErrUnknownExporter,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			shutdown, err := tracing.Init(context.Background(), "test", testCase.exporter)
+			assert.Equal(t, testCase.expectedErr, err)
+			if err == nil {
+				assert.NoError(t, shutdown(context.Background()))
+			}
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	if _, err := tracing.Init(context.Background(), "test", tracing.ExporterNone); err != nil {
+		t.Fatalf("Tracing initialization throws an error: %v", err)
+	}
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	carrier := tracing.Inject(trace.ContextWithSpanContext(context.Background(), spanContext))
+	assert.Equal(t, "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01", carrier["traceparent"])
+
+	extracted := trace.SpanContextFromContext(tracing.Extract(context.Background(), carrier))
+	assert.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	assert.Equal(t, spanContext.SpanID(), extracted.SpanID())
+	assert.True(t, extracted.IsRemote())
+}